@@ -0,0 +1,208 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// IDL is the Anchor IDL of a program.
+// See https://github.com/project-serum/anchor/blob/master/ts/src/idl.ts
+type IDL struct {
+	Version      string           `json:"version"`
+	Name         string           `json:"name"`
+	Instructions []IDLInstruction `json:"instructions"`
+	State        *IDLState        `json:"state,omitempty"`
+	Accounts     []IDLTypeDef     `json:"accounts,omitempty"`
+	Types        []IDLTypeDef     `json:"types,omitempty"`
+	Events       []IDLEvent       `json:"events,omitempty"`
+}
+
+// IDLState is the `#[state]` struct of a program, along with its methods.
+type IDLState struct {
+	Struct  IDLTypeDef       `json:"struct"`
+	Methods []IDLInstruction `json:"methods"`
+}
+
+type IDLInstruction struct {
+	Name     string           `json:"name"`
+	Accounts []IDLAccountItem `json:"accounts"`
+	Args     []IDLField       `json:"args"`
+}
+
+// IDLAccountItem is either a single account (IsMut, IsSigner)
+// or a group of accounts (Accounts).
+type IDLAccountItem struct {
+	Name     string           `json:"name"`
+	IsMut    bool             `json:"isMut"`
+	IsSigner bool             `json:"isSigner"`
+	Accounts []IDLAccountItem `json:"accounts,omitempty"`
+}
+
+type IDLField struct {
+	Name string  `json:"name"`
+	Type IDLType `json:"type"`
+}
+
+type IDLEvent struct {
+	Name   string          `json:"name"`
+	Fields []IDLEventField `json:"fields"`
+}
+
+type IDLEventField struct {
+	Name  string  `json:"name"`
+	Type  IDLType `json:"type"`
+	Index bool    `json:"index"`
+}
+
+type IDLTypeDef struct {
+	Name string       `json:"name"`
+	Type IDLTypeDefTy `json:"type"`
+}
+
+const (
+	IDLTypeDefKindStruct = "struct"
+	IDLTypeDefKindEnum   = "enum"
+)
+
+type IDLTypeDefTy struct {
+	Kind     string           `json:"kind"`
+	Fields   []IDLField       `json:"fields,omitempty"`
+	Variants []IDLEnumVariant `json:"variants,omitempty"`
+}
+
+// IDLEnumVariant is a variant of an enum; its fields are either
+// named (NamedFields) or positional (TupleFields), or absent.
+type IDLEnumVariant struct {
+	Name        string
+	NamedFields []IDLField
+	TupleFields []IDLType
+}
+
+func (v IDLEnumVariant) IsUnit() bool {
+	return len(v.NamedFields) == 0 && len(v.TupleFields) == 0
+}
+
+func (v IDLEnumVariant) MarshalJSON() ([]byte, error) {
+	out := map[string]interface{}{"name": v.Name}
+	switch {
+	case len(v.NamedFields) > 0:
+		out["fields"] = v.NamedFields
+	case len(v.TupleFields) > 0:
+		out["fields"] = v.TupleFields
+	}
+	return json.Marshal(out)
+}
+
+func (v *IDLEnumVariant) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name   string            `json:"name"`
+		Fields []json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*v = IDLEnumVariant{Name: raw.Name}
+	for _, field := range raw.Fields {
+		var named IDLField
+		if err := json.Unmarshal(field, &named); err == nil && named.Name != "" {
+			v.NamedFields = append(v.NamedFields, named)
+			continue
+		}
+		var tuple IDLType
+		if err := json.Unmarshal(field, &tuple); err != nil {
+			return fmt.Errorf("enum variant %q: %w", raw.Name, err)
+		}
+		v.TupleFields = append(v.TupleFields, tuple)
+	}
+	if len(v.NamedFields) > 0 && len(v.TupleFields) > 0 {
+		return fmt.Errorf("enum variant %q: mixed named and tuple fields", raw.Name)
+	}
+	return nil
+}
+
+// IDLType is the type of a field; exactly one of its members is set.
+type IDLType struct {
+	// Primitive is one of "bool", "u8", "i8", "u16", "i16", "u32", "i32",
+	// "u64", "i64", "u128", "i128", "f32", "f64", "bytes", "string", "publicKey".
+	Primitive string
+	Vec       *IDLType
+	Option    *IDLType
+	Array     *IDLTypeArray
+	Defined   string
+}
+
+type IDLTypeArray struct {
+	Elem IDLType
+	Len  int
+}
+
+func (t IDLType) MarshalJSON() ([]byte, error) {
+	switch {
+	case t.Primitive != "":
+		return json.Marshal(t.Primitive)
+	case t.Vec != nil:
+		return json.Marshal(map[string]interface{}{"vec": t.Vec})
+	case t.Option != nil:
+		return json.Marshal(map[string]interface{}{"option": t.Option})
+	case t.Array != nil:
+		return json.Marshal(map[string]interface{}{"array": []interface{}{t.Array.Elem, t.Array.Len}})
+	case t.Defined != "":
+		return json.Marshal(map[string]interface{}{"defined": t.Defined})
+	default:
+		return nil, errors.New("empty IDL type")
+	}
+}
+
+func (t *IDLType) UnmarshalJSON(data []byte) error {
+	*t = IDLType{}
+	if err := json.Unmarshal(data, &t.Primitive); err == nil {
+		return nil
+	}
+
+	var obj struct {
+		Vec     *IDLType          `json:"vec"`
+		Option  *IDLType          `json:"option"`
+		Array   []json.RawMessage `json:"array"`
+		Defined string            `json:"defined"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("invalid IDL type %s: %w", string(data), err)
+	}
+	switch {
+	case obj.Vec != nil:
+		t.Vec = obj.Vec
+	case obj.Option != nil:
+		t.Option = obj.Option
+	case obj.Array != nil:
+		if len(obj.Array) != 2 {
+			return fmt.Errorf("invalid IDL array type %s", string(data))
+		}
+		t.Array = new(IDLTypeArray)
+		if err := json.Unmarshal(obj.Array[0], &t.Array.Elem); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(obj.Array[1], &t.Array.Len); err != nil {
+			return fmt.Errorf("invalid IDL array length %s: %w", string(obj.Array[1]), err)
+		}
+	case obj.Defined != "":
+		t.Defined = obj.Defined
+	default:
+		return fmt.Errorf("unknown IDL type %s", string(data))
+	}
+	return nil
+}
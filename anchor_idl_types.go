@@ -0,0 +1,238 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"fmt"
+	"reflect"
+	"unicode"
+)
+
+var (
+	uint128Type = reflect.TypeOf(Uint128{})
+	int128Type  = reflect.TypeOf(Int128{})
+)
+
+// GenerateIDLTypes walks the provided Go values (structs, or pointers to structs)
+// and returns the Anchor IDL type definitions of their types, followed by the
+// definitions of all the named struct types they reference.
+//
+// The types are mapped following their borsh encoding:
+//   - Uint128 and Int128 to `u128` and `i128`;
+//   - [32]byte to `publicKey`, other arrays to `array`;
+//   - []byte to `bytes`, other slices to `vec`;
+//   - fields tagged with `bin:"optional"` to `option`;
+//   - complex enums (structs with a `borsh_enum` BorshEnum first field) to `enum`.
+//
+// Field names are converted to camelCase, as in IDLs generated by anchor.
+func GenerateIDLTypes(values ...interface{}) ([]IDLTypeDef, error) {
+	g := &idlTypesGenerator{
+		seen: make(map[string]reflect.Type),
+	}
+	for _, v := range values {
+		rt := reflect.TypeOf(v)
+		for rt != nil && rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		if rt == nil || rt.Kind() != reflect.Struct || rt.Name() == "" {
+			return nil, fmt.Errorf("idl: %T is not a named struct", v)
+		}
+		if _, err := g.defined(rt); err != nil {
+			return nil, err
+		}
+	}
+	return g.defs, nil
+}
+
+type idlTypesGenerator struct {
+	defs []IDLTypeDef
+	seen map[string]reflect.Type
+}
+
+// defined registers the definition of the named struct type
+// (if not already done), and returns the reference to it.
+func (g *idlTypesGenerator) defined(rt reflect.Type) (IDLType, error) {
+	ref := IDLType{Defined: rt.Name()}
+	if prev, ok := g.seen[rt.Name()]; ok {
+		if prev != rt {
+			return IDLType{}, fmt.Errorf("idl: types %s and %s have the same name", prev, rt)
+		}
+		return ref, nil
+	}
+	g.seen[rt.Name()] = rt
+
+	// Reserve the position of the definition, so that
+	// the definitions are listed in the order they are met.
+	index := len(g.defs)
+	g.defs = append(g.defs, IDLTypeDef{Name: rt.Name()})
+
+	var ty IDLTypeDefTy
+	var err error
+	if isComplexEnumType(rt) {
+		ty, err = g.enumTy(rt)
+	} else {
+		ty.Kind = IDLTypeDefKindStruct
+		ty.Fields, err = g.fields(rt)
+	}
+	if err != nil {
+		return IDLType{}, fmt.Errorf("idl: type %s: %w", rt.Name(), err)
+	}
+	g.defs[index].Type = ty
+	return ref, nil
+}
+
+func isComplexEnumType(rt reflect.Type) bool {
+	if rt.NumField() == 0 {
+		return false
+	}
+	firstField := rt.Field(0)
+	return isTypeBorshEnum(firstField.Type) && parseFieldTag(firstField.Tag).IsBorshEnum
+}
+
+func (g *idlTypesGenerator) enumTy(rt reflect.Type) (IDLTypeDefTy, error) {
+	ty := IDLTypeDefTy{Kind: IDLTypeDefKindEnum}
+	for i := 1; i < rt.NumField(); i++ {
+		structField := rt.Field(i)
+		variant := IDLEnumVariant{Name: structField.Name}
+
+		ft := structField.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft != uint128Type && ft != int128Type && !isComplexEnumType(ft) {
+			fields, err := g.fields(ft)
+			if err != nil {
+				return ty, fmt.Errorf("variant %s: %w", structField.Name, err)
+			}
+			if len(fields) > 0 {
+				variant.NamedFields = fields
+			}
+		} else {
+			typ, err := g.idlType(ft)
+			if err != nil {
+				return ty, fmt.Errorf("variant %s: %w", structField.Name, err)
+			}
+			variant.TupleFields = []IDLType{typ}
+		}
+		ty.Variants = append(ty.Variants, variant)
+	}
+	return ty, nil
+}
+
+func (g *idlTypesGenerator) fields(rt reflect.Type) ([]IDLField, error) {
+	out := make([]IDLField, 0, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		structField := rt.Field(i)
+		fieldTag := parseFieldTag(structField.Tag)
+		if fieldTag.Skip || structField.PkgPath != "" {
+			continue
+		}
+
+		ft := structField.Type
+		if fieldTag.Optional && ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		typ, err := g.idlType(ft)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", structField.Name, err)
+		}
+		if fieldTag.Optional {
+			elem := typ
+			typ = IDLType{Option: &elem}
+		}
+		out = append(out, IDLField{
+			Name: idlFieldName(structField.Name),
+			Type: typ,
+		})
+	}
+	return out, nil
+}
+
+func (g *idlTypesGenerator) idlType(rt reflect.Type) (IDLType, error) {
+	switch rt {
+	case uint128Type:
+		return IDLType{Primitive: "u128"}, nil
+	case int128Type:
+		return IDLType{Primitive: "i128"}, nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		return IDLType{Primitive: "bool"}, nil
+	case reflect.Uint8:
+		return IDLType{Primitive: "u8"}, nil
+	case reflect.Int8:
+		return IDLType{Primitive: "i8"}, nil
+	case reflect.Uint16:
+		return IDLType{Primitive: "u16"}, nil
+	case reflect.Int16:
+		return IDLType{Primitive: "i16"}, nil
+	case reflect.Uint32:
+		return IDLType{Primitive: "u32"}, nil
+	case reflect.Int32:
+		return IDLType{Primitive: "i32"}, nil
+	case reflect.Uint64:
+		return IDLType{Primitive: "u64"}, nil
+	case reflect.Int64:
+		return IDLType{Primitive: "i64"}, nil
+	case reflect.Float32:
+		return IDLType{Primitive: "f32"}, nil
+	case reflect.Float64:
+		return IDLType{Primitive: "f64"}, nil
+	case reflect.String:
+		return IDLType{Primitive: "string"}, nil
+	case reflect.Ptr:
+		// Non-optional pointers are encoded as their element.
+		return g.idlType(rt.Elem())
+	case reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 && rt.Len() == 32 {
+			return IDLType{Primitive: "publicKey"}, nil
+		}
+		elem, err := g.idlType(rt.Elem())
+		if err != nil {
+			return IDLType{}, err
+		}
+		return IDLType{Array: &IDLTypeArray{Elem: elem, Len: rt.Len()}}, nil
+	case reflect.Slice:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			return IDLType{Primitive: "bytes"}, nil
+		}
+		elem, err := g.idlType(rt.Elem())
+		if err != nil {
+			return IDLType{}, err
+		}
+		return IDLType{Vec: &elem}, nil
+	case reflect.Struct:
+		if rt.Name() == "" {
+			return IDLType{}, fmt.Errorf("anonymous struct %s is not supported", rt)
+		}
+		return g.defined(rt)
+	default:
+		return IDLType{}, fmt.Errorf("unsupported type %s", rt)
+	}
+}
+
+// idlFieldName converts a Go field name to camelCase,
+// lowering leading initialisms (e.g. "URLPath" to "urlPath").
+func idlFieldName(name string) string {
+	runes := []rune(name)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type idlTestVault struct {
+	Authority   [32]byte
+	Delegate    *[32]byte `bin:"optional"`
+	Balance     Uint128
+	Debt        Int128
+	Fees        [4]uint16
+	Memo        string
+	Data        []byte
+	History     []idlTestEntry
+	LastAction  idlTestAction
+	internal    uint8
+	Ignored     uint8 `bin:"-"`
+	URLChecksum uint32
+}
+
+type idlTestEntry struct {
+	Slot   uint64
+	Amount int64
+}
+
+type idlTestAction struct {
+	Enum     BorshEnum `borsh_enum:"true"`
+	Noop     idlTestEmpty
+	Deposit  idlTestEntry
+	Withdraw uint64
+}
+
+type idlTestEmpty struct{}
+
+func TestGenerateIDLTypes(t *testing.T) {
+	defs, err := GenerateIDLTypes(&idlTestVault{})
+	require.NoError(t, err)
+
+	out, err := json.Marshal(defs)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+  {
+    "name": "idlTestVault",
+    "type": {
+      "kind": "struct",
+      "fields": [
+        {"name": "authority", "type": "publicKey"},
+        {"name": "delegate", "type": {"option": "publicKey"}},
+        {"name": "balance", "type": "u128"},
+        {"name": "debt", "type": "i128"},
+        {"name": "fees", "type": {"array": ["u16", 4]}},
+        {"name": "memo", "type": "string"},
+        {"name": "data", "type": "bytes"},
+        {"name": "history", "type": {"vec": {"defined": "idlTestEntry"}}},
+        {"name": "lastAction", "type": {"defined": "idlTestAction"}},
+        {"name": "urlChecksum", "type": "u32"}
+      ]
+    }
+  },
+  {
+    "name": "idlTestEntry",
+    "type": {
+      "kind": "struct",
+      "fields": [
+        {"name": "slot", "type": "u64"},
+        {"name": "amount", "type": "i64"}
+      ]
+    }
+  },
+  {
+    "name": "idlTestAction",
+    "type": {
+      "kind": "enum",
+      "variants": [
+        {"name": "Noop"},
+        {"name": "Deposit", "fields": [{"name": "slot", "type": "u64"}, {"name": "amount", "type": "i64"}]},
+        {"name": "Withdraw", "fields": ["u64"]}
+      ]
+    }
+  }
+]`, string(out))
+
+	// The output can be parsed back:
+	var parsed []IDLTypeDef
+	require.NoError(t, json.Unmarshal(out, &parsed))
+	assert.Equal(t, defs, parsed)
+}
+
+func TestGenerateIDLTypes_Errors(t *testing.T) {
+	_, err := GenerateIDLTypes(uint64(1))
+	require.EqualError(t, err, "idl: uint64 is not a named struct")
+
+	type withMap struct {
+		M map[string]uint64
+	}
+	_, err = GenerateIDLTypes(withMap{})
+	require.EqualError(t, err, "idl: type withMap: field M: unsupported type map[string]uint64")
+}
+
+func TestIDLFieldName(t *testing.T) {
+	assert.Equal(t, "clientId", idlFieldName("ClientId"))
+	assert.Equal(t, "id", idlFieldName("ID"))
+	assert.Equal(t, "urlPath", idlFieldName("URLPath"))
+	assert.Equal(t, "a", idlFieldName("A"))
+}
@@ -0,0 +1,599 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// MarshalAnchorJSON returns the JSON representation of v produced by the
+// `@coral-xyz/anchor` coder for the same borsh data:
+//   - u64, i64, u128 and i128 as decimal strings; smaller integers as numbers;
+//   - [32]byte (public keys) as base58 strings;
+//   - byte slices and arrays as arrays of numbers;
+//   - `bin:"optional"` fields as null when absent;
+//   - complex enums as `{"variantName": {...}}`, with the fields of tuple
+//     variants keyed by their index (see AnchorTupleVariant), and simple
+//     enums (see AnchorSimpleEnum) as `{"variantName": {}}`;
+//   - struct fields with camelCase names.
+//
+// The same struct tags as the borsh encoder are honored.
+func MarshalAnchorJSON(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := marshalAnchorJSON(buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalAnchorJSON parses the anchor JSON representation of a value
+// (see MarshalAnchorJSON) into v, which must be a non-nil pointer.
+// Integers are accepted both as numbers and as decimal strings,
+// and simple enums also as numbers.
+func UnmarshalAnchorJSON(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidDecoderError{reflect.TypeOf(v)}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var node interface{}
+	if err := dec.Decode(&node); err != nil {
+		return err
+	}
+	return unmarshalAnchorJSON(node, rv.Elem())
+}
+
+// AnchorSimpleEnum marks the uint8 types of the simple enums (the enums
+// without fields), which anchorgen generates. String returns the name of
+// the variant of a value, and an empty string for the values without variant.
+type AnchorSimpleEnum interface {
+	fmt.Stringer
+	AnchorSimpleEnum()
+}
+
+// AnchorTupleVariant marks the structs holding the fields of a tuple
+// variant of a complex enum, which anchorgen generates. Their fields are
+// keyed by index in the anchor JSON: `{"0": ..., "1": ...}`.
+type AnchorTupleVariant interface {
+	AnchorTupleVariant()
+}
+
+func marshalAnchorJSON(buf *bytes.Buffer, rv reflect.Value) error {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+
+	switch rv.Type() {
+	case uint128Type:
+		return writeJSONString(buf, rv.Interface().(Uint128).DecimalString())
+	case int128Type:
+		return writeJSONString(buf, rv.Interface().(Int128).DecimalString())
+	}
+	if isSimpleEnumType(rv.Type()) {
+		return marshalAnchorJSONSimpleEnum(buf, rv)
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		buf.WriteString(strconv.FormatBool(rv.Bool()))
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		buf.WriteString(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		buf.WriteString(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Int, reflect.Int64:
+		return writeJSONString(buf, strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint64:
+		return writeJSONString(buf, strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("anchor json: unsupported float value %v", f)
+		}
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, rv.Type().Bits()))
+	case reflect.String:
+		return writeJSONString(buf, rv.String())
+	case reflect.Array:
+		if isPublicKeyType(rv.Type()) {
+			key := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(key), rv)
+			return writeJSONString(buf, base58Encode(key))
+		}
+		return marshalAnchorJSONList(buf, rv)
+	case reflect.Slice:
+		return marshalAnchorJSONList(buf, rv)
+	case reflect.Struct:
+		if isComplexEnumType(rv.Type()) {
+			return marshalAnchorJSONEnum(buf, rv)
+		}
+		return marshalAnchorJSONStruct(buf, rv, false)
+	default:
+		return fmt.Errorf("anchor json: unsupported type %s", rv.Type())
+	}
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) error {
+	out, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	buf.Write(out)
+	return nil
+}
+
+func isPublicKeyType(rt reflect.Type) bool {
+	return rt.Kind() == reflect.Array && rt.Len() == 32 && rt.Elem().Kind() == reflect.Uint8
+}
+
+func marshalAnchorJSONList(buf *bytes.Buffer, rv reflect.Value) error {
+	buf.WriteByte('[')
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := marshalAnchorJSON(buf, rv.Index(i)); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// marshalAnchorJSONStruct writes the fields of a struct keyed by their
+// camelCase name, or by their index if tuple is true.
+func marshalAnchorJSONStruct(buf *bytes.Buffer, rv reflect.Value, tuple bool) error {
+	rt := rv.Type()
+	buf.WriteByte('{')
+	index := 0
+	for i := 0; i < rt.NumField(); i++ {
+		structField := rt.Field(i)
+		fieldTag := parseFieldTag(structField.Tag)
+		if fieldTag.Skip || structField.PkgPath != "" {
+			continue
+		}
+		if index > 0 {
+			buf.WriteByte(',')
+		}
+		name := idlFieldName(structField.Name)
+		if tuple {
+			name = strconv.Itoa(index)
+		}
+		index++
+
+		if err := writeJSONString(buf, name); err != nil {
+			return err
+		}
+		buf.WriteByte(':')
+
+		field := rv.Field(i)
+		if fieldTag.Optional && field.IsZero() {
+			// Borsh encodes the zero value of optional fields as None.
+			buf.WriteString("null")
+			continue
+		}
+		if err := marshalAnchorJSON(buf, field); err != nil {
+			return fmt.Errorf("field %s: %w", structField.Name, err)
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func marshalAnchorJSONEnum(buf *bytes.Buffer, rv reflect.Value) error {
+	rt := rv.Type()
+	enum := int(rv.Field(0).Uint())
+	if enum+1 >= rt.NumField() {
+		return errors.New("anchor json: complex enum too large")
+	}
+	structField := rt.Field(enum + 1)
+
+	buf.WriteByte('{')
+	if err := writeJSONString(buf, idlFieldName(structField.Name)); err != nil {
+		return err
+	}
+	buf.WriteByte(':')
+
+	field := rv.Field(enum + 1)
+	for field.Kind() == reflect.Ptr && !field.IsNil() {
+		field = field.Elem()
+	}
+	switch {
+	case field.Kind() == reflect.Ptr:
+		// Unit variant without value.
+		buf.WriteString("{}")
+	case isEnumVariantStruct(field.Type()):
+		if err := marshalAnchorJSONStruct(buf, field, isTupleVariantType(field.Type())); err != nil {
+			return fmt.Errorf("variant %s: %w", structField.Name, err)
+		}
+	default:
+		// Tuple variant of a single field, which is named by its index.
+		buf.WriteString(`{"0":`)
+		if err := marshalAnchorJSON(buf, field); err != nil {
+			return fmt.Errorf("variant %s: %w", structField.Name, err)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+var (
+	anchorSimpleEnumType   = reflect.TypeOf((*AnchorSimpleEnum)(nil)).Elem()
+	anchorTupleVariantType = reflect.TypeOf((*AnchorTupleVariant)(nil)).Elem()
+)
+
+// isSimpleEnumType returns true if rt is a simple enum (see AnchorSimpleEnum).
+func isSimpleEnumType(rt reflect.Type) bool {
+	return rt.Kind() == reflect.Uint8 && rt.Implements(anchorSimpleEnumType)
+}
+
+// isTupleVariantType returns true if rt holds the fields of a tuple variant
+// (see AnchorTupleVariant).
+func isTupleVariantType(rt reflect.Type) bool {
+	return rt.Kind() == reflect.Struct && rt.Implements(anchorTupleVariantType)
+}
+
+func marshalAnchorJSONSimpleEnum(buf *bytes.Buffer, rv reflect.Value) error {
+	name := rv.Interface().(fmt.Stringer).String()
+	if name == "" {
+		return fmt.Errorf("anchor json: unknown variant %d for %s", rv.Uint(), rv.Type())
+	}
+	buf.WriteByte('{')
+	if err := writeJSONString(buf, idlFieldName(name)); err != nil {
+		return err
+	}
+	buf.WriteString(":{}}")
+	return nil
+}
+
+// isEnumVariantStruct returns true if the enum variant field is
+// a struct holding the named fields of the variant.
+func isEnumVariantStruct(rt reflect.Type) bool {
+	return rt.Kind() == reflect.Struct && rt != uint128Type && rt != int128Type && !isComplexEnumType(rt)
+}
+
+func unmarshalAnchorJSON(node interface{}, rv reflect.Value) error {
+	if rv.Kind() == reflect.Ptr {
+		if node == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return unmarshalAnchorJSON(node, rv.Elem())
+	}
+	if node == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	switch rv.Type() {
+	case uint128Type, int128Type:
+		n, err := anchorJSONBigInt(node)
+		if err != nil {
+			return err
+		}
+		value, err := uint128FromBigInt(n, rv.Type() == int128Type)
+		if err != nil {
+			return err
+		}
+		if rv.Type() == int128Type {
+			rv.Set(reflect.ValueOf(Int128(value)))
+		} else {
+			rv.Set(reflect.ValueOf(value))
+		}
+		return nil
+	}
+
+	if obj, ok := node.(map[string]interface{}); ok && isSimpleEnumType(rv.Type()) {
+		return unmarshalAnchorJSONSimpleEnum(obj, rv)
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		b, ok := node.(bool)
+		if !ok {
+			return fmt.Errorf("anchor json: expected bool, got %T", node)
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s, err := anchorJSONNumber(node)
+		if err != nil {
+			return err
+		}
+		n, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("anchor json: %w", err)
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s, err := anchorJSONNumber(node)
+		if err != nil {
+			return err
+		}
+		n, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("anchor json: %w", err)
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		s, err := anchorJSONNumber(node)
+		if err != nil {
+			return err
+		}
+		f, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("anchor json: %w", err)
+		}
+		rv.SetFloat(f)
+	case reflect.String:
+		s, ok := node.(string)
+		if !ok {
+			return fmt.Errorf("anchor json: expected string, got %T", node)
+		}
+		rv.SetString(s)
+	case reflect.Array:
+		if s, ok := node.(string); ok && isPublicKeyType(rv.Type()) {
+			key, err := base58Decode(s)
+			if err != nil {
+				return err
+			}
+			if len(key) != rv.Len() {
+				return fmt.Errorf("anchor json: public key %q has %d bytes, expected %d", s, len(key), rv.Len())
+			}
+			reflect.Copy(rv, reflect.ValueOf(key))
+			return nil
+		}
+		list, ok := node.([]interface{})
+		if !ok {
+			return fmt.Errorf("anchor json: expected array, got %T", node)
+		}
+		if len(list) != rv.Len() {
+			return fmt.Errorf("anchor json: expected array of %d elements, got %d", rv.Len(), len(list))
+		}
+		for i, elem := range list {
+			if err := unmarshalAnchorJSON(elem, rv.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+	case reflect.Slice:
+		list, ok := node.([]interface{})
+		if !ok {
+			return fmt.Errorf("anchor json: expected array, got %T", node)
+		}
+		if len(list) == 0 {
+			// Empty slices are left nil, like in the borsh decoder.
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		rv.Set(reflect.MakeSlice(rv.Type(), len(list), len(list)))
+		for i, elem := range list {
+			if err := unmarshalAnchorJSON(elem, rv.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+	case reflect.Struct:
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("anchor json: expected object, got %T", node)
+		}
+		if isComplexEnumType(rv.Type()) {
+			return unmarshalAnchorJSONEnum(obj, rv)
+		}
+		return unmarshalAnchorJSONStruct(obj, rv, false)
+	default:
+		return fmt.Errorf("anchor json: unsupported type %s", rv.Type())
+	}
+	return nil
+}
+
+// unmarshalAnchorJSONStruct reads the fields of a struct keyed by their
+// camelCase name, or by their index if tuple is true.
+func unmarshalAnchorJSONStruct(obj map[string]interface{}, rv reflect.Value, tuple bool) error {
+	rt := rv.Type()
+	index := 0
+	for i := 0; i < rt.NumField(); i++ {
+		structField := rt.Field(i)
+		fieldTag := parseFieldTag(structField.Tag)
+		if fieldTag.Skip || structField.PkgPath != "" {
+			continue
+		}
+		name := idlFieldName(structField.Name)
+		if tuple {
+			name = strconv.Itoa(index)
+		}
+		index++
+		node, found := obj[name]
+		if !found {
+			if fieldTag.Optional {
+				continue
+			}
+			return fmt.Errorf("anchor json: missing field %q", name)
+		}
+		if err := unmarshalAnchorJSON(node, rv.Field(i)); err != nil {
+			return fmt.Errorf("field %s: %w", structField.Name, err)
+		}
+	}
+	return nil
+}
+
+func unmarshalAnchorJSONEnum(obj map[string]interface{}, rv reflect.Value) error {
+	if len(obj) != 1 {
+		return fmt.Errorf("anchor json: expected enum object with exactly one key, got %d", len(obj))
+	}
+	rt := rv.Type()
+	for name, payload := range obj {
+		for i := 1; i < rt.NumField(); i++ {
+			structField := rt.Field(i)
+			if idlFieldName(structField.Name) != name {
+				continue
+			}
+			rv.Field(0).SetUint(uint64(i - 1))
+
+			field := rv.Field(i)
+			if field.Kind() == reflect.Ptr {
+				field.Set(reflect.New(field.Type().Elem()))
+				field = field.Elem()
+			}
+			fields, ok := payload.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("anchor json: variant %s: expected object, got %T", structField.Name, payload)
+			}
+			if isEnumVariantStruct(field.Type()) {
+				if err := unmarshalAnchorJSONStruct(fields, field, isTupleVariantType(field.Type())); err != nil {
+					return fmt.Errorf("variant %s: %w", structField.Name, err)
+				}
+				return nil
+			}
+			if err := unmarshalAnchorJSON(fields["0"], field); err != nil {
+				return fmt.Errorf("variant %s: %w", structField.Name, err)
+			}
+			return nil
+		}
+		return fmt.Errorf("anchor json: unknown variant %q for %s", name, rt)
+	}
+	return nil
+}
+
+func unmarshalAnchorJSONSimpleEnum(obj map[string]interface{}, rv reflect.Value) error {
+	if len(obj) != 1 {
+		return fmt.Errorf("anchor json: expected enum object with exactly one key, got %d", len(obj))
+	}
+	rt := rv.Type()
+	for name, payload := range obj {
+		if fields, ok := payload.(map[string]interface{}); !ok || len(fields) > 0 {
+			return fmt.Errorf("anchor json: variant %s: expected empty object, got %v", name, payload)
+		}
+		variant := reflect.New(rt).Elem()
+		for value := 0; value <= math.MaxUint8; value++ {
+			variant.SetUint(uint64(value))
+			if variantName := variant.Interface().(fmt.Stringer).String(); variantName != "" && idlFieldName(variantName) == name {
+				rv.Set(variant)
+				return nil
+			}
+		}
+		return fmt.Errorf("anchor json: unknown variant %q for %s", name, rt)
+	}
+	return nil
+}
+
+// anchorJSONNumber returns the textual representation of a JSON number,
+// or of a decimal string.
+func anchorJSONNumber(node interface{}) (string, error) {
+	switch n := node.(type) {
+	case json.Number:
+		return n.String(), nil
+	case string:
+		return n, nil
+	default:
+		return "", fmt.Errorf("anchor json: expected number, got %T", node)
+	}
+}
+
+func anchorJSONBigInt(node interface{}) (*big.Int, error) {
+	s, err := anchorJSONNumber(node)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("anchor json: invalid integer %q", s)
+	}
+	return n, nil
+}
+
+var (
+	twoPow128   = new(big.Int).Lsh(big.NewInt(1), 128)
+	twoPow127   = new(big.Int).Lsh(big.NewInt(1), 127)
+	uint64Mask  = new(big.Int).SetUint64(math.MaxUint64)
+	minusPow127 = new(big.Int).Neg(twoPow127)
+)
+
+// uint128FromBigInt converts n to the (two's complement, if signed)
+// 128 bits representation.
+func uint128FromBigInt(n *big.Int, signed bool) (Uint128, error) {
+	if signed {
+		if n.Cmp(minusPow127) < 0 || n.Cmp(twoPow127) >= 0 {
+			return Uint128{}, fmt.Errorf("anchor json: %s overflows i128", n)
+		}
+		if n.Sign() < 0 {
+			n = new(big.Int).Add(n, twoPow128)
+		}
+	} else if n.Sign() < 0 || n.Cmp(twoPow128) >= 0 {
+		return Uint128{}, fmt.Errorf("anchor json: %s overflows u128", n)
+	}
+	return Uint128{
+		Lo: new(big.Int).And(n, uint64Mask).Uint64(),
+		Hi: new(big.Int).Rsh(n, 64).Uint64(),
+	}, nil
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Radix = big.NewInt(58)
+
+func base58Encode(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	mod := new(big.Int)
+	out := make([]byte, 0, len(data)*138/100+1)
+	for n.Sign() > 0 {
+		n.DivMod(n, base58Radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	ReverseBytes(out)
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	for i := 0; i < len(s); i++ {
+		index := bytes.IndexByte([]byte(base58Alphabet), s[i])
+		if index < 0 {
+			return nil, fmt.Errorf("base58: invalid character %q", s[i])
+		}
+		n.Mul(n, base58Radix)
+		n.Add(n, big.NewInt(int64(index)))
+	}
+	out := n.Bytes()
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), out...), nil
+}
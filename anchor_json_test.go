@@ -0,0 +1,273 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnchorJSON(t *testing.T) {
+	var authority [32]byte
+	authority[31] = 1
+	vault := idlTestVault{
+		Authority: authority,
+		Balance:   Uint128{Lo: 0, Hi: 1},
+		Debt:      Int128{Lo: 0xfffffffffffffffe, Hi: 0xffffffffffffffff},
+		Fees:      [4]uint16{1, 2, 3, 4},
+		Memo:      "hello",
+		Data:      []byte{0xde, 0xad},
+		History: []idlTestEntry{
+			{Slot: 18446744073709551615, Amount: -5},
+		},
+		LastAction: idlTestAction{
+			Enum:    1,
+			Deposit: idlTestEntry{Slot: 7, Amount: 8},
+		},
+		URLChecksum: 42,
+	}
+
+	out, err := MarshalAnchorJSON(vault)
+	require.NoError(t, err)
+	assert.Equal(t,
+		`{"authority":"11111111111111111111111111111112","delegate":null,`+
+			`"balance":"18446744073709551616","debt":"-2","fees":[1,2,3,4],`+
+			`"memo":"hello","data":[222,173],"history":[{"slot":"18446744073709551615","amount":"-5"}],`+
+			`"lastAction":{"deposit":{"slot":"7","amount":"8"}},"urlChecksum":42}`,
+		string(out),
+	)
+
+	var decoded idlTestVault
+	require.NoError(t, UnmarshalAnchorJSON(out, &decoded))
+	assert.Equal(t, vault, decoded)
+
+	// The JSON must describe the same value as the borsh encoding:
+	expected, err := MarshalBorsh(vault)
+	require.NoError(t, err)
+	got, err := MarshalBorsh(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+}
+
+func TestAnchorJSON_Variants(t *testing.T) {
+	delegate := [32]byte{}
+	tests := []struct {
+		name  string
+		value idlTestVault
+		json  string
+	}{
+		{
+			name:  "unit variant",
+			value: idlTestVault{LastAction: idlTestAction{Enum: 0}},
+			json:  `"lastAction":{"noop":{}}`,
+		},
+		{
+			name:  "tuple variant",
+			value: idlTestVault{LastAction: idlTestAction{Enum: 2, Withdraw: 99}},
+			json:  `"lastAction":{"withdraw":{"0":"99"}}`,
+		},
+		{
+			name:  "optional set",
+			value: idlTestVault{Delegate: &delegate},
+			json:  `"delegate":"11111111111111111111111111111111"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := MarshalAnchorJSON(test.value)
+			require.NoError(t, err)
+			assert.Contains(t, string(out), test.json)
+
+			var decoded idlTestVault
+			require.NoError(t, UnmarshalAnchorJSON(out, &decoded))
+			assert.Equal(t, test.value, decoded)
+		})
+	}
+}
+
+type idlTestSide BorshEnum
+
+const (
+	idlTestSideBid idlTestSide = iota
+	idlTestSideAsk
+	idlTestSideTakeProfit
+)
+
+func (value idlTestSide) String() string {
+	switch value {
+	case idlTestSideBid:
+		return "Bid"
+	case idlTestSideAsk:
+		return "Ask"
+	case idlTestSideTakeProfit:
+		return "TakeProfit"
+	default:
+		return ""
+	}
+}
+
+func (idlTestSide) AnchorSimpleEnum() {}
+
+// idlTestLevel has a String method, but is not a simple enum.
+type idlTestLevel uint8
+
+func (value idlTestLevel) String() string {
+	return "level"
+}
+
+func TestAnchorJSON_SimpleEnum(t *testing.T) {
+	type order struct {
+		Side  idlTestSide
+		Sides []idlTestSide
+	}
+
+	value := order{Side: idlTestSideAsk, Sides: []idlTestSide{idlTestSideTakeProfit, idlTestSideBid}}
+	out, err := MarshalAnchorJSON(value)
+	require.NoError(t, err)
+	assert.Equal(t, `{"side":{"ask":{}},"sides":[{"takeProfit":{}},{"bid":{}}]}`, string(out))
+
+	var decoded order
+	require.NoError(t, UnmarshalAnchorJSON(out, &decoded))
+	assert.Equal(t, value, decoded)
+
+	// The numbers are still accepted:
+	require.NoError(t, UnmarshalAnchorJSON([]byte(`{"side":1,"sides":[2,0]}`), &decoded))
+	assert.Equal(t, value, decoded)
+
+	_, err = MarshalAnchorJSON(order{Side: 7})
+	assert.EqualError(t, err, `field Side: anchor json: unknown variant 7 for bin.idlTestSide`)
+	assert.EqualError(t,
+		UnmarshalAnchorJSON([]byte(`{"side":{"sell":{}},"sides":[]}`), &decoded),
+		`field Side: anchor json: unknown variant "sell" for bin.idlTestSide`,
+	)
+	assert.EqualError(t,
+		UnmarshalAnchorJSON([]byte(`{"side":{"ask":{"0":1}},"sides":[]}`), &decoded),
+		`field Side: anchor json: variant ask: expected empty object, got map[0:1]`,
+	)
+}
+
+func TestAnchorJSON_NotSimpleEnum(t *testing.T) {
+	type value struct {
+		Level idlTestLevel
+	}
+
+	out, err := MarshalAnchorJSON(value{Level: 3})
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":3}`, string(out))
+
+	var decoded value
+	require.NoError(t, UnmarshalAnchorJSON(out, &decoded))
+	assert.Equal(t, value{Level: 3}, decoded)
+}
+
+type idlTestPair struct {
+	Elem0 uint16
+	Elem1 bool
+}
+
+func (idlTestPair) AnchorTupleVariant() {}
+
+type idlTestFee struct {
+	Enum   BorshEnum `borsh_enum:"true"`
+	None   idlTestEmpty
+	SetFee idlTestPair
+	Named  idlTestPair2
+}
+
+type idlTestPair2 idlTestPair
+
+func TestAnchorJSON_TupleVariant(t *testing.T) {
+	tests := []struct {
+		name  string
+		value idlTestFee
+		json  string
+	}{
+		{
+			name:  "tuple",
+			value: idlTestFee{Enum: 1, SetFee: idlTestPair{Elem0: 30, Elem1: true}},
+			json:  `{"setFee":{"0":30,"1":true}}`,
+		},
+		{
+			name:  "named",
+			value: idlTestFee{Enum: 2, Named: idlTestPair2{Elem0: 30, Elem1: true}},
+			json:  `{"named":{"elem0":30,"elem1":true}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := MarshalAnchorJSON(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.json, string(out))
+
+			var decoded idlTestFee
+			require.NoError(t, UnmarshalAnchorJSON(out, &decoded))
+			assert.Equal(t, test.value, decoded)
+		})
+	}
+
+	assert.EqualError(t,
+		UnmarshalAnchorJSON([]byte(`{"setFee":{"0":30}}`), &idlTestFee{}),
+		`variant SetFee: anchor json: missing field "1"`,
+	)
+}
+
+func TestAnchorJSON_Unmarshal(t *testing.T) {
+	type value struct {
+		Small  uint8
+		Large  uint64
+		Signed Int128
+	}
+
+	// Integers are accepted both as numbers and strings.
+	var v value
+	require.NoError(t, UnmarshalAnchorJSON([]byte(`{"small":"3","large":12,"signed":-1}`), &v))
+	assert.Equal(t, value{Small: 3, Large: 12, Signed: Int128{Lo: 0xffffffffffffffff, Hi: 0xffffffffffffffff}}, v)
+
+	assert.EqualError(t,
+		UnmarshalAnchorJSON([]byte(`{"small":256,"large":0,"signed":0}`), &v),
+		`field Small: anchor json: strconv.ParseUint: parsing "256": value out of range`,
+	)
+	assert.EqualError(t,
+		UnmarshalAnchorJSON([]byte(`{"small":1,"signed":0}`), &v),
+		`anchor json: missing field "large"`,
+	)
+	assert.EqualError(t,
+		UnmarshalAnchorJSON([]byte(`{"noop":{},"withdraw":{"0":1}}`), &idlTestAction{}),
+		`anchor json: expected enum object with exactly one key, got 2`,
+	)
+	assert.EqualError(t,
+		UnmarshalAnchorJSON([]byte(`{"burn":{}}`), &idlTestAction{}),
+		`anchor json: unknown variant "burn" for bin.idlTestAction`,
+	)
+	assert.Error(t, UnmarshalAnchorJSON([]byte(`{}`), value{}))
+}
+
+func TestBase58(t *testing.T) {
+	for _, data := range [][]byte{
+		{},
+		{0},
+		{0, 0, 1},
+		{0xff, 0xee, 0x00, 0x01},
+	} {
+		decoded, err := base58Decode(base58Encode(data))
+		require.NoError(t, err)
+		assert.Equal(t, data, decoded)
+	}
+	assert.Equal(t, "2g", base58Encode([]byte{'a'}))
+	_, err := base58Decode("0OIl")
+	assert.EqualError(t, err, `base58: invalid character '0'`)
+}
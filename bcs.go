@@ -0,0 +1,150 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// BCSMaxSequenceLength is the maximum length of a sequence (or map) in BCS.
+const BCSMaxSequenceLength = 1<<31 - 1
+
+// WriteULEB128 writes v as an unsigned LEB128, as used by BCS
+// for sequence lengths and enum variant indexes.
+func (e *Encoder) WriteULEB128(v uint32) (err error) {
+	if traceEnabled {
+		zlog.Debug("encode: write uleb128", zap.Uint32("val", v))
+	}
+	buf := make([]byte, 0, 5)
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	buf = append(buf, byte(v))
+	return e.toWriter(buf)
+}
+
+// ReadULEB128 reads an unsigned LEB128 value that fits in 32 bits,
+// rejecting non-canonical (non-minimal) encodings as required by BCS.
+func (dec *Decoder) ReadULEB128() (out uint32, err error) {
+	var value uint64
+	for shift := uint(0); shift < 35; shift += 7 {
+		b, err := dec.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("uleb128: %w", err)
+		}
+		value |= uint64(b&0x7f) << shift
+		if b&0x80 != 0 {
+			continue
+		}
+		if b == 0 && shift > 0 {
+			return 0, errors.New("uleb128: non-canonical encoding")
+		}
+		if value > 0xffffffff {
+			return 0, errors.New("uleb128: value overflows u32")
+		}
+		if traceEnabled {
+			zlog.Debug("decode: read uleb128", zap.Uint64("val", value))
+		}
+		return uint32(value), nil
+	}
+	return 0, errors.New("uleb128: value overflows u32")
+}
+
+// bcsEncoding is the definition of BCS: little-endian integers, ULEB128
+// lengths and enum tags, no floats, and maps in canonical order.
+type bcsEncoding struct{ noEncodingRules }
+
+func (bcsEncoding) Name() string                { return EncodingBCS.String() }
+func (bcsEncoding) ByteOrder() binary.ByteOrder { return binary.LittleEndian }
+
+func (bcsEncoding) WriteLength(enc *Encoder, length int) error { return enc.WriteLength(length) }
+func (bcsEncoding) ReadLength(dec *Decoder) (int, error)       { return dec.ReadLength() }
+
+func (bcsEncoding) WriteOptionTag(enc *Encoder, isPresent bool) error {
+	return enc.WriteBool(isPresent)
+}
+func (bcsEncoding) ReadOptionTag(dec *Decoder) (bool, error) { return dec.ReadBool() }
+
+func (bcsEncoding) WriteEnumTag(enc *Encoder, index uint32) error { return enc.WriteULEB128(index) }
+func (bcsEncoding) ReadEnumTag(dec *Decoder) (uint32, error)      { return dec.ReadULEB128() }
+
+func (bcsEncoding) WriteString(enc *Encoder, s string) error { return enc.WriteString(s) }
+func (bcsEncoding) ReadString(dec *Decoder) (string, error)  { return dec.readUTF8String("bcs", nil) }
+
+func (bcsEncoding) WriteBool(enc *Encoder, b bool) error { return enc.WriteBool(b) }
+func (bcsEncoding) ReadBool(dec *Decoder) (bool, error)  { return dec.ReadBool() }
+
+// SortMapKeys sorts the keys by the lexicographic order of
+// their serialization, as required by BCS.
+func (bcsEncoding) SortMapKeys(keys []reflect.Value) {
+	type entry struct {
+		key        reflect.Value
+		serialized []byte
+	}
+	entries := make([]entry, len(keys))
+	for i, key := range keys {
+		buf := new(bytes.Buffer)
+		// A key that fails to encode fails again with its entry:
+		_ = NewBCSEncoder(buf).Encode(key.Interface())
+		entries[i] = entry{key: key, serialized: buf.Bytes()}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].serialized, entries[j].serialized) < 0
+	})
+	for i := range entries {
+		keys[i] = entries[i].key
+	}
+}
+
+func (bcsEncoding) checkMapKey(prev, key []byte) error {
+	if prev != nil && bytes.Compare(prev, key) >= 0 {
+		return errors.New("bcs: map keys are not in canonical order")
+	}
+	return nil
+}
+
+func (bcsEncoding) encodeValue(enc *Encoder, rv reflect.Value, opt *option) (bool, error) {
+	if isBinaryMarshaler(rv) {
+		return false, nil
+	}
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return true, fmt.Errorf("encode: bcs does not support floating point type %q", rv.Type())
+	}
+	return enc.writeLittleEndian(rv)
+}
+
+func (bcsEncoding) decodeValue(dec *Decoder, rv reflect.Value, opt *option, hasUnmarshaler bool) (bool, error) {
+	if hasUnmarshaler {
+		return false, nil
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		s, err := dec.readUTF8String("bcs", opt)
+		rv.SetString(s)
+		return true, err
+	case reflect.Float32, reflect.Float64:
+		return true, fmt.Errorf("decode: bcs does not support floating point type %q", rv.Type())
+	}
+	return dec.readLittleEndian(rv)
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bcsTestEnum struct {
+	Enum     BorshEnum `borsh_enum:"true"`
+	Variant0 uint16
+	Variant1 uint8
+	Variant2 string
+}
+
+type bcsTestStruct struct {
+	A     uint32
+	B     []byte
+	C     *uint8 `bin:"optional"`
+	D     bool
+	E     bcsTestEnum
+	F     map[uint16]string
+	G     Uint128
+	H     [2]int8
+	Inner *bcsTestInner
+}
+
+type bcsTestInner struct {
+	Name string
+}
+
+func TestBCS_Encode(t *testing.T) {
+	c := uint8(8)
+	val := bcsTestStruct{
+		A: 0x12345678,
+		B: []byte{0xaa, 0xbb},
+		C: &c,
+		D: true,
+		E: bcsTestEnum{Enum: 0, Variant0: 8000},
+		// The entries are sorted by their serialized keys:
+		// 256 (0x0001) comes before 1 (0x0100).
+		F:     map[uint16]string{1: "a", 256: "b"},
+		G:     Uint128{Lo: 1},
+		H:     [2]int8{-1, 1},
+		Inner: &bcsTestInner{Name: "çå∞"},
+	}
+
+	data, err := MarshalBCS(val)
+	require.NoError(t, err)
+	assert.Equal(t, concatByteSlices(
+		[]byte{0x78, 0x56, 0x34, 0x12},
+		[]byte{0x02, 0xaa, 0xbb},
+		[]byte{0x01, 0x08},
+		[]byte{0x01},
+		[]byte{0x00, 0x40, 0x1f},
+		[]byte{0x02, 0x00, 0x01, 0x01, 'b', 0x01, 0x00, 0x01, 'a'},
+		[]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		[]byte{0xff, 0x01},
+		[]byte{0x07, 0xc3, 0xa7, 0xc3, 0xa5, 0xe2, 0x88, 0x9e},
+	), data)
+
+	count, err := BCSByteCount(val)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(data)), count)
+
+	var got bcsTestStruct
+	require.NoError(t, UnmarshalBCS(&got, data))
+	assert.Equal(t, val, got)
+}
+
+func TestBCS_Enum(t *testing.T) {
+	tests := []struct {
+		value bcsTestEnum
+		data  []byte
+	}{
+		{bcsTestEnum{Enum: 0, Variant0: 8000}, []byte{0x00, 0x40, 0x1f}},
+		{bcsTestEnum{Enum: 1, Variant1: 255}, []byte{0x01, 0xff}},
+		{bcsTestEnum{Enum: 2, Variant2: "e"}, []byte{0x02, 0x01, 0x65}},
+	}
+	for _, test := range tests {
+		data, err := MarshalBCS(test.value)
+		require.NoError(t, err)
+		assert.Equal(t, test.data, data)
+
+		var got bcsTestEnum
+		require.NoError(t, UnmarshalBCS(&got, data))
+		assert.Equal(t, test.value, got)
+	}
+}
+
+func TestBCS_ULEB128(t *testing.T) {
+	tests := []struct {
+		value uint32
+		data  []byte
+	}{
+		{0, []byte{0x00}},
+		{1, []byte{0x01}},
+		{128, []byte{0x80, 0x01}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+		{9487, []byte{0x8f, 0x4a}},
+		{0xffffffff, []byte{0xff, 0xff, 0xff, 0xff, 0x0f}},
+	}
+	for _, test := range tests {
+		enc, buf := newBCSTestEncoder()
+		require.NoError(t, enc.WriteULEB128(test.value))
+		assert.Equal(t, test.data, buf.Bytes())
+
+		got, err := NewBCSDecoder(test.data).ReadULEB128()
+		require.NoError(t, err)
+		assert.Equal(t, test.value, got)
+	}
+
+	_, err := NewBCSDecoder([]byte{0x80, 0x00}).ReadULEB128()
+	assert.EqualError(t, err, "uleb128: non-canonical encoding")
+	_, err = NewBCSDecoder([]byte{0xff, 0xff, 0xff, 0xff, 0x1f}).ReadULEB128()
+	assert.EqualError(t, err, "uleb128: value overflows u32")
+	_, err = NewBCSDecoder([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x01}).ReadULEB128()
+	assert.EqualError(t, err, "uleb128: value overflows u32")
+}
+
+func TestBCS_CanonicalDecoding(t *testing.T) {
+	var b bool
+	assert.EqualError(t, UnmarshalBCS(&b, []byte{0x02}), "bcs: invalid bool value 2")
+
+	var opt struct {
+		V *uint8 `bin:"optional"`
+	}
+	assert.Error(t, UnmarshalBCS(&opt, []byte{0x02, 0x00}))
+
+	var seq []uint8
+	assert.EqualError(t,
+		UnmarshalBCS(&seq, []byte{0x80, 0x80, 0x80, 0x80, 0x08}),
+		"bcs: sequence length 2147483648 exceeds the maximum 2147483647",
+	)
+	assert.EqualError(t,
+		UnmarshalBCS(&seq, []byte{0xff, 0xff, 0xff, 0xff, 0x07}),
+		"bcs: sequence length 2147483647 exceeds the remaining 0 bytes",
+	)
+
+	var m map[uint8]uint8
+	assert.EqualError(t,
+		UnmarshalBCS(&m, []byte{0x02, 0x02, 0x00, 0x01, 0x00}),
+		"bcs: map keys are not in canonical order",
+	)
+	assert.EqualError(t,
+		UnmarshalBCS(&m, []byte{0x02, 0x01, 0x00, 0x01, 0x00}),
+		"bcs: map keys are not in canonical order",
+	)
+
+	var s string
+	assert.EqualError(t, UnmarshalBCS(&s, []byte{0x01, 0xff}), "bcs: string is not valid utf-8")
+
+	var e bcsTestEnum
+	assert.EqualError(t, UnmarshalBCS(&e, []byte{0x03}), "complex enum too large")
+
+	_, err := MarshalBCS(float32(1))
+	assert.EqualError(t, err, `encode: bcs does not support floating point type "float32"`)
+}
+
+func newBCSTestEncoder() (*Encoder, *bytes.Buffer) {
+	buf := new(bytes.Buffer)
+	return NewBCSEncoder(buf), buf
+}
@@ -0,0 +1,269 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+// Markers of the bincode varint encoding: values up to
+// bincodeSingleByteMax are written as a single byte, larger values
+// are written as a marker followed by the little-endian value.
+const (
+	bincodeSingleByteMax = 250
+	bincodeU16Marker     = 251
+	bincodeU32Marker     = 252
+	bincodeU64Marker     = 253
+	bincodeU128Marker    = 254
+)
+
+// WriteBincodeVarint writes v in the varint encoding of bincode.
+func (e *Encoder) WriteBincodeVarint(v uint64) error {
+	return e.writeBincodeVarint128(Uint128{Lo: v})
+}
+
+func (e *Encoder) writeBincodeVarint128(v Uint128) error {
+	if traceEnabled {
+		zlog.Debug("encode: write bincode varint", zap.Stringer("val", v))
+	}
+	var buf []byte
+	switch {
+	case v.Hi != 0:
+		buf = make([]byte, 17)
+		buf[0] = bincodeU128Marker
+		binary.LittleEndian.PutUint64(buf[1:], v.Lo)
+		binary.LittleEndian.PutUint64(buf[9:], v.Hi)
+	case v.Lo <= bincodeSingleByteMax:
+		buf = []byte{byte(v.Lo)}
+	case v.Lo <= 0xffff:
+		buf = make([]byte, 3)
+		buf[0] = bincodeU16Marker
+		binary.LittleEndian.PutUint16(buf[1:], uint16(v.Lo))
+	case v.Lo <= 0xffffffff:
+		buf = make([]byte, 5)
+		buf[0] = bincodeU32Marker
+		binary.LittleEndian.PutUint32(buf[1:], uint32(v.Lo))
+	default:
+		buf = make([]byte, 9)
+		buf[0] = bincodeU64Marker
+		binary.LittleEndian.PutUint64(buf[1:], v.Lo)
+	}
+	return e.toWriter(buf)
+}
+
+// ReadBincodeVarint reads a value in the varint encoding of bincode,
+// which must fit in 64 bits.
+func (dec *Decoder) ReadBincodeVarint() (uint64, error) {
+	v, err := dec.readBincodeVarint128()
+	if err != nil {
+		return 0, err
+	}
+	if v.Hi != 0 {
+		return 0, fmt.Errorf("bincode: varint %s overflows u64", v)
+	}
+	return v.Lo, nil
+}
+
+func (dec *Decoder) readBincodeVarint128() (out Uint128, err error) {
+	marker, err := dec.ReadByte()
+	if err != nil {
+		return out, err
+	}
+	switch marker {
+	case bincodeU16Marker:
+		var v uint16
+		v, err = dec.ReadUint16(LE)
+		out.Lo = uint64(v)
+	case bincodeU32Marker:
+		var v uint32
+		v, err = dec.ReadUint32(LE)
+		out.Lo = uint64(v)
+	case bincodeU64Marker:
+		out.Lo, err = dec.ReadUint64(LE)
+	case bincodeU128Marker:
+		out, err = dec.ReadUint128(LE)
+	case 255:
+		err = fmt.Errorf("bincode: invalid varint marker %d", marker)
+	default:
+		out.Lo = uint64(marker)
+	}
+	if err != nil {
+		return Uint128{}, err
+	}
+	if traceEnabled {
+		zlog.Debug("decode: read bincode varint", zap.Stringer("val", out))
+	}
+	return out, nil
+}
+
+// readBincodeUvarint reads an unsigned varint of the provided bit size.
+func (dec *Decoder) readBincodeUvarint(bitSize int) (uint64, error) {
+	v, err := dec.ReadBincodeVarint()
+	if err != nil {
+		return 0, err
+	}
+	if bitSize < 64 && v>>uint(bitSize) != 0 {
+		return 0, fmt.Errorf("bincode: varint %d overflows u%d", v, bitSize)
+	}
+	return v, nil
+}
+
+// readBincodeVarint reads a zigzag-encoded signed varint of the provided bit size.
+func (dec *Decoder) readBincodeVarint(bitSize int) (int64, error) {
+	v, err := dec.readBincodeUvarint(bitSize)
+	if err != nil {
+		return 0, err
+	}
+	return zigzagDecode(v), nil
+}
+
+func zigzagEncode(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func zigzagDecode(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// zigzagEncode128 zigzag-encodes the two's complement 128 bits integer v.
+func zigzagEncode128(v Int128) Uint128 {
+	sign := uint64(int64(v.Hi) >> 63)
+	return Uint128{
+		Lo: v.Lo<<1 ^ sign,
+		Hi: (v.Hi<<1 | v.Lo>>63) ^ sign,
+	}
+}
+
+func zigzagDecode128(v Uint128) Int128 {
+	sign := -(v.Lo & 1)
+	return Int128{
+		Lo: (v.Lo>>1 | v.Hi<<63) ^ sign,
+		Hi: v.Hi>>1 ^ sign,
+	}
+}
+
+// bincodeEncoding is the definition of bincode: little-endian integers,
+// u64 lengths and u32 enum tags, or varints with varint set.
+type bincodeEncoding struct {
+	noEncodingRules
+	varint bool
+}
+
+func (def bincodeEncoding) Name() string {
+	if def.varint {
+		return EncodingBincodeVarint.String()
+	}
+	return EncodingBincode.String()
+}
+
+func (bincodeEncoding) ByteOrder() binary.ByteOrder { return binary.LittleEndian }
+
+func (bincodeEncoding) WriteLength(enc *Encoder, length int) error { return enc.WriteLength(length) }
+func (bincodeEncoding) ReadLength(dec *Decoder) (int, error)       { return dec.ReadLength() }
+
+func (bincodeEncoding) WriteOptionTag(enc *Encoder, isPresent bool) error {
+	return enc.WriteBool(isPresent)
+}
+func (bincodeEncoding) ReadOptionTag(dec *Decoder) (bool, error) { return dec.ReadBool() }
+
+func (def bincodeEncoding) WriteEnumTag(enc *Encoder, index uint32) error {
+	if def.varint {
+		return enc.WriteBincodeVarint(uint64(index))
+	}
+	return enc.WriteUint32(index, LE)
+}
+
+func (def bincodeEncoding) ReadEnumTag(dec *Decoder) (uint32, error) {
+	if def.varint {
+		tag, err := dec.readBincodeUvarint(32)
+		return uint32(tag), err
+	}
+	return dec.ReadUint32(LE)
+}
+
+func (bincodeEncoding) WriteString(enc *Encoder, s string) error { return enc.WriteString(s) }
+func (bincodeEncoding) ReadString(dec *Decoder) (string, error) {
+	return dec.readUTF8String("bincode", nil)
+}
+
+func (bincodeEncoding) WriteBool(enc *Encoder, b bool) error { return enc.WriteBool(b) }
+func (bincodeEncoding) ReadBool(dec *Decoder) (bool, error)  { return dec.ReadBool() }
+
+func (bincodeEncoding) SortMapKeys(keys []reflect.Value) { SortMapKeysByValue(keys) }
+
+func (def bincodeEncoding) encodeValue(enc *Encoder, rv reflect.Value, opt *option) (bool, error) {
+	if iv := reflect.Indirect(rv); def.varint && iv.IsValid() {
+		// 128 bits integers are varints too:
+		switch v := iv.Interface().(type) {
+		case Uint128:
+			return true, enc.writeBincodeVarint128(v)
+		case Int128:
+			return true, enc.writeBincodeVarint128(zigzagEncode128(v))
+		}
+	}
+	if isBinaryMarshaler(rv) {
+		return false, nil
+	}
+	if def.varint {
+		switch rv.Kind() {
+		case reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+			return true, enc.WriteBincodeVarint(zigzagEncode(rv.Int()))
+		case reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+			return true, enc.WriteBincodeVarint(rv.Uint())
+		}
+	}
+	return enc.writeLittleEndian(rv)
+}
+
+func (def bincodeEncoding) decodeValue(dec *Decoder, rv reflect.Value, opt *option, hasUnmarshaler bool) (bool, error) {
+	if def.varint {
+		// 128 bits integers are varints too:
+		switch rv.Type() {
+		case uint128Type:
+			v, err := dec.readBincodeVarint128()
+			rv.Set(reflect.ValueOf(v))
+			return true, err
+		case int128Type:
+			v, err := dec.readBincodeVarint128()
+			rv.Set(reflect.ValueOf(zigzagDecode128(v)))
+			return true, err
+		}
+	}
+	if hasUnmarshaler {
+		return false, nil
+	}
+	if rv.Kind() == reflect.String {
+		s, err := dec.readUTF8String("bincode", opt)
+		rv.SetString(s)
+		return true, err
+	}
+	if def.varint {
+		switch rv.Kind() {
+		case reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+			n, err := dec.readBincodeVarint(rv.Type().Bits())
+			rv.SetInt(n)
+			return true, err
+		case reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+			n, err := dec.readBincodeUvarint(rv.Type().Bits())
+			rv.SetUint(n)
+			return true, err
+		}
+	}
+	return dec.readLittleEndian(rv)
+}
@@ -0,0 +1,295 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A subset of the instructions of the System and Stake native programs,
+// which are serialized with the default configuration of bincode.
+
+type bincodeSystemInstruction struct {
+	Enum                  BorshEnum `borsh_enum:"true"`
+	CreateAccount         bincodeCreateAccount
+	Assign                [32]byte
+	Transfer              uint64
+	CreateAccountWithSeed bincodeCreateAccountWithSeed
+}
+
+type bincodeCreateAccount struct {
+	Lamports uint64
+	Space    uint64
+	Owner    [32]byte
+}
+
+type bincodeCreateAccountWithSeed struct {
+	Base     [32]byte
+	Seed     string
+	Lamports uint64
+	Space    uint64
+	Owner    [32]byte
+}
+
+type bincodeStakeInstruction struct {
+	Enum          BorshEnum `borsh_enum:"true"`
+	Initialize    bincodeStakeInitialize
+	Authorize     bincodeStakeAuthorize
+	DelegateStake struct{}
+	Split         uint64
+}
+
+type bincodeStakeInitialize struct {
+	Staker     [32]byte
+	Withdrawer [32]byte
+	Lockup     struct {
+		UnixTimestamp int64
+		Epoch         uint64
+		Custodian     [32]byte
+	}
+}
+
+type bincodeStakeAuthorize struct {
+	NewAuthority [32]byte
+	// 0 for Staker, 1 for Withdrawer.
+	StakeAuthorize uint32
+}
+
+func TestBincode_NativeInstructions(t *testing.T) {
+	stakeProgram := [32]byte{}
+	copy(stakeProgram[:], mustHex("06a1d8179137542a983437bdfe2a7ab2557f535c8a78722b68a49dc000000000"))
+	key := [32]byte{}
+	for i := range key {
+		key[i] = byte(i + 1)
+	}
+	keyHex := hex.EncodeToString(key[:])
+	zeros := hex.EncodeToString(make([]byte, 32))
+
+	tests := []struct {
+		name  string
+		value interface{}
+		hex   string
+	}{
+		{
+			name:  "system transfer",
+			value: &bincodeSystemInstruction{Enum: 2, Transfer: 1000000000},
+			hex:   "02000000" + "00ca9a3b00000000",
+		},
+		{
+			name: "system create account",
+			value: &bincodeSystemInstruction{Enum: 0, CreateAccount: bincodeCreateAccount{
+				Lamports: 2282880,
+				Space:    200,
+				Owner:    stakeProgram,
+			}},
+			hex: "00000000" + "80d5220000000000" + "c800000000000000" + "06a1d8179137542a983437bdfe2a7ab2557f535c8a78722b68a49dc000000000",
+		},
+		{
+			name: "system create account with seed",
+			value: &bincodeSystemInstruction{Enum: 3, CreateAccountWithSeed: bincodeCreateAccountWithSeed{
+				Base:     key,
+				Seed:     "stake:0",
+				Lamports: 2282880,
+				Space:    200,
+				Owner:    stakeProgram,
+			}},
+			hex: "03000000" + keyHex + "0700000000000000" + "7374616b653a30" + "80d5220000000000" + "c800000000000000" + "06a1d8179137542a983437bdfe2a7ab2557f535c8a78722b68a49dc000000000",
+		},
+		{
+			name: "stake initialize",
+			value: &bincodeStakeInstruction{Enum: 0, Initialize: bincodeStakeInitialize{
+				Staker:     key,
+				Withdrawer: key,
+			}},
+			hex: "00000000" + keyHex + keyHex + "0000000000000000" + "0000000000000000" + zeros,
+		},
+		{
+			name:  "stake authorize withdrawer",
+			value: &bincodeStakeInstruction{Enum: 1, Authorize: bincodeStakeAuthorize{NewAuthority: key, StakeAuthorize: 1}},
+			hex:   "01000000" + keyHex + "01000000",
+		},
+		{
+			name:  "stake delegate",
+			value: &bincodeStakeInstruction{Enum: 2},
+			hex:   "02000000",
+		},
+		{
+			name:  "stake split",
+			value: &bincodeStakeInstruction{Enum: 3, Split: 500000000},
+			hex:   "03000000" + "0065cd1d00000000",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := MarshalBincode(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.hex, hex.EncodeToString(data))
+
+			switch expected := test.value.(type) {
+			case *bincodeSystemInstruction:
+				var got bincodeSystemInstruction
+				require.NoError(t, UnmarshalBincode(&got, data))
+				assert.Equal(t, *expected, got)
+			case *bincodeStakeInstruction:
+				var got bincodeStakeInstruction
+				require.NoError(t, UnmarshalBincode(&got, data))
+				assert.Equal(t, *expected, got)
+			}
+		})
+	}
+}
+
+type bincodeTestStruct struct {
+	A int16
+	B uint32
+	C int64
+	D Uint128
+	E Int128
+	F *uint64 `bin:"optional"`
+	G []string
+	H map[uint8]bool
+	I float64
+}
+
+func TestBincode_Fixint(t *testing.T) {
+	f := uint64(7)
+	val := bincodeTestStruct{
+		A: -2,
+		B: 300,
+		C: -1,
+		D: Uint128{Lo: 1},
+		E: Int128{Lo: math.MaxUint64, Hi: math.MaxUint64},
+		F: &f,
+		G: []string{"ab"},
+		H: map[uint8]bool{2: true, 1: false},
+		I: 1.5,
+	}
+	data, err := MarshalBincode(val)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"feff"+
+			"2c010000"+
+			"ffffffffffffffff"+
+			"01000000000000000000000000000000"+
+			"ffffffffffffffffffffffffffffffff"+
+			"01"+"0700000000000000"+
+			"0100000000000000"+"0200000000000000"+"6162"+
+			"0200000000000000"+"0100"+"0201"+
+			"000000000000f83f",
+		hex.EncodeToString(data),
+	)
+
+	var got bincodeTestStruct
+	require.NoError(t, UnmarshalBincode(&got, data))
+	assert.Equal(t, val, got)
+
+	assert.EqualError(t, UnmarshalBincode(&got.H, []byte{0x01, 0, 0, 0, 0, 0, 0, 0, 0x01, 0x02}), "bincode: invalid bool value 2")
+	assert.EqualError(t, UnmarshalBincode(&got.G, []byte{0xff, 0xff, 0, 0, 0, 0, 0, 0}), "bincode: length 65535 exceeds the remaining 0 bytes")
+}
+
+func TestBincode_Varint(t *testing.T) {
+	f := uint64(7)
+	val := bincodeTestStruct{
+		A: -2,
+		B: 300,
+		C: -200,
+		D: Uint128{Lo: 0, Hi: 1},
+		E: Int128{Lo: math.MaxUint64, Hi: math.MaxUint64},
+		F: &f,
+		G: []string{"ab"},
+		H: map[uint8]bool{1: true},
+		I: 1.5,
+	}
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, NewBincodeVarintEncoder(buf).Encode(val))
+	assert.Equal(t,
+		"03"+
+			"fb2c01"+
+			"fb8f01"+
+			"fe"+"00000000000000000100000000000000"+
+			"01"+
+			"01"+"07"+
+			"01"+"02"+"6162"+
+			"01"+"0101"+
+			"000000000000f83f",
+		hex.EncodeToString(buf.Bytes()),
+	)
+
+	var got bincodeTestStruct
+	require.NoError(t, NewBincodeVarintDecoder(buf.Bytes()).Decode(&got))
+	assert.Equal(t, val, got)
+
+	// Also through pointers:
+	buf.Reset()
+	require.NoError(t, NewBincodeVarintEncoder(buf).Encode(&Uint128{Lo: 251}))
+	assert.Equal(t, "fbfb00", hex.EncodeToString(buf.Bytes()))
+
+	// Enum tags are varints too:
+	buf.Reset()
+	require.NoError(t, NewBincodeVarintEncoder(buf).Encode(bincodeStakeInstruction{Enum: 3, Split: 1 << 32}))
+	assert.Equal(t, "03"+"fd0000000001000000", hex.EncodeToString(buf.Bytes()))
+}
+
+func TestBincode_VarintValues(t *testing.T) {
+	tests := []struct {
+		value uint64
+		hex   string
+	}{
+		{0, "00"},
+		{250, "fa"},
+		{251, "fbfb00"},
+		{65535, "fbffff"},
+		{65536, "fc00000100"},
+		{math.MaxUint32, "fcffffffff"},
+		{math.MaxUint32 + 1, "fd0000000001000000"},
+	}
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		require.NoError(t, NewBincodeVarintEncoder(buf).WriteBincodeVarint(test.value))
+		assert.Equal(t, test.hex, hex.EncodeToString(buf.Bytes()))
+
+		got, err := NewBincodeVarintDecoder(buf.Bytes()).ReadBincodeVarint()
+		require.NoError(t, err)
+		assert.Equal(t, test.value, got)
+	}
+
+	for _, v := range []int64{0, -1, 1, math.MinInt64, math.MaxInt64} {
+		assert.Equal(t, v, zigzagDecode(zigzagEncode(v)))
+	}
+	assert.Equal(t, uint64(3), zigzagEncode(-2))
+	for _, v := range []Int128{{}, {Lo: 5}, {Lo: math.MaxUint64, Hi: math.MaxUint64}, {Hi: 1 << 63}} {
+		assert.Equal(t, v, zigzagDecode128(zigzagEncode128(v)))
+	}
+
+	var small uint16
+	assert.EqualError(t, NewBincodeVarintDecoder([]byte{0xfc, 0x00, 0x00, 0x01, 0x00}).Decode(&small), "bincode: varint 65536 overflows u16")
+	_, err := NewBincodeVarintDecoder([]byte{0xff}).ReadBincodeVarint()
+	assert.EqualError(t, err, "bincode: invalid varint marker 255")
+}
+
+func mustHex(s string) []byte {
+	out, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
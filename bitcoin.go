@@ -0,0 +1,138 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+// BitcoinMaxSize is the maximum length of a vector, as checked
+// by Bitcoin Core when deserializing (MAX_SIZE).
+const BitcoinMaxSize = 0x02000000
+
+// ErrNonCanonicalCompactSize is returned when decoding a CompactSize
+// that is not encoded with the least possible number of bytes.
+var ErrNonCanonicalCompactSize = errors.New("compactsize: non-canonical encoding")
+
+// WriteCompactSize writes v as a Bitcoin CompactSize: one byte below 0xfd,
+// otherwise a 0xfd, 0xfe or 0xff prefix followed by
+// a little-endian uint16, uint32 or uint64.
+func (e *Encoder) WriteCompactSize(v uint64) (err error) {
+	if traceEnabled {
+		zlog.Debug("encode: write compactsize", zap.Uint64("val", v))
+	}
+	var buf []byte
+	switch {
+	case v < 0xfd:
+		buf = []byte{byte(v)}
+	case v <= 0xffff:
+		buf = make([]byte, 3)
+		buf[0] = 0xfd
+		binary.LittleEndian.PutUint16(buf[1:], uint16(v))
+	case v <= 0xffffffff:
+		buf = make([]byte, 5)
+		buf[0] = 0xfe
+		binary.LittleEndian.PutUint32(buf[1:], uint32(v))
+	default:
+		buf = make([]byte, 9)
+		buf[0] = 0xff
+		binary.LittleEndian.PutUint64(buf[1:], v)
+	}
+	return e.toWriter(buf)
+}
+
+// ReadCompactSize reads a Bitcoin CompactSize, rejecting the values
+// that could have been encoded with fewer bytes.
+func (dec *Decoder) ReadCompactSize() (out uint64, err error) {
+	prefix, err := dec.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("compactsize: %w", err)
+	}
+	var min uint64
+	switch prefix {
+	case 0xfd:
+		var v uint16
+		v, err = dec.ReadUint16(LE)
+		out, min = uint64(v), 0xfd
+	case 0xfe:
+		var v uint32
+		v, err = dec.ReadUint32(LE)
+		out, min = uint64(v), 0x10000
+	case 0xff:
+		out, err = dec.ReadUint64(LE)
+		min = 0x100000000
+	default:
+		out = uint64(prefix)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("compactsize: %w", err)
+	}
+	if out < min {
+		return 0, ErrNonCanonicalCompactSize
+	}
+	if traceEnabled {
+		zlog.Debug("decode: read compactsize", zap.Uint64("val", out))
+	}
+	return out, nil
+}
+
+// bitcoinEncoding is the definition of the Bitcoin consensus serialization:
+// little-endian integers, and CompactSize lengths.
+type bitcoinEncoding struct{ noEncodingRules }
+
+func (bitcoinEncoding) Name() string                { return EncodingBitcoin.String() }
+func (bitcoinEncoding) ByteOrder() binary.ByteOrder { return binary.LittleEndian }
+
+func (bitcoinEncoding) WriteLength(enc *Encoder, length int) error { return enc.WriteLength(length) }
+func (bitcoinEncoding) ReadLength(dec *Decoder) (int, error)       { return dec.ReadLength() }
+
+func (bitcoinEncoding) WriteOptionTag(enc *Encoder, isPresent bool) error {
+	return enc.WriteBool(isPresent)
+}
+
+func (bitcoinEncoding) ReadOptionTag(dec *Decoder) (bool, error) {
+	isPresent, err := dec.ReadByte()
+	return isPresent != 0, err
+}
+
+func (bitcoinEncoding) WriteEnumTag(enc *Encoder, index uint32) error {
+	if index > math.MaxUint8 {
+		return fmt.Errorf("bitcoin: enum tag %d overflows u8", index)
+	}
+	return enc.WriteUint8(uint8(index))
+}
+
+func (bitcoinEncoding) ReadEnumTag(dec *Decoder) (uint32, error) {
+	tag, err := dec.ReadUint8()
+	return uint32(tag), err
+}
+
+func (bitcoinEncoding) WriteString(enc *Encoder, s string) error { return enc.WriteString(s) }
+
+func (bitcoinEncoding) ReadString(dec *Decoder) (string, error) {
+	data, err := dec.readStringWithLengthPrefix(nil)
+	return string(data), err
+}
+
+func (bitcoinEncoding) WriteBool(enc *Encoder, b bool) error { return enc.WriteBool(b) }
+func (bitcoinEncoding) ReadBool(dec *Decoder) (bool, error)  { return dec.ReadBool() }
+
+func (bitcoinEncoding) SortMapKeys(keys []reflect.Value) { SortMapKeysByValue(keys) }
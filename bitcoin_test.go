@@ -0,0 +1,139 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactSize(t *testing.T) {
+	tests := []struct {
+		value    uint64
+		expected string
+	}{
+		{0, "00"},
+		{0xfc, "fc"},
+		{0xfd, "fdfd00"},
+		{0xffff, "fdffff"},
+		{0x10000, "fe00000100"},
+		{0xffffffff, "feffffffff"},
+		{0x100000000, "ff0000000001000000"},
+		{math.MaxUint64, "ffffffffffffffffff"},
+	}
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		require.NoError(t, NewBitcoinEncoder(buf).WriteCompactSize(test.value))
+		assert.Equal(t, test.expected, hex.EncodeToString(buf.Bytes()))
+
+		got, err := NewBitcoinDecoder(buf.Bytes()).ReadCompactSize()
+		require.NoError(t, err)
+		assert.Equal(t, test.value, got)
+	}
+
+	for _, data := range []string{"fdfc00", "feffff0000", "ffffffffff00000000"} {
+		_, err := NewBitcoinDecoder(mustHex(data)).ReadCompactSize()
+		assert.Equal(t, ErrNonCanonicalCompactSize, err, data)
+	}
+
+	_, err := NewBitcoinDecoder(mustHex("fdff")).ReadCompactSize()
+	require.Error(t, err)
+
+	_, err = NewBitcoinDecoder(mustHex("fe01000002")).ReadLength()
+	require.EqualError(t, err, "bitcoin: length 33554433 exceeds the maximum 33554432")
+}
+
+type btcTxIn struct {
+	PrevHash  [32]byte
+	PrevIndex uint32
+	Script    []byte
+	Sequence  uint32
+}
+
+type btcTxOut struct {
+	Value    int64
+	PkScript []byte
+}
+
+type btcTx struct {
+	Version  int32
+	Inputs   []btcTxIn
+	Outputs  []btcTxOut
+	LockTime uint32
+}
+
+func TestBitcoin_Transaction(t *testing.T) {
+	// The coinbase transaction of the genesis block.
+	raw := "01000000" + "01" +
+		"0000000000000000000000000000000000000000000000000000000000000000" + "ffffffff" +
+		"4d" + "04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73" +
+		"ffffffff" +
+		"01" + "00f2052a01000000" +
+		"43" + "4104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac" +
+		"00000000"
+
+	var tx btcTx
+	require.NoError(t, UnmarshalBitcoin(&tx, mustHex(raw)))
+	assert.Equal(t, int32(1), tx.Version)
+	require.Len(t, tx.Inputs, 1)
+	assert.Equal(t, uint32(0xffffffff), tx.Inputs[0].PrevIndex)
+	assert.Contains(t, string(tx.Inputs[0].Script), "The Times 03/Jan/2009 Chancellor on brink of second bailout for banks")
+	require.Len(t, tx.Outputs, 1)
+	assert.Equal(t, int64(50*100000000), tx.Outputs[0].Value)
+
+	data, err := MarshalBitcoin(tx)
+	require.NoError(t, err)
+	assert.Equal(t, raw, hex.EncodeToString(data))
+
+	first := sha256.Sum256(data)
+	txid := sha256.Sum256(first[:])
+	ReverseBytes(txid[:])
+	assert.Equal(t, "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b", hex.EncodeToString(txid[:]))
+
+	{
+		// A script whose length is not minimally encoded:
+		var tx btcTx
+		nonCanonical := raw[:82] + "fd4d00" + raw[84:]
+		err := UnmarshalBitcoin(&tx, mustHex(nonCanonical))
+		require.True(t, errors.Is(err, ErrNonCanonicalCompactSize), err)
+	}
+	{
+		data, err := MarshalBitcoin(btcTxOut{Value: 1, PkScript: make([]byte, 0xfd)})
+		require.NoError(t, err)
+		assert.Equal(t, "0100000000000000"+"fdfd00", hex.EncodeToString(data[:11]))
+	}
+}
+
+func TestBitcoin_LengthBound(t *testing.T) {
+	// A 5-byte length of 32M elements must not allocate them:
+	var v struct{ B [][4096]byte }
+	require.EqualError(t,
+		UnmarshalBitcoin(&v, mustHex("fe00000002")),
+		`error while decoding "B" field: bitcoin: length 33554432 exceeds the remaining 0 bytes`,
+	)
+
+	var m map[uint8][4096]byte
+	require.EqualError(t,
+		UnmarshalBitcoin(&m, mustHex("fe00000002")),
+		"bitcoin: length 33554432 exceeds the remaining 0 bytes",
+	)
+}
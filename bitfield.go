@@ -0,0 +1,195 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+)
+
+// A bitfield packs the integer and bool fields with a `bits=N` tag into
+// the unsigned integer of the field with the `bitfield` tag that precedes
+// them (usually a blank field, which declares the width of the bitfield):
+//
+//	type Header struct {
+//		_          uint8 `bin:"bitfield"`
+//		IsSigner   bool  `bin:"bits=1"`
+//		IsWritable bool  `bin:"bits=1"`
+//		Kind       uint8 `bin:"bits=3"`
+//	}
+//
+// The first field takes the least significant bits, unless
+// the tag is `bitfield=msb`. The signed fields are sign-extended.
+// The integer is encoded like the other integers of the encoding.
+
+const (
+	bitfieldLSBFirst = "lsb"
+	bitfieldMSBFirst = "msb"
+)
+
+type bitfieldGroup struct {
+	container reflect.Type
+	fields    []bitfieldField
+	// last is the index of the last struct field of the bitfield.
+	last int
+}
+
+type bitfieldField struct {
+	index int
+	bits  uint
+	shift uint
+}
+
+func bitfieldMask(bits uint) uint64 {
+	return uint64(1)<<bits - 1
+}
+
+// newBitfieldGroup returns the bitfield of the struct rt whose
+// container is the field at index start.
+func newBitfieldGroup(rt reflect.Type, start int) (*bitfieldGroup, error) {
+	containerField := rt.Field(start)
+	containerTag := parseFieldTag(containerField.Tag)
+	if containerTag.Bitfield == "" {
+		return nil, fmt.Errorf("field %s: bits tag outside of a bitfield", containerField.Name)
+	}
+	switch containerField.Type.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return nil, fmt.Errorf("field %s: bitfield must be an unsigned integer, got %s", containerField.Name, containerField.Type)
+	}
+
+	g := &bitfieldGroup{container: containerField.Type, last: start}
+	width := uint(containerField.Type.Bits())
+	var used uint
+	for i := start + 1; i < rt.NumField(); i++ {
+		structField := rt.Field(i)
+		fieldTag := parseFieldTag(structField.Tag)
+		if fieldTag.Bits <= 0 || fieldTag.Bitfield != "" {
+			break
+		}
+		if fieldTag.If != "" {
+			return nil, fmt.Errorf("field %s: if= on a bits field, the condition goes on the bitfield %s", structField.Name, containerField.Name)
+		}
+		bits := uint(fieldTag.Bits)
+
+		switch structField.Type.Kind() {
+		case reflect.Bool:
+			if bits != 1 {
+				return nil, fmt.Errorf("field %s: bool bitfield must have 1 bit, got %d", structField.Name, bits)
+			}
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint,
+			reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+			if bits > uint(structField.Type.Bits()) {
+				return nil, fmt.Errorf("field %s: %d bits do not fit in %s", structField.Name, bits, structField.Type)
+			}
+		default:
+			return nil, fmt.Errorf("field %s: bitfield must be an integer or a bool, got %s", structField.Name, structField.Type)
+		}
+		if structField.PkgPath != "" {
+			return nil, fmt.Errorf("field %s: bitfield must be exported", structField.Name)
+		}
+		if used+bits > width {
+			return nil, fmt.Errorf("field %s: bitfield overflows its %d bits", structField.Name, width)
+		}
+
+		shift := used
+		if containerTag.Bitfield == bitfieldMSBFirst {
+			shift = width - used - bits
+		}
+		g.fields = append(g.fields, bitfieldField{index: i, bits: bits, shift: shift})
+		g.last = i
+		used += bits
+	}
+	if len(g.fields) == 0 {
+		return nil, fmt.Errorf("field %s: bitfield without bits fields", containerField.Name)
+	}
+	return g, nil
+}
+
+// pack returns the integer of the bitfield of the struct rv.
+func (g *bitfieldGroup) pack(rv reflect.Value) (reflect.Value, error) {
+	var word uint64
+	for _, f := range g.fields {
+		field := rv.Field(f.index)
+		var v uint64
+		switch field.Kind() {
+		case reflect.Bool:
+			if field.Bool() {
+				v = 1
+			}
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+			n := field.Int()
+			if sign := n >> (f.bits - 1); sign != 0 && sign != -1 {
+				return reflect.Value{}, fmt.Errorf("field %s: %d overflows %d bits", rv.Type().Field(f.index).Name, n, f.bits)
+			}
+			v = uint64(n) & bitfieldMask(f.bits)
+		default:
+			v = field.Uint()
+			if v&^bitfieldMask(f.bits) != 0 {
+				return reflect.Value{}, fmt.Errorf("field %s: %d overflows %d bits", rv.Type().Field(f.index).Name, v, f.bits)
+			}
+		}
+		word |= v << f.shift
+	}
+	out := reflect.New(g.container).Elem()
+	out.SetUint(word)
+	return out, nil
+}
+
+// unpack sets the fields of the bitfield of the struct rv from its integer.
+func (g *bitfieldGroup) unpack(word uint64, rv reflect.Value) {
+	for _, f := range g.fields {
+		field := rv.Field(f.index)
+		v := word >> f.shift & bitfieldMask(f.bits)
+		switch field.Kind() {
+		case reflect.Bool:
+			field.SetBool(v != 0)
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+			field.SetInt(int64(v<<(64-f.bits)) >> (64 - f.bits))
+		default:
+			field.SetUint(v)
+		}
+	}
+}
+
+// encodeBitfield encodes the bitfield whose container is the i-th field
+// of the struct rv with encode, and returns the index of its last field.
+func encodeBitfield(rv reflect.Value, i int, order binary.ByteOrder, encode func(reflect.Value, *option) error) (int, error) {
+	g, err := newBitfieldGroup(rv.Type(), i)
+	if err != nil {
+		return i, err
+	}
+	word, err := g.pack(rv)
+	if err != nil {
+		return i, err
+	}
+	return g.last, encode(word, &option{Order: order})
+}
+
+// decodeBitfield decodes the bitfield whose container is the i-th field
+// of the struct rv with decode, and returns the index of its last field.
+func decodeBitfield(rv reflect.Value, i int, order binary.ByteOrder, decode func(reflect.Value, *option) error) (int, error) {
+	g, err := newBitfieldGroup(rv.Type(), i)
+	if err != nil {
+		return i, err
+	}
+	word := reflect.New(g.container).Elem()
+	if err := decode(word, &option{Order: order}); err != nil {
+		return i, err
+	}
+	g.unpack(word.Uint(), rv)
+	return g.last, nil
+}
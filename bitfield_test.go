@@ -0,0 +1,108 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bitfieldHeader struct {
+	Version    uint8
+	_          uint8  `bin:"bitfield"`
+	IsSigner   bool   `bin:"bits=1"`
+	IsWritable bool   `bin:"bits=1"`
+	Kind       uint8  `bin:"bits=3"`
+	Delta      int8   `bin:"bits=3"`
+	_          uint16 `bin:"bitfield=msb big"`
+	High       uint8  `bin:"bits=4"`
+	Low        uint16 `bin:"bits=12"`
+	Count      uint32
+}
+
+func TestBitfield(t *testing.T) {
+	value := bitfieldHeader{
+		Version:  1,
+		IsSigner: true,
+		Kind:     5,
+		Delta:    -2,
+		High:     0xa,
+		Low:      0x123,
+		Count:    9,
+	}
+
+	tests := []struct {
+		name      string
+		marshal   func(v interface{}) ([]byte, error)
+		unmarshal func(v interface{}, b []byte) error
+		expected  string
+	}{
+		// Borsh and bincode integers are always little-endian:
+		{"borsh", MarshalBorsh, UnmarshalBorsh, "01" + "d5" + "23a1" + "09000000"},
+		{"bin", MarshalBin, UnmarshalBin, "01" + "d5" + "a123" + "09000000"},
+		{"bincode", MarshalBincode, UnmarshalBincode, "01" + "d5" + "23a1" + "09000000"},
+		{"postcard", MarshalPostcard, UnmarshalPostcard, "01" + "d5" + "a3c202" + "09"},
+		{"xdr", MarshalXDR, UnmarshalXDR, "00000001" + "000000d5" + "0000a123" + "00000009"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := test.marshal(value)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, hex.EncodeToString(data))
+
+			var got bitfieldHeader
+			require.NoError(t, test.unmarshal(&got, data))
+			assert.Equal(t, value, got)
+		})
+	}
+}
+
+func TestBitfield_Errors(t *testing.T) {
+	_, err := MarshalBorsh(bitfieldHeader{Kind: 8})
+	require.EqualError(t, err, "field Kind: 8 overflows 3 bits")
+
+	_, err = MarshalBorsh(bitfieldHeader{Delta: 4})
+	require.EqualError(t, err, "field Delta: 4 overflows 3 bits")
+
+	_, err = MarshalBorsh(struct {
+		A uint8 `bin:"bits=1"`
+	}{})
+	require.EqualError(t, err, "field A: bits tag outside of a bitfield")
+
+	_, err = MarshalBorsh(struct {
+		_ int8  `bin:"bitfield"`
+		A uint8 `bin:"bits=1"`
+	}{})
+	require.EqualError(t, err, "field _: bitfield must be an unsigned integer, got int8")
+
+	err = UnmarshalBorsh(&struct {
+		_ uint8 `bin:"bitfield"`
+		A uint8 `bin:"bits=5"`
+		B uint8 `bin:"bits=4"`
+	}{}, []byte{0})
+	require.EqualError(t, err, "field B: bitfield overflows its 8 bits")
+
+	_, err = MarshalBorsh(struct {
+		_ uint8 `bin:"bitfield"`
+		A bool  `bin:"bits=2"`
+	}{})
+	require.EqualError(t, err, "field A: bool bitfield must have 1 bit, got 2")
+
+	_, err = MarshalRLP(bitfieldHeader{})
+	require.EqualError(t, err, "rlp: bitfieldHeader: field IsSigner: bitfields are not supported")
+}
@@ -0,0 +1,19 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package example is generated by anchorgen from testdata/example.json,
+// and is used to verify that the generated code compiles and round-trips.
+package example
+
+//go:generate go run github.com/gagliardetto/binary/cmd/anchorgen -idl ../testdata/example.json -pkg example -out example.go
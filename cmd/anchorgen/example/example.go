@@ -0,0 +1,246 @@
+// Code generated by anchorgen. DO NOT EDIT.
+
+package example
+
+import (
+	"fmt"
+
+	bin "github.com/gagliardetto/binary"
+)
+
+type Order struct {
+	Side     Side
+	Price    uint64
+	Quantity int64
+	Active   bool
+}
+
+type Side bin.BorshEnum
+
+const (
+	SideBid Side = iota
+	SideAsk
+)
+
+func (value Side) String() string {
+	switch value {
+	case SideBid:
+		return "Bid"
+	case SideAsk:
+		return "Ask"
+	default:
+		return ""
+	}
+}
+
+func (Side) AnchorSimpleEnum() {}
+
+type Action struct {
+	Enum     bin.BorshEnum `borsh_enum:"true"`
+	Noop     ActionNoop
+	Transfer ActionTransfer
+	SetFee   ActionSetFee
+}
+
+const (
+	ActionKindNoop bin.BorshEnum = iota
+	ActionKindTransfer
+	ActionKindSetFee
+)
+
+type ActionNoop struct {
+}
+
+type ActionTransfer struct {
+	Amount uint64
+	To     [32]byte
+}
+
+type ActionSetFee struct {
+	Elem0 uint16
+	Elem1 bool
+}
+
+func (ActionSetFee) AnchorTupleVariant() {}
+
+var MarketDiscriminator = bin.SighashTypeID("account", "Market")
+
+type Market struct {
+	Authority [32]byte
+	Bump      uint8
+	Fees      [4]uint16
+	Delegate  *[32]byte `bin:"optional"`
+	Orders    []Order
+	Volume    bin.Int128
+	Metadata  []byte
+}
+
+// marketLayout has the same layout as Market, without the discriminator.
+type marketLayout Market
+
+func (obj *Market) UnmarshalWithDecoder(decoder *bin.Decoder) error {
+	discriminator, err := decoder.ReadTypeID()
+	if err != nil {
+		return err
+	}
+	if discriminator != MarketDiscriminator {
+		return fmt.Errorf("wrong discriminator for Market: expected %x, got %x", MarketDiscriminator, discriminator)
+	}
+	return decoder.Decode((*marketLayout)(obj))
+}
+
+func (obj Market) MarshalWithEncoder(encoder *bin.Encoder) error {
+	if err := encoder.WriteBytes(MarketDiscriminator.Bytes(), false); err != nil {
+		return err
+	}
+	return encoder.Encode(marketLayout(obj))
+}
+
+var CounterDiscriminator = bin.SighashTypeID("account", "Counter")
+
+type Counter struct {
+	Count     uint64
+	Authority [32]byte
+}
+
+// counterLayout has the same layout as Counter, without the discriminator.
+type counterLayout Counter
+
+func (obj *Counter) UnmarshalWithDecoder(decoder *bin.Decoder) error {
+	discriminator, err := decoder.ReadTypeID()
+	if err != nil {
+		return err
+	}
+	if discriminator != CounterDiscriminator {
+		return fmt.Errorf("wrong discriminator for Counter: expected %x, got %x", CounterDiscriminator, discriminator)
+	}
+	return decoder.Decode((*counterLayout)(obj))
+}
+
+func (obj Counter) MarshalWithEncoder(encoder *bin.Encoder) error {
+	if err := encoder.WriteBytes(CounterDiscriminator.Bytes(), false); err != nil {
+		return err
+	}
+	return encoder.Encode(counterLayout(obj))
+}
+
+var OrderPlacedDiscriminator = bin.SighashTypeID("event", "OrderPlaced")
+
+type OrderPlaced struct {
+	Market [32]byte
+	Price  uint64
+	Side   Side
+}
+
+// orderPlacedLayout has the same layout as OrderPlaced, without the discriminator.
+type orderPlacedLayout OrderPlaced
+
+func (obj *OrderPlaced) UnmarshalWithDecoder(decoder *bin.Decoder) error {
+	discriminator, err := decoder.ReadTypeID()
+	if err != nil {
+		return err
+	}
+	if discriminator != OrderPlacedDiscriminator {
+		return fmt.Errorf("wrong discriminator for OrderPlaced: expected %x, got %x", OrderPlacedDiscriminator, discriminator)
+	}
+	return decoder.Decode((*orderPlacedLayout)(obj))
+}
+
+func (obj OrderPlaced) MarshalWithEncoder(encoder *bin.Encoder) error {
+	if err := encoder.WriteBytes(OrderPlacedDiscriminator.Bytes(), false); err != nil {
+		return err
+	}
+	return encoder.Encode(orderPlacedLayout(obj))
+}
+
+// InitializeArgs are the arguments of the "initialize" instruction.
+//
+// Accounts:
+//  0. [writable] market
+//  1. [signer] authority
+//  2. systemProgram
+type InitializeArgs struct {
+}
+
+// PlaceOrderArgs are the arguments of the "place_order" instruction.
+//
+// Accounts:
+//  0. [writable] market
+//  1. [signer] owner.wallet
+//  2. [writable] owner.openOrders
+type PlaceOrderArgs struct {
+	Side     Side
+	Price    uint64
+	Size     bin.Uint128
+	ClientId *uint64 `bin:"optional"`
+	Memo     string
+}
+
+// ExecuteArgs are the arguments of the "execute" instruction.
+//
+// Accounts:
+//  0. [writable] market
+type ExecuteArgs struct {
+	Actions []Action
+}
+
+// IncrementArgs are the arguments of the "increment" instruction.
+//
+// Accounts:
+//  0. [writable] market
+type IncrementArgs struct {
+	Fees uint16
+}
+
+// StateIncrementArgs are the arguments of the "increment" state method.
+//
+// Accounts:
+//  0. [signer] authority
+type StateIncrementArgs struct {
+	By uint64
+}
+
+var InstructionDefinition = bin.NewVariantDefinition(
+	bin.AnchorTypeIDEncoding,
+	[]bin.VariantType{
+		{Name: "initialize", Type: (*InitializeArgs)(nil)},
+		{Name: "place_order", Type: (*PlaceOrderArgs)(nil)},
+		{Name: "execute", Type: (*ExecuteArgs)(nil)},
+		{Name: "increment", Type: (*IncrementArgs)(nil)},
+		bin.NewNamespacedVariantType(bin.SIGHASH_STATE_NAMESPACE, "increment", (*StateIncrementArgs)(nil)),
+	},
+)
+
+var (
+	InstructionInitialize     = InstructionDefinition.TypeID("initialize")
+	InstructionPlaceOrder     = InstructionDefinition.TypeID("place_order")
+	InstructionExecute        = InstructionDefinition.TypeID("execute")
+	InstructionIncrement      = InstructionDefinition.TypeID("increment")
+	InstructionStateIncrement = InstructionDefinition.TypeID("state:increment")
+)
+
+// Instruction is an instruction of the program, whose Impl is
+// a pointer to the arguments struct of the instruction.
+type Instruction struct {
+	bin.BaseVariant
+}
+
+func (inst *Instruction) UnmarshalWithDecoder(decoder *bin.Decoder) error {
+	return inst.BaseVariant.UnmarshalBinaryVariant(decoder, InstructionDefinition)
+}
+
+func (inst Instruction) MarshalWithEncoder(encoder *bin.Encoder) error {
+	if err := encoder.WriteBytes(inst.TypeID.Bytes(), false); err != nil {
+		return err
+	}
+	return encoder.Encode(inst.Impl)
+}
+
+// DecodeInstruction decodes the borsh-encoded instruction data.
+func DecodeInstruction(data []byte) (*Instruction, error) {
+	inst := new(Instruction)
+	if err := bin.NewBorshDecoder(data).Decode(inst); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
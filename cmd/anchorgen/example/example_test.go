@@ -0,0 +1,215 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package example
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/stretchr/testify/require"
+)
+
+func concat(slices ...[]byte) []byte {
+	return bytes.Join(slices, nil)
+}
+
+func pubkey(b byte) (out [32]byte) {
+	for i := range out {
+		out[i] = b
+	}
+	return
+}
+
+func bytesOf(key [32]byte) []byte {
+	return key[:]
+}
+
+func TestInstruction_PlaceOrder(t *testing.T) {
+	fixture := concat(
+		[]byte{0x33, 0xc2, 0x9b, 0xaf, 0x6d, 0x82, 0x60, 0x6a}, // sighash("global:place_order")
+		[]byte{1},                            // side: Ask
+		[]byte{0xe8, 0x03, 0, 0, 0, 0, 0, 0}, // price: 1000
+		[]byte{5, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, // size: 5
+		[]byte{1, 42, 0, 0, 0, 0, 0, 0, 0},                     // clientId: Some(42)
+		[]byte{2, 0, 0, 0, 'h', 'i'},                           // memo: "hi"
+	)
+
+	inst, err := DecodeInstruction(fixture)
+	require.NoError(t, err)
+	require.Equal(t, InstructionPlaceOrder, inst.TypeID)
+
+	clientID := uint64(42)
+	require.Equal(t, &PlaceOrderArgs{
+		Side:     SideAsk,
+		Price:    1000,
+		Size:     bin.Uint128{Lo: 5},
+		ClientId: &clientID,
+		Memo:     "hi",
+	}, inst.Impl)
+
+	got, err := bin.MarshalBorsh(inst)
+	require.NoError(t, err)
+	require.Equal(t, fixture, got)
+}
+
+func TestInstruction_Execute(t *testing.T) {
+	fixture := concat(
+		[]byte{0x82, 0xdd, 0xf2, 0x9a, 0x0d, 0xc1, 0xbd, 0x1d}, // sighash("global:execute")
+		[]byte{3, 0, 0, 0}, // actions: 3 elements
+		[]byte{0},          // Noop
+		[]byte{1},          // Transfer
+		[]byte{7, 0, 0, 0, 0, 0, 0, 0},
+		bytesOf(pubkey(9)),
+		[]byte{2},        // SetFee
+		[]byte{30, 0, 1}, // (30, true)
+	)
+
+	inst, err := DecodeInstruction(fixture)
+	require.NoError(t, err)
+	require.Equal(t, InstructionExecute, inst.TypeID)
+	require.Equal(t, &ExecuteArgs{
+		Actions: []Action{
+			{Enum: ActionKindNoop},
+			{Enum: ActionKindTransfer, Transfer: ActionTransfer{Amount: 7, To: pubkey(9)}},
+			{Enum: ActionKindSetFee, SetFee: ActionSetFee{Elem0: 30, Elem1: true}},
+		},
+	}, inst.Impl)
+
+	got, err := bin.MarshalBorsh(inst)
+	require.NoError(t, err)
+	require.Equal(t, fixture, got)
+}
+
+func TestInstruction_Execute_AnchorJSON(t *testing.T) {
+	// The arguments in the JSON form of the anchor coder (hand-written, not
+	// produced by the TypeScript client): the fields of tuple variants are
+	// keyed by their index.
+	fixture, err := ioutil.ReadFile("../testdata/execute_args.json")
+	require.NoError(t, err)
+
+	var args ExecuteArgs
+	require.NoError(t, bin.UnmarshalAnchorJSON(fixture, &args))
+	require.Equal(t, ExecuteArgs{
+		Actions: []Action{
+			{Enum: ActionKindNoop},
+			{Enum: ActionKindTransfer, Transfer: ActionTransfer{Amount: 7, To: pubkey(9)}},
+			{Enum: ActionKindSetFee, SetFee: ActionSetFee{Elem0: 30, Elem1: true}},
+		},
+	}, args)
+
+	got, err := bin.MarshalAnchorJSON(args)
+	require.NoError(t, err)
+	require.JSONEq(t, string(fixture), string(got))
+}
+
+func TestAccount_Market(t *testing.T) {
+	fixture := concat(
+		[]byte{0xdb, 0xbe, 0xd5, 0x37, 0x00, 0xe3, 0xc6, 0x9a}, // sighash("account:Market")
+		bytesOf(pubkey(1)),              // authority
+		[]byte{254},                     // bump
+		[]byte{1, 0, 2, 0, 3, 0, 4, 0},  // fees
+		[]byte{0},                       // delegate: None
+		[]byte{1, 0, 0, 0},              // orders: 1 element
+		[]byte{0},                       // side: Bid
+		[]byte{10, 0, 0, 0, 0, 0, 0, 0}, // price
+		[]byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, // quantity: -2
+		[]byte{1}, // active
+		[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, // volume: -1
+		[]byte{2, 0, 0, 0, 0xca, 0xfe}, // metadata
+	)
+
+	var market Market
+	require.NoError(t, bin.UnmarshalBorsh(&market, fixture))
+	require.Equal(t, Market{
+		Authority: pubkey(1),
+		Bump:      254,
+		Fees:      [4]uint16{1, 2, 3, 4},
+		Orders: []Order{
+			{Side: SideBid, Price: 10, Quantity: -2, Active: true},
+		},
+		Volume:   bin.Int128{Lo: 0xffffffffffffffff, Hi: 0xffffffffffffffff},
+		Metadata: []byte{0xca, 0xfe},
+	}, market)
+
+	got, err := bin.MarshalBorsh(market)
+	require.NoError(t, err)
+	require.Equal(t, fixture, got)
+
+	// Wrong discriminator:
+	fixture[0] ^= 0xff
+	require.Error(t, bin.UnmarshalBorsh(&market, fixture))
+}
+
+func TestEvent_OrderPlaced(t *testing.T) {
+	fixture := concat(
+		[]byte{0x60, 0x82, 0xcc, 0xea, 0xa9, 0xdb, 0xd8, 0xe3}, // sighash("event:OrderPlaced")
+		bytesOf(pubkey(3)),
+		[]byte{99, 0, 0, 0, 0, 0, 0, 0},
+		[]byte{1},
+	)
+
+	var event OrderPlaced
+	require.NoError(t, bin.UnmarshalBorsh(&event, fixture))
+	require.Equal(t, OrderPlaced{Market: pubkey(3), Price: 99, Side: SideAsk}, event)
+	require.Equal(t, "Ask", event.Side.String())
+
+	got, err := bin.MarshalBorsh(event)
+	require.NoError(t, err)
+	require.Equal(t, fixture, got)
+}
+
+func TestEvent_OrderPlaced_AnchorJSON(t *testing.T) {
+	// The event data in the JSON form of the anchor coder (hand-written, not
+	// produced by the TypeScript client): simple enums are objects keyed by
+	// the camelCase name of their variant.
+	fixture, err := ioutil.ReadFile("../testdata/order_placed.json")
+	require.NoError(t, err)
+
+	var event OrderPlaced
+	require.NoError(t, bin.UnmarshalAnchorJSON(fixture, &event))
+	require.Equal(t, OrderPlaced{Market: pubkey(3), Price: 99, Side: SideAsk}, event)
+
+	got, err := bin.MarshalAnchorJSON(event)
+	require.NoError(t, err)
+	require.JSONEq(t, string(fixture), string(got))
+}
+
+func TestInstruction_StateMethod(t *testing.T) {
+	fixture := concat(
+		[]byte{0x5e, 0x7a, 0x79, 0xb0, 0x74, 0x28, 0x80, 0x71}, // sighash("state:increment")
+		[]byte{3, 0, 0, 0, 0, 0, 0, 0},                         // by: 3
+	)
+
+	inst, err := DecodeInstruction(fixture)
+	require.NoError(t, err)
+	require.Equal(t, InstructionStateIncrement, inst.TypeID)
+	require.Equal(t, &StateIncrementArgs{By: 3}, inst.Impl)
+
+	got, err := bin.MarshalBorsh(inst)
+	require.NoError(t, err)
+	require.Equal(t, fixture, got)
+
+	// The instruction of the same name has the global sighash:
+	fixture = concat(
+		[]byte{0x0b, 0x12, 0x68, 0x09, 0x68, 0xae, 0x3b, 0x21}, // sighash("global:increment")
+		[]byte{7, 0}, // fees: 7
+	)
+	inst, err = DecodeInstruction(fixture)
+	require.NoError(t, err)
+	require.Equal(t, InstructionIncrement, inst.TypeID)
+	require.Equal(t, &IncrementArgs{Fees: 7}, inst.Impl)
+}
@@ -0,0 +1,445 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+
+	bin "github.com/gagliardetto/binary"
+)
+
+var primitiveGoTypes = map[string]string{
+	"bool":      "bool",
+	"u8":        "uint8",
+	"i8":        "int8",
+	"u16":       "uint16",
+	"i16":       "int16",
+	"u32":       "uint32",
+	"i32":       "int32",
+	"u64":       "uint64",
+	"i64":       "int64",
+	"u128":      "bin.Uint128",
+	"i128":      "bin.Int128",
+	"f32":       "float32",
+	"f64":       "float64",
+	"bytes":     "[]byte",
+	"string":    "string",
+	"publicKey": "[32]byte",
+}
+
+type generator struct {
+	idl *bin.IDL
+	buf bytes.Buffer
+}
+
+// generate returns the formatted Go source of the types,
+// accounts, events and instructions of the provided IDL.
+func generate(idl *bin.IDL, pkg string) ([]byte, error) {
+	g := &generator{idl: idl}
+
+	g.printf("// Code generated by anchorgen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", pkg)
+	g.printf("import (\n")
+	if len(idl.Accounts) > 0 || len(idl.Events) > 0 || idl.State != nil {
+		g.printf("\"fmt\"\n\n")
+	}
+	g.printf("bin %q\n", "github.com/gagliardetto/binary")
+	g.printf(")\n\n")
+
+	for _, typeDef := range idl.Types {
+		if err := g.genTypeDef(typeDef); err != nil {
+			return nil, fmt.Errorf("type %q: %w", typeDef.Name, err)
+		}
+	}
+	for _, account := range idl.Accounts {
+		if err := g.genDiscriminated(account.Name, bin.SIGHASH_ACCOUNT_NAMESPACE, account.Type.Fields); err != nil {
+			return nil, fmt.Errorf("account %q: %w", account.Name, err)
+		}
+	}
+	if idl.State != nil {
+		state := idl.State.Struct
+		if err := g.genDiscriminated(state.Name, bin.SIGHASH_ACCOUNT_NAMESPACE, state.Type.Fields); err != nil {
+			return nil, fmt.Errorf("state %q: %w", state.Name, err)
+		}
+	}
+	for _, event := range idl.Events {
+		fields := make([]bin.IDLField, len(event.Fields))
+		for i, field := range event.Fields {
+			fields[i] = bin.IDLField{Name: field.Name, Type: field.Type}
+		}
+		if err := g.genDiscriminated(event.Name, bin.SIGHASH_EVENT_NAMESPACE, fields); err != nil {
+			return nil, fmt.Errorf("event %q: %w", event.Name, err)
+		}
+	}
+	if err := g.genInstructions(); err != nil {
+		return nil, err
+	}
+
+	out, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return out, nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) genTypeDef(typeDef bin.IDLTypeDef) error {
+	name := toPascal(typeDef.Name)
+	switch typeDef.Type.Kind {
+	case bin.IDLTypeDefKindStruct:
+		g.printf("type %s struct {\n", name)
+		if err := g.genFields(typeDef.Type.Fields); err != nil {
+			return err
+		}
+		g.printf("}\n\n")
+		return nil
+	case bin.IDLTypeDefKindEnum:
+		if isSimpleEnum(typeDef.Type.Variants) {
+			g.genSimpleEnum(name, typeDef.Type.Variants)
+			return nil
+		}
+		return g.genComplexEnum(name, typeDef.Type.Variants)
+	default:
+		return fmt.Errorf("unsupported kind %q", typeDef.Type.Kind)
+	}
+}
+
+func isSimpleEnum(variants []bin.IDLEnumVariant) bool {
+	for _, variant := range variants {
+		if !variant.IsUnit() {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *generator) genSimpleEnum(name string, variants []bin.IDLEnumVariant) {
+	g.printf("type %s bin.BorshEnum\n\n", name)
+	g.printf("const (\n")
+	for i, variant := range variants {
+		if i == 0 {
+			g.printf("%s%s %s = iota\n", name, toPascal(variant.Name), name)
+		} else {
+			g.printf("%s%s\n", name, toPascal(variant.Name))
+		}
+	}
+	g.printf(")\n\n")
+
+	g.printf("func (value %s) String() string {\n", name)
+	g.printf("switch value {\n")
+	for _, variant := range variants {
+		g.printf("case %s%s:\nreturn %q\n", name, toPascal(variant.Name), variant.Name)
+	}
+	g.printf("default:\nreturn \"\"\n}\n}\n\n")
+
+	g.printf("func (%s) AnchorSimpleEnum() {}\n\n", name)
+}
+
+func (g *generator) genComplexEnum(name string, variants []bin.IDLEnumVariant) error {
+	g.printf("type %s struct {\n", name)
+	g.printf("Enum bin.BorshEnum `borsh_enum:\"true\"`\n")
+	for _, variant := range variants {
+		g.printf("%s %s%s\n", toPascal(variant.Name), name, toPascal(variant.Name))
+	}
+	g.printf("}\n\n")
+
+	g.printf("const (\n")
+	for i, variant := range variants {
+		if i == 0 {
+			g.printf("%sKind%s bin.BorshEnum = iota\n", name, toPascal(variant.Name))
+		} else {
+			g.printf("%sKind%s\n", name, toPascal(variant.Name))
+		}
+	}
+	g.printf(")\n\n")
+
+	for _, variant := range variants {
+		g.printf("type %s%s struct {\n", name, toPascal(variant.Name))
+		switch {
+		case len(variant.NamedFields) > 0:
+			if err := g.genFields(variant.NamedFields); err != nil {
+				return fmt.Errorf("variant %q: %w", variant.Name, err)
+			}
+		case len(variant.TupleFields) > 0:
+			fields := make([]bin.IDLField, len(variant.TupleFields))
+			for i, typ := range variant.TupleFields {
+				fields[i] = bin.IDLField{Name: fmt.Sprintf("elem%d", i), Type: typ}
+			}
+			if err := g.genFields(fields); err != nil {
+				return fmt.Errorf("variant %q: %w", variant.Name, err)
+			}
+		}
+		g.printf("}\n\n")
+		if len(variant.TupleFields) > 0 {
+			g.printf("func (%s%s) AnchorTupleVariant() {}\n\n", name, toPascal(variant.Name))
+		}
+	}
+	return nil
+}
+
+func (g *generator) genFields(fields []bin.IDLField) error {
+	for _, field := range fields {
+		if field.Type.Option != nil {
+			typ, err := goType(*field.Type.Option)
+			if err != nil {
+				return fmt.Errorf("field %q: %w", field.Name, err)
+			}
+			g.printf("%s *%s `bin:\"optional\"`\n", toPascal(field.Name), typ)
+			continue
+		}
+		typ, err := goType(field.Type)
+		if err != nil {
+			return fmt.Errorf("field %q: %w", field.Name, err)
+		}
+		g.printf("%s %s\n", toPascal(field.Name), typ)
+	}
+	return nil
+}
+
+// genDiscriminated generates a struct prefixed by an 8-bytes anchor
+// discriminator, i.e. an account or an event.
+func (g *generator) genDiscriminated(name string, namespace string, fields []bin.IDLField) error {
+	goName := toPascal(name)
+	layoutName := toCamel(goName) + "Layout"
+
+	g.printf("var %sDiscriminator = bin.SighashTypeID(%q, %q)\n\n", goName, namespace, name)
+
+	g.printf("type %s struct {\n", goName)
+	if err := g.genFields(fields); err != nil {
+		return err
+	}
+	g.printf("}\n\n")
+
+	g.printf("// %s has the same layout as %s, without the discriminator.\n", layoutName, goName)
+	g.printf("type %s %s\n\n", layoutName, goName)
+
+	g.printf("func (obj *%s) UnmarshalWithDecoder(decoder *bin.Decoder) error {\n", goName)
+	g.printf("discriminator, err := decoder.ReadTypeID()\n")
+	g.printf("if err != nil {\nreturn err\n}\n")
+	g.printf("if discriminator != %sDiscriminator {\n", goName)
+	g.printf("return fmt.Errorf(\"wrong discriminator for %s: expected %%x, got %%x\", %sDiscriminator, discriminator)\n}\n", goName, goName)
+	g.printf("return decoder.Decode((*%s)(obj))\n}\n\n", layoutName)
+
+	g.printf("func (obj %s) MarshalWithEncoder(encoder *bin.Encoder) error {\n", goName)
+	g.printf("if err := encoder.WriteBytes(%sDiscriminator.Bytes(), false); err != nil {\nreturn err\n}\n", goName)
+	g.printf("return encoder.Encode(%s(obj))\n}\n\n", layoutName)
+	return nil
+}
+
+// namespacedInstruction is an instruction, or a method of the
+// program state (in the "state" sighash namespace).
+type namespacedInstruction struct {
+	bin.IDLInstruction
+	namespace string
+}
+
+// goName returns the Go name of the instruction, prefixed with
+// "State" for the state methods, which can share the name of an instruction.
+func (inst namespacedInstruction) goName() string {
+	if inst.namespace == bin.SIGHASH_STATE_NAMESPACE {
+		return "State" + toPascal(inst.Name)
+	}
+	return toPascal(inst.Name)
+}
+
+// variantName returns the name of the instruction in the InstructionDefinition.
+func (inst namespacedInstruction) variantName() string {
+	if inst.namespace == bin.SIGHASH_STATE_NAMESPACE {
+		return inst.namespace + ":" + toSnake(inst.Name)
+	}
+	return toSnake(inst.Name)
+}
+
+func (g *generator) genInstructions() error {
+	instructions := make([]namespacedInstruction, 0, len(g.idl.Instructions))
+	for _, instruction := range g.idl.Instructions {
+		instructions = append(instructions, namespacedInstruction{instruction, bin.SIGHASH_GLOBAL_NAMESPACE})
+	}
+	if g.idl.State != nil {
+		for _, method := range g.idl.State.Methods {
+			instructions = append(instructions, namespacedInstruction{method, bin.SIGHASH_STATE_NAMESPACE})
+		}
+	}
+	if len(instructions) == 0 {
+		return nil
+	}
+
+	for _, instruction := range instructions {
+		argsName := instruction.goName() + "Args"
+		if instruction.namespace == bin.SIGHASH_STATE_NAMESPACE {
+			g.printf("// %s are the arguments of the %q state method.\n", argsName, toSnake(instruction.Name))
+		} else {
+			g.printf("// %s are the arguments of the %q instruction.\n", argsName, toSnake(instruction.Name))
+		}
+		if len(instruction.Accounts) > 0 {
+			g.printf("//\n// Accounts:\n")
+			for i, account := range flattenAccounts("", instruction.Accounts) {
+				g.printf("//  %d. %s\n", i, account)
+			}
+		}
+		g.printf("type %s struct {\n", argsName)
+		if err := g.genFields(instruction.Args); err != nil {
+			return fmt.Errorf("instruction %q: %w", instruction.Name, err)
+		}
+		g.printf("}\n\n")
+	}
+
+	g.printf("var InstructionDefinition = bin.NewVariantDefinition(\nbin.AnchorTypeIDEncoding,\n[]bin.VariantType{\n")
+	for _, instruction := range instructions {
+		if instruction.namespace == bin.SIGHASH_STATE_NAMESPACE {
+			g.printf("bin.NewNamespacedVariantType(bin.SIGHASH_STATE_NAMESPACE, %q, (*%sArgs)(nil)),\n", toSnake(instruction.Name), instruction.goName())
+		} else {
+			g.printf("{Name: %q, Type: (*%sArgs)(nil)},\n", toSnake(instruction.Name), instruction.goName())
+		}
+	}
+	g.printf("},\n)\n\n")
+
+	g.printf("var (\n")
+	for _, instruction := range instructions {
+		g.printf("Instruction%s = InstructionDefinition.TypeID(%q)\n", instruction.goName(), instruction.variantName())
+	}
+	g.printf(")\n\n")
+
+	g.printf(`// Instruction is an instruction of the program, whose Impl is
+// a pointer to the arguments struct of the instruction.
+type Instruction struct {
+	bin.BaseVariant
+}
+
+func (inst *Instruction) UnmarshalWithDecoder(decoder *bin.Decoder) error {
+	return inst.BaseVariant.UnmarshalBinaryVariant(decoder, InstructionDefinition)
+}
+
+func (inst Instruction) MarshalWithEncoder(encoder *bin.Encoder) error {
+	if err := encoder.WriteBytes(inst.TypeID.Bytes(), false); err != nil {
+		return err
+	}
+	return encoder.Encode(inst.Impl)
+}
+
+// DecodeInstruction decodes the borsh-encoded instruction data.
+func DecodeInstruction(data []byte) (*Instruction, error) {
+	inst := new(Instruction)
+	if err := bin.NewBorshDecoder(data).Decode(inst); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+`)
+	return nil
+}
+
+func flattenAccounts(prefix string, accounts []bin.IDLAccountItem) []string {
+	out := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		if len(account.Accounts) > 0 {
+			out = append(out, flattenAccounts(prefix+account.Name+".", account.Accounts)...)
+			continue
+		}
+		flags := make([]string, 0, 2)
+		if account.IsMut {
+			flags = append(flags, "writable")
+		}
+		if account.IsSigner {
+			flags = append(flags, "signer")
+		}
+		line := prefix + account.Name
+		if len(flags) > 0 {
+			line = "[" + strings.Join(flags, ", ") + "] " + line
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+func goType(t bin.IDLType) (string, error) {
+	switch {
+	case t.Primitive != "":
+		typ, ok := primitiveGoTypes[t.Primitive]
+		if !ok {
+			return "", fmt.Errorf("unsupported primitive type %q", t.Primitive)
+		}
+		return typ, nil
+	case t.Vec != nil:
+		elem, err := goType(*t.Vec)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case t.Array != nil:
+		elem, err := goType(t.Array.Elem)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("[%d]%s", t.Array.Len, elem), nil
+	case t.Defined != "":
+		return toPascal(t.Defined), nil
+	case t.Option != nil:
+		// The optionality is carried by the `bin:"optional"` field tag,
+		// which cannot be applied to nested types.
+		return "", fmt.Errorf("option is only supported as the type of a field")
+	default:
+		return "", fmt.Errorf("empty type")
+	}
+}
+
+// toPascal converts a camelCase or snake_case name to PascalCase.
+func toPascal(name string) string {
+	parts := strings.Split(name, "_")
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		parts[i] = string(runes)
+	}
+	return strings.Join(parts, "")
+}
+
+// toCamel converts a PascalCase name to camelCase.
+func toCamel(name string) string {
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// toSnake converts a camelCase name to snake_case, which is the
+// form of the name used by anchor to compute instruction sighashes.
+func toSnake(name string) string {
+	runes := []rune(name)
+	out := make([]rune, 0, len(runes)+4)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				out = append(out, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate_Example(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/example.json")
+	require.NoError(t, err)
+
+	var idl bin.IDL
+	require.NoError(t, json.Unmarshal(data, &idl))
+
+	got, err := generate(&idl, "example")
+	require.NoError(t, err)
+
+	// The generated package is committed (and tested) in ./example;
+	// run `go generate ./...` to update it.
+	expected, err := ioutil.ReadFile("example/example.go")
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(got))
+}
+
+func TestGenerate_NestedOption(t *testing.T) {
+	idl := &bin.IDL{
+		Name: "nested",
+		Types: []bin.IDLTypeDef{
+			{
+				Name: "Foo",
+				Type: bin.IDLTypeDefTy{
+					Kind: bin.IDLTypeDefKindStruct,
+					Fields: []bin.IDLField{
+						{Name: "items", Type: bin.IDLType{Vec: &bin.IDLType{Option: &bin.IDLType{Primitive: "u8"}}}},
+					},
+				},
+			},
+		},
+	}
+	_, err := generate(idl, "nested")
+	require.EqualError(t, err, `type "Foo": field "items": option is only supported as the type of a field`)
+}
+
+func TestNames(t *testing.T) {
+	require.Equal(t, "place_order", toSnake("placeOrder"))
+	require.Equal(t, "set_fee2", toSnake("setFee2"))
+	require.Equal(t, "initialize", toSnake("initialize"))
+	require.Equal(t, "PlaceOrder", toPascal("placeOrder"))
+	require.Equal(t, "PlaceOrder", toPascal("place_order"))
+	require.Equal(t, "placeOrder", toCamel("PlaceOrder"))
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command anchorgen generates Go types from an Anchor IDL:
+// the IDL types, the accounts and events (with discriminator checks),
+// the instruction arguments and the instruction VariantDefinition.
+//
+// Usage:
+//
+//	anchorgen -idl program.json -pkg program -out program.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	bin "github.com/gagliardetto/binary"
+)
+
+func main() {
+	idlPath := flag.String("idl", "", "path to the anchor IDL (JSON)")
+	pkg := flag.String("pkg", "", "name of the generated package (defaults to the IDL name)")
+	out := flag.String("out", "", "path of the generated file (defaults to stdout)")
+	flag.Parse()
+
+	if err := run(*idlPath, *pkg, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "anchorgen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(idlPath string, pkg string, out string) error {
+	if idlPath == "" {
+		return fmt.Errorf("missing -idl flag")
+	}
+	data, err := ioutil.ReadFile(idlPath)
+	if err != nil {
+		return err
+	}
+
+	var idl bin.IDL
+	if err := json.Unmarshal(data, &idl); err != nil {
+		return fmt.Errorf("parse IDL %q: %w", idlPath, err)
+	}
+	if pkg == "" {
+		pkg = toSnake(idl.Name)
+	}
+
+	code, err := generate(&idl, pkg)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return ioutil.WriteFile(out, code, 0644)
+}
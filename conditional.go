@@ -0,0 +1,121 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A field with an `if=` tag is encoded and decoded only when its condition
+// on a previous field of the struct holds; otherwise it is left out (and
+// zeroed on decode). The conditions compare an integer or bool field with
+// a value (`if=Version>=2`, `if=Kind!=0`, `if=HasAuthority==true`), test the
+// bits of a mask (`if=Flags&0x4`), or test that the field is non-zero
+// (`if=HasAuthority`). The condition of a bitfield goes on its `bitfield`
+// field, and covers all its `bits=N` fields.
+
+var fieldConditionOps = []string{">=", "<=", "==", "!=", ">", "<", "&"}
+
+// fieldConditionHolds evaluates the `if=` condition of the i-th field of the struct rv.
+func fieldConditionHolds(rv reflect.Value, i int, cond string) (bool, error) {
+	rt := rv.Type()
+	name, op, operand := cond, "", ""
+	if at := strings.IndexAny(cond, "<>=!&"); at >= 0 {
+		name = cond[:at]
+		for _, candidate := range fieldConditionOps {
+			if strings.HasPrefix(cond[at:], candidate) {
+				op, operand = candidate, cond[at+len(candidate):]
+				break
+			}
+		}
+		if op == "" || operand == "" {
+			return false, fmt.Errorf("field %s: invalid condition %q", rt.Field(i).Name, cond)
+		}
+	}
+
+	structField, ok := rt.FieldByName(name)
+	if !ok || len(structField.Index) != 1 || structField.Index[0] >= i {
+		return false, fmt.Errorf("field %s: condition %q requires a previous field %s", rt.Field(i).Name, cond, name)
+	}
+	field := rv.Field(structField.Index[0])
+
+	if op == "" {
+		return !field.IsZero(), nil
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		want, err := strconv.ParseBool(operand)
+		if err != nil || (op != "==" && op != "!=") {
+			return false, fmt.Errorf("field %s: invalid condition %q on bool field %s", rt.Field(i).Name, cond, name)
+		}
+		return (field.Bool() == want) == (op == "=="), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		want, err := strconv.ParseUint(operand, 0, 64)
+		if err != nil {
+			return false, fmt.Errorf("field %s: invalid condition %q: %w", rt.Field(i).Name, cond, err)
+		}
+		got := field.Uint()
+		if op == "&" {
+			return got&want != 0, nil
+		}
+		cmp := 0
+		if got < want {
+			cmp = -1
+		} else if got > want {
+			cmp = 1
+		}
+		return fieldConditionMatches(op, cmp), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		want, err := strconv.ParseInt(operand, 0, 64)
+		if err != nil {
+			return false, fmt.Errorf("field %s: invalid condition %q: %w", rt.Field(i).Name, cond, err)
+		}
+		got := field.Int()
+		if op == "&" {
+			return got&want != 0, nil
+		}
+		cmp := 0
+		if got < want {
+			cmp = -1
+		} else if got > want {
+			cmp = 1
+		}
+		return fieldConditionMatches(op, cmp), nil
+	}
+	return false, fmt.Errorf("field %s: condition %q on field %s of type %s", rt.Field(i).Name, cond, name, field.Type())
+}
+
+// fieldConditionMatches returns whether the comparison op holds,
+// given the sign of the difference between the field and the value.
+func fieldConditionMatches(op string, cmp int) bool {
+	switch op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp < 0
+	}
+}
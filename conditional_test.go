@@ -0,0 +1,166 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type conditionalRecord struct {
+	Version   uint8
+	Flags     uint16
+	HasMemo   bool
+	Authority [2]byte `bin:"if=Version>=2"`
+	Delegate  uint8   `bin:"if=Flags&0x4"`
+	Memo      string  `bin:"if=HasMemo"`
+	Offset    int8
+	Extra     uint8 `bin:"if=Offset<0"`
+	Closed    bool  `bin:"if=HasMemo==false"`
+}
+
+func TestConditionalFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    conditionalRecord
+		expected string
+	}{
+		{
+			name:     "absent",
+			value:    conditionalRecord{Version: 1, Flags: 0x3, Offset: 1, Closed: true},
+			expected: "01" + "0300" + "00" + "01" + "01",
+		},
+		{
+			name: "present",
+			value: conditionalRecord{
+				Version:   2,
+				Flags:     0x4,
+				HasMemo:   true,
+				Authority: [2]byte{0xaa, 0xbb},
+				Delegate:  7,
+				Memo:      "a",
+				Offset:    -1,
+				Extra:     9,
+			},
+			expected: "02" + "0400" + "01" + "aabb" + "07" + "0100000061" + "ff" + "09",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := MarshalBorsh(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, hex.EncodeToString(data))
+
+			var got conditionalRecord
+			require.NoError(t, UnmarshalBorsh(&got, data))
+			assert.Equal(t, test.value, got)
+
+			// The fields left out are zeroed:
+			got = conditionalRecord{Authority: [2]byte{1, 2}, Delegate: 3, Memo: "b", Extra: 4, Closed: true}
+			require.NoError(t, UnmarshalBorsh(&got, data))
+			assert.Equal(t, test.value, got)
+
+			for _, enc := range []Encoding{EncodingBin, EncodingBincode, EncodingPostcard, EncodingXDR} {
+				buf := new(bytes.Buffer)
+				require.NoError(t, NewEncoderWithEncoding(buf, enc).Encode(test.value))
+
+				got := conditionalRecord{Authority: [2]byte{1, 2}, Delegate: 3, Memo: "b", Extra: 4, Closed: true}
+				require.NoError(t, NewDecoderWithEncoding(buf.Bytes(), enc).Decode(&got))
+				assert.Equal(t, test.value, got, enc.String())
+			}
+		})
+	}
+
+	{
+		// The fields left out are not encoded, whatever their value:
+		data, err := MarshalBorsh(conditionalRecord{Version: 1, Authority: [2]byte{1, 2}, Delegate: 3})
+		require.NoError(t, err)
+		assert.Equal(t, "01"+"0000"+"00"+"00"+"00", hex.EncodeToString(data))
+	}
+}
+
+type conditionalBitfield struct {
+	Version  uint8
+	_        uint8 `bin:"bitfield if=Version>=2"`
+	IsSigner bool  `bin:"bits=1"`
+	Kind     uint8 `bin:"bits=3"`
+	Tail     uint8
+}
+
+func TestConditionalFields_Bitfield(t *testing.T) {
+	// The condition of the bitfield covers all its fields:
+	present := conditionalBitfield{Version: 2, IsSigner: true, Kind: 5, Tail: 7}
+	absent := conditionalBitfield{Version: 1, Tail: 7}
+	for _, enc := range []Encoding{EncodingBin, EncodingBorsh, EncodingBincode, EncodingPostcard, EncodingXDR} {
+		t.Run(enc.String(), func(t *testing.T) {
+			for _, value := range []conditionalBitfield{present, absent} {
+				buf := new(bytes.Buffer)
+				require.NoError(t, NewEncoderWithEncoding(buf, enc).Encode(value))
+
+				got := conditionalBitfield{IsSigner: true, Kind: 3}
+				require.NoError(t, NewDecoderWithEncoding(buf.Bytes(), enc).Decode(&got))
+				assert.Equal(t, value, got)
+			}
+		})
+	}
+
+	data, err := MarshalBorsh(present)
+	require.NoError(t, err)
+	assert.Equal(t, "02"+"0b"+"07", hex.EncodeToString(data))
+
+	data, err = MarshalBorsh(conditionalBitfield{Version: 1, IsSigner: true, Kind: 5, Tail: 7})
+	require.NoError(t, err)
+	assert.Equal(t, "01"+"07", hex.EncodeToString(data))
+}
+
+func TestConditionalFields_Errors(t *testing.T) {
+	_, err := MarshalBorsh(struct {
+		A uint8 `bin:"if=B==1"`
+		B uint8
+	}{})
+	require.EqualError(t, err, `field A: condition "B==1" requires a previous field B`)
+
+	_, err = MarshalBorsh(struct {
+		A uint8
+		B uint8 `bin:"if=A=>1"`
+	}{})
+	require.EqualError(t, err, `field B: invalid condition "A=>1"`)
+
+	_, err = MarshalBorsh(struct {
+		A bool
+		B uint8 `bin:"if=A>=1"`
+	}{})
+	require.EqualError(t, err, `field B: invalid condition "A>=1" on bool field A`)
+
+	err = UnmarshalBorsh(&struct {
+		A string
+		B uint8 `bin:"if=A==1"`
+	}{}, []byte{0, 0, 0, 0})
+	require.EqualError(t, err, `field B: condition "A==1" on field A of type string`)
+
+	_, err = MarshalBorsh(struct {
+		A uint8
+		_ uint8 `bin:"bitfield"`
+		B bool  `bin:"bits=1 if=A==1"`
+	}{})
+	require.EqualError(t, err, `field B: if= on a bits field, the condition goes on the bitfield _`)
+
+	_, err = MarshalRLP(conditionalRecord{})
+	require.EqualError(t, err, "rlp: conditionalRecord: field Authority: conditional fields are not supported")
+}
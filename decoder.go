@@ -93,6 +93,46 @@ func (dec *Decoder) IsCompactU16() bool {
 	return dec.encoding.IsCompactU16()
 }
 
+func (dec *Decoder) IsBCS() bool {
+	return dec.encoding.IsBCS()
+}
+
+func (dec *Decoder) IsSCALE() bool {
+	return dec.encoding.IsSCALE()
+}
+
+func (dec *Decoder) IsBincode() bool {
+	return dec.encoding.IsBincode()
+}
+
+func (dec *Decoder) IsEVMABI() bool {
+	return dec.encoding.IsEVMABI()
+}
+
+func (dec *Decoder) IsRLP() bool {
+	return dec.encoding.IsRLP()
+}
+
+func (dec *Decoder) IsSSZ() bool {
+	return dec.encoding.IsSSZ()
+}
+
+func (dec *Decoder) IsXDR() bool {
+	return dec.encoding.IsXDR()
+}
+
+func (dec *Decoder) IsBitcoin() bool {
+	return dec.encoding.IsBitcoin()
+}
+
+func (dec *Decoder) IsPostcard() bool {
+	return dec.encoding.IsPostcard()
+}
+
+func (dec *Decoder) IsReprC() bool {
+	return dec.encoding.IsReprC()
+}
+
 func NewDecoderWithEncoding(data []byte, enc Encoding) *Decoder {
 	if !isValidEncoding(enc) {
 		panic(fmt.Sprintf("provided encoding is not valid: %s", enc))
@@ -115,6 +155,68 @@ func NewCompactU16Decoder(data []byte) *Decoder {
 	return NewDecoderWithEncoding(data, EncodingCompactU16)
 }
 
+func NewBCSDecoder(data []byte) *Decoder {
+	return NewDecoderWithEncoding(data, EncodingBCS)
+}
+
+func NewSCALEDecoder(data []byte) *Decoder {
+	return NewDecoderWithEncoding(data, EncodingSCALE)
+}
+
+// NewBincodeDecoder returns a decoder using the default configuration
+// of bincode (1.x): fixed-size integers, u64 lengths and u32 enum tags.
+func NewBincodeDecoder(data []byte) *Decoder {
+	return NewDecoderWithEncoding(data, EncodingBincode)
+}
+
+// NewBincodeVarintDecoder returns a decoder using the varint configuration
+// of bincode, where integers, lengths and enum tags are varint-encoded.
+func NewBincodeVarintDecoder(data []byte) *Decoder {
+	return NewDecoderWithEncoding(data, EncodingBincodeVarint)
+}
+
+// NewEVMABIDecoder returns a decoder of the solidity contract ABI,
+// where each call to Decode reads a value encoded with `abi.encode`.
+func NewEVMABIDecoder(data []byte) *Decoder {
+	return NewDecoderWithEncoding(data, EncodingEVMABI)
+}
+
+// NewRLPDecoder returns a decoder of the Ethereum recursive length prefix
+// serialization, where each call to Decode reads one item.
+func NewRLPDecoder(data []byte) *Decoder {
+	return NewDecoderWithEncoding(data, EncodingRLP)
+}
+
+// NewSSZDecoder returns a decoder of the Simple Serialize format of
+// the Ethereum consensus layer. As SSZ values are not self-delimiting,
+// each call to Decode reads all the remaining bytes.
+func NewSSZDecoder(data []byte) *Decoder {
+	return NewDecoderWithEncoding(data, EncodingSSZ)
+}
+
+// NewXDRDecoder returns a decoder of the External Data Representation
+// (RFC 4506) used by Stellar.
+func NewXDRDecoder(data []byte) *Decoder {
+	return NewDecoderWithEncoding(data, EncodingXDR)
+}
+
+// NewBitcoinDecoder returns a decoder of the Bitcoin consensus serialization,
+// which rejects the non-canonical CompactSize lengths.
+func NewBitcoinDecoder(data []byte) *Decoder {
+	return NewDecoderWithEncoding(data, EncodingBitcoin)
+}
+
+// NewPostcardDecoder returns a decoder of the postcard format of Rust.
+func NewPostcardDecoder(data []byte) *Decoder {
+	return NewDecoderWithEncoding(data, EncodingPostcard)
+}
+
+// NewReprCDecoder returns a decoder of the `#[repr(C)]` memory layout,
+// which ignores the padding bytes.
+func NewReprCDecoder(data []byte) *Decoder {
+	return NewDecoderWithEncoding(data, EncodingReprC)
+}
+
 func (dec *Decoder) Decode(v interface{}) (err error) {
 	switch dec.encoding {
 	case EncodingBin:
@@ -123,28 +225,48 @@ func (dec *Decoder) Decode(v interface{}) (err error) {
 		return dec.decodeWithOptionBorsh(v, nil)
 	case EncodingCompactU16:
 		return dec.decodeWithOptionCompactU16(v, nil)
+	case EncodingEVMABI:
+		return dec.decodeWithOptionEVMABI(v, nil)
+	case EncodingRLP:
+		return dec.decodeWithOptionRLP(v, nil)
+	case EncodingSSZ:
+		return dec.decodeWithOptionSSZ(v, nil)
+	case EncodingReprC:
+		return dec.decodeWithOptionReprC(v, nil)
 	default:
+		if def, ok := encodingDefinition(dec.encoding); ok {
+			return dec.decodeWithOptionRegistered(def, v, nil)
+		}
 		panic(fmt.Errorf("encoding not implemented: %s", dec.encoding))
 	}
 }
 
-func sizeof(t reflect.Type, v reflect.Value) int {
+func sizeof(t reflect.Type, v reflect.Value) (int, error) {
+	if t == compactType {
+		c := v.Interface().(Compact)
+		if c.Hi != 0 || c.Lo > uint64(maxInt) {
+			return 0, fmt.Errorf("sizeof length %s overflows int", c)
+		}
+		return int(c.Lo), nil
+	}
 	switch t.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return int(v.Int())
+		return int(v.Int()), nil
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		n := int(v.Uint())
 		// all the builtin array length types are native int
 		// so this guards against weird truncation
 		if n < 0 {
-			return 0
+			return 0, nil
 		}
-		return n
+		return n, nil
 	default:
 		panic(fmt.Sprintf("sizeof field "))
 	}
 }
 
+const maxInt = int(^uint(0) >> 1)
+
 var ErrVarIntBufferSize = errors.New("varint: invalid buffer size")
 
 func (dec *Decoder) ReadUvarint64() (uint64, error) {
@@ -258,8 +380,93 @@ func (dec *Decoder) ReadLength() (length int, err error) {
 			return 0, err
 		}
 		length = val
+	case EncodingBCS:
+		val, err := dec.ReadULEB128()
+		if err != nil {
+			return 0, err
+		}
+		if val > BCSMaxSequenceLength {
+			return 0, fmt.Errorf("bcs: sequence length %d exceeds the maximum %d", val, BCSMaxSequenceLength)
+		}
+		if uint64(val) > uint64(dec.Remaining()) {
+			return 0, fmt.Errorf("bcs: sequence length %d exceeds the remaining %d bytes", val, dec.Remaining())
+		}
+		length = int(val)
+	case EncodingSCALE:
+		val, err := dec.ReadCompact()
+		if err != nil {
+			return 0, err
+		}
+		if val.Hi != 0 || val.Lo > math.MaxInt32 {
+			return 0, fmt.Errorf("scale: length %s too large", val)
+		}
+		if val.Lo > uint64(dec.Remaining()) {
+			return 0, fmt.Errorf("scale: length %d exceeds the remaining %d bytes", val.Lo, dec.Remaining())
+		}
+		length = int(val.Lo)
+	case EncodingBincode, EncodingBincodeVarint:
+		var val uint64
+		if dec.encoding == EncodingBincodeVarint {
+			val, err = dec.ReadBincodeVarint()
+		} else {
+			val, err = dec.ReadUint64(LE)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if val > uint64(dec.Remaining()) {
+			return 0, fmt.Errorf("bincode: length %d exceeds the remaining %d bytes", val, dec.Remaining())
+		}
+		length = int(val)
+	case EncodingEVMABI:
+		val, err := evmABIReadLength(dec.data, dec.pos)
+		if err != nil {
+			return 0, fmt.Errorf("abi: length: %w", err)
+		}
+		dec.pos += 32
+		length = val
+	case EncodingRLP:
+		return 0, errors.New("rlp: lengths are part of the item headers")
+	case EncodingSSZ:
+		return 0, errors.New("ssz: lengths are defined by the offsets")
+	case EncodingXDR:
+		val, err := dec.ReadUint32(BE)
+		if err != nil {
+			return 0, err
+		}
+		if uint64(val) > uint64(dec.Remaining()) {
+			return 0, fmt.Errorf("xdr: length %d exceeds the remaining %d bytes", val, dec.Remaining())
+		}
+		length = int(val)
+	case EncodingBitcoin:
+		val, err := dec.ReadCompactSize()
+		if err != nil {
+			return 0, err
+		}
+		if val > BitcoinMaxSize {
+			return 0, fmt.Errorf("bitcoin: length %d exceeds the maximum %d", val, BitcoinMaxSize)
+		}
+		if val > uint64(dec.Remaining()) {
+			return 0, fmt.Errorf("bitcoin: length %d exceeds the remaining %d bytes", val, dec.Remaining())
+		}
+		length = int(val)
+	case EncodingPostcard:
+		val, err := dec.ReadPostcardVarint(64)
+		if err != nil {
+			return 0, err
+		}
+		if val.Lo > uint64(dec.Remaining()) {
+			return 0, fmt.Errorf("postcard: length %d exceeds the remaining %d bytes", val.Lo, dec.Remaining())
+		}
+		length = int(val.Lo)
+	case EncodingReprC:
+		return 0, errors.New("reprc: layouts have no length prefixes")
 	default:
-		panic(fmt.Errorf("encoding not implemented: %s", dec.encoding))
+		def, ok := registeredEncoding(dec.encoding)
+		if !ok {
+			panic(fmt.Errorf("encoding not implemented: %s", dec.encoding))
+		}
+		return def.ReadLength(dec)
 	}
 	return
 }
@@ -338,6 +545,9 @@ func (dec *Decoder) ReadBool() (out bool, err error) {
 	if err != nil {
 		err = fmt.Errorf("readBool, %s", err)
 	}
+	if b > 1 && (dec.IsBCS() || dec.IsSCALE() || dec.IsBincode()) {
+		return false, fmt.Errorf("%s: invalid bool value %d", strings.ToLower(dec.encoding.String()), b)
+	}
 	out = b != 0
 	if traceEnabled {
 		zlog.Debug("decode: read bool", zap.Bool("val", out))
@@ -592,6 +802,47 @@ func (dec *Decoder) HasRemaining() bool {
 	return dec.Remaining() > 0
 }
 
+// DecodeSized decodes v from the next `size` bytes, which hold the whole
+// struct: `bin:"binary_extension"` fields are absent if the struct ends
+// before them, even when more data follows, and the other fields cannot be
+// read past its end. After decoding, the position is moved to the end of
+// the struct, skipping any unread (padding) bytes.
+func (dec *Decoder) DecodeSized(v interface{}, size int) (err error) {
+	if size < 0 || size > dec.Remaining() {
+		return fmt.Errorf("decode: struct size %d out of bounds, remaining bytes %d", size, dec.Remaining())
+	}
+
+	end := dec.pos + size
+	sub := &Decoder{
+		data:     dec.data[:end],
+		pos:      dec.pos,
+		encoding: dec.encoding,
+	}
+	if err = sub.Decode(v); err != nil {
+		return fmt.Errorf("decode %T of %d bytes: %w", v, size, err)
+	}
+	dec.pos = end
+	return nil
+}
+
+// isExtensionAbsent returns true if the data (or the sized struct)
+// ends before the current binary extension field. When padded is set,
+// a remainder made only of zero bytes is treated as absent too.
+func (dec *Decoder) isExtensionAbsent(padded bool) bool {
+	if dec.pos >= len(dec.data) {
+		return true
+	}
+	if !padded {
+		return false
+	}
+	for _, b := range dec.data[dec.pos:] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // indirect walks down v allocating pointers as needed,
 // until it gets to a non-pointer.
 // if it encounters an Unmarshaler, indirect stops and returns that.
@@ -76,6 +76,10 @@ func (dec *Decoder) decodeBin(rv reflect.Value, opt *option) (err error) {
 		unmarshaler, rv = indirect(rv, false)
 	}
 
+	if opt.hasFixedString() {
+		return dec.readFixedString(rv, opt)
+	}
+
 	if unmarshaler != nil {
 		if traceEnabled {
 			zlog.Debug("decode: using UnmarshalWithDecoder method to decode type")
@@ -86,6 +90,14 @@ func (dec *Decoder) decodeBin(rv reflect.Value, opt *option) (err error) {
 
 	switch rv.Kind() {
 	case reflect.String:
+		if opt.hasLenPrefix() {
+			data, e := dec.readStringWithLengthPrefix(opt)
+			if e != nil {
+				return e
+			}
+			rv.SetString(string(data))
+			return
+		}
 		s, e := dec.ReadRustString()
 		if e != nil {
 			err = e
@@ -170,7 +182,7 @@ func (dec *Decoder) decodeBin(rv reflect.Value, opt *option) (err error) {
 			l = opt.getSizeOfSlice()
 		} else {
 			// TODO: what type is length? Is it really Uvarint64?
-			length, err := dec.ReadUvarint64()
+			length, err := dec.readLengthPrefix(opt)
 			if err != nil {
 				return err
 			}
@@ -195,7 +207,7 @@ func (dec *Decoder) decodeBin(rv reflect.Value, opt *option) (err error) {
 
 	case reflect.Map:
 		// TODO: what type is length? Is it really Uvarint64?
-		l, err := dec.ReadUvarint64()
+		l, err := dec.readLengthPrefix(opt)
 		if err != nil {
 			return err
 		}
@@ -233,11 +245,14 @@ func (dec *Decoder) decodeStructBin(rt reflect.Type, rv reflect.Value) (err erro
 		zlog.Debug("decode: struct", zap.Int("fields", l), zap.Stringer("type", rv.Kind()))
 	}
 
-	sizeOfMap := map[string]int{}
+	fields := newStructFields(rv)
 	seenBinaryExtensionField := false
 	for i := 0; i < l; i++ {
 		structField := rt.Field(i)
-		fieldTag := parseFieldTag(structField.Tag)
+		fieldTag, err := parseStructFieldTag(structField)
+		if err != nil {
+			return err
+		}
 
 		if fieldTag.Skip {
 			if traceEnabled {
@@ -254,17 +269,21 @@ func (dec *Decoder) decodeStructBin(rt reflect.Type, rv reflect.Value) (err erro
 
 		if fieldTag.BinaryExtension {
 			seenBinaryExtensionField = true
-			// FIXME: This works only if what is in `d.data` is the actual full data buffer that
-			//        needs to be decoded. If there is for example two structs in the buffer, this
-			//        will not work as we would continue into the next struct.
-			//
-			//        But at the same time, does it make sense otherwise? What would be the inference
-			//        rule in the case of extra bytes available? Continue decoding and revert if it's
-			//        not working? But how to detect valid errors?
-			if len(dec.data[dec.pos:]) <= 0 {
+			// Without a struct boundary (see DecodeSized), this assumes that the data
+			// ends with the struct: with extra bytes available, we would continue
+			// into what follows the struct.
+			if dec.isExtensionAbsent(fieldTag.Padded) {
 				continue
 			}
 		}
+		decodeField, err := dec.decodeFieldTags(fields, i, fieldTag, fieldTag.Order, dec.decodeBin)
+		if err != nil {
+			return err
+		}
+		if !decodeField {
+			continue
+		}
+
 		v := rv.Field(i)
 		if !v.CanSet() {
 			// This means that the field cannot be set, to fix this
@@ -292,14 +311,7 @@ func (dec *Decoder) decodeStructBin(rt reflect.Type, rv reflect.Value) (err erro
 			continue
 		}
 
-		option := &option{
-			OptionalField: fieldTag.Optional,
-			Order:         fieldTag.Order,
-		}
-
-		if s, ok := sizeOfMap[structField.Name]; ok {
-			option.setSizeOfSlice(s)
-		}
+		option := fields.option(i, fieldTag, fieldTag.Order)
 
 		if traceEnabled {
 			zlog.Debug("decode: struct field",
@@ -314,15 +326,8 @@ func (dec *Decoder) decodeStructBin(rt reflect.Type, rv reflect.Value) (err erro
 			return fmt.Errorf("error while decoding %q field: %w", structField.Name, err)
 		}
 
-		if fieldTag.SizeOf != "" {
-			size := sizeof(structField.Type, v)
-			if traceEnabled {
-				zlog.Debug("setting size of field",
-					zap.String("field_name", fieldTag.SizeOf),
-					zap.Int("size", size),
-				)
-			}
-			sizeOfMap[fieldTag.SizeOf] = size
+		if err := fields.setSizeOf(i, fieldTag, v); err != nil {
+			return err
 		}
 	}
 	return
@@ -78,6 +78,10 @@ func (dec *Decoder) decodeBorsh(rv reflect.Value, opt *option) (err error) {
 	// Reset optionality so it won't propagate to child types:
 	opt = opt.clone().setIsOptional(false)
 
+	if opt.hasFixedString() {
+		return dec.readFixedString(rv, opt)
+	}
+
 	if unmarshaler != nil {
 		if traceEnabled {
 			zlog.Debug("decode: using UnmarshalWithDecoder method to decode type")
@@ -100,12 +104,12 @@ func (dec *Decoder) decodeBorsh(rv reflect.Value, opt *option) (err error) {
 	// 	rv.SetUint(n)
 	// 	return
 	case reflect.String:
-		s, e := dec.ReadString()
+		data, e := dec.readStringWithLengthPrefix(opt)
 		if e != nil {
 			err = e
 			return
 		}
-		rv.SetString(s)
+		rv.SetString(string(data))
 		return
 	case reflect.Uint8:
 		var n byte
@@ -185,7 +189,7 @@ func (dec *Decoder) decodeBorsh(rv reflect.Value, opt *option) (err error) {
 		if opt.hasSizeOfSlice() {
 			l = opt.getSizeOfSlice()
 		} else {
-			length, err := dec.ReadUint32(LE)
+			length, err := dec.readLengthPrefix(opt)
 			if err != nil {
 				return err
 			}
@@ -214,7 +218,7 @@ func (dec *Decoder) decodeBorsh(rv reflect.Value, opt *option) (err error) {
 		}
 
 	case reflect.Map:
-		l, err := dec.ReadUint32(LE)
+		l, err := dec.readLengthPrefix(opt)
 		if err != nil {
 			return err
 		}
@@ -287,11 +291,14 @@ func (dec *Decoder) decodeStructBorsh(rt reflect.Type, rv reflect.Value) (err er
 		}
 	}
 
-	sizeOfMap := map[string]int{}
+	fields := newStructFields(rv)
 	seenBinaryExtensionField := false
 	for i := 0; i < l; i++ {
 		structField := rt.Field(i)
-		fieldTag := parseFieldTag(structField.Tag)
+		fieldTag, err := parseStructFieldTag(structField)
+		if err != nil {
+			return err
+		}
 
 		if fieldTag.Skip {
 			if traceEnabled {
@@ -308,17 +315,21 @@ func (dec *Decoder) decodeStructBorsh(rt reflect.Type, rv reflect.Value) (err er
 
 		if fieldTag.BinaryExtension {
 			seenBinaryExtensionField = true
-			// FIXME: This works only if what is in `d.data` is the actual full data buffer that
-			//        needs to be decoded. If there is for example two structs in the buffer, this
-			//        will not work as we would continue into the next struct.
-			//
-			//        But at the same time, does it make sense otherwise? What would be the inference
-			//        rule in the case of extra bytes available? Continue decoding and revert if it's
-			//        not working? But how to detect valid errors?
-			if len(dec.data[dec.pos:]) <= 0 {
+			// Without a struct boundary (see DecodeSized), this assumes that the data
+			// ends with the struct: with extra bytes available, we would continue
+			// into what follows the struct.
+			if dec.isExtensionAbsent(fieldTag.Padded) {
 				continue
 			}
 		}
+		decodeField, err := dec.decodeFieldTags(fields, i, fieldTag, fieldTag.Order, dec.decodeBorsh)
+		if err != nil {
+			return err
+		}
+		if !decodeField {
+			continue
+		}
+
 		v := rv.Field(i)
 		if !v.CanSet() {
 			// This means that the field cannot be set, to fix this
@@ -346,14 +357,7 @@ func (dec *Decoder) decodeStructBorsh(rt reflect.Type, rv reflect.Value) (err er
 			continue
 		}
 
-		option := &option{
-			OptionalField: fieldTag.Optional,
-			Order:         fieldTag.Order,
-		}
-
-		if s, ok := sizeOfMap[structField.Name]; ok {
-			option.setSizeOfSlice(s)
-		}
+		option := fields.option(i, fieldTag, fieldTag.Order)
 
 		if traceEnabled {
 			zlog.Debug("decode: struct field",
@@ -377,6 +381,9 @@ func (dec *Decoder) decodeStructBorsh(rt reflect.Type, rv reflect.Value) (err er
 					return err
 				}
 				v.Set(reflect.ValueOf(val).Elem())
+				if err := fields.setSizeOf(i, fieldTag, v); err != nil {
+					return err
+				}
 				continue
 			case vImplements:
 				m := reflect.New(rt.Elem())
@@ -386,6 +393,9 @@ func (dec *Decoder) decodeStructBorsh(rt reflect.Type, rv reflect.Value) (err er
 					return err
 				}
 				v.Set(reflect.ValueOf(val))
+				if err := fields.setSizeOf(i, fieldTag, v); err != nil {
+					return err
+				}
 				continue
 			}
 		}
@@ -394,15 +404,8 @@ func (dec *Decoder) decodeStructBorsh(rt reflect.Type, rv reflect.Value) (err er
 			return fmt.Errorf("error while decoding %q field: %w", structField.Name, err)
 		}
 
-		if fieldTag.SizeOf != "" {
-			size := sizeof(structField.Type, v)
-			if traceEnabled {
-				zlog.Debug("setting size of field",
-					zap.String("field_name", fieldTag.SizeOf),
-					zap.Int("size", size),
-				)
-			}
-			sizeOfMap[fieldTag.SizeOf] = size
+		if err := fields.setSizeOf(i, fieldTag, v); err != nil {
+			return err
 		}
 	}
 	return
@@ -75,6 +75,10 @@ func (dec *Decoder) decodeCompactU16(rv reflect.Value, opt *option) (err error)
 		unmarshaler, rv = indirect(rv, false)
 	}
 
+	if opt.hasFixedString() {
+		return dec.readFixedString(rv, opt)
+	}
+
 	if unmarshaler != nil {
 		if traceEnabled {
 			zlog.Debug("decode: using UnmarshalWithDecoder method to decode type")
@@ -85,12 +89,12 @@ func (dec *Decoder) decodeCompactU16(rv reflect.Value, opt *option) (err error)
 
 	switch rv.Kind() {
 	case reflect.String:
-		s, e := dec.ReadString()
+		data, e := dec.readStringWithLengthPrefix(opt)
 		if e != nil {
 			err = e
 			return
 		}
-		rv.SetString(s)
+		rv.SetString(string(data))
 		return
 	case reflect.Uint8:
 		var n byte
@@ -168,7 +172,7 @@ func (dec *Decoder) decodeCompactU16(rv reflect.Value, opt *option) (err error)
 		if opt.hasSizeOfSlice() {
 			l = opt.getSizeOfSlice()
 		} else {
-			length, err := dec.ReadCompactU16Length()
+			length, err := dec.readLengthPrefix(opt)
 			if err != nil {
 				return err
 			}
@@ -192,7 +196,7 @@ func (dec *Decoder) decodeCompactU16(rv reflect.Value, opt *option) (err error)
 		}
 
 	case reflect.Map:
-		l, err := dec.ReadCompactU16Length()
+		l, err := dec.readLengthPrefix(opt)
 		if err != nil {
 			return err
 		}
@@ -230,11 +234,14 @@ func (dec *Decoder) decodeStructCompactU16(rt reflect.Type, rv reflect.Value) (e
 		zlog.Debug("decode: struct", zap.Int("fields", l), zap.Stringer("type", rv.Kind()))
 	}
 
-	sizeOfMap := map[string]int{}
+	fields := newStructFields(rv)
 	seenBinaryExtensionField := false
 	for i := 0; i < l; i++ {
 		structField := rt.Field(i)
-		fieldTag := parseFieldTag(structField.Tag)
+		fieldTag, err := parseStructFieldTag(structField)
+		if err != nil {
+			return err
+		}
 
 		if fieldTag.Skip {
 			if traceEnabled {
@@ -251,17 +258,21 @@ func (dec *Decoder) decodeStructCompactU16(rt reflect.Type, rv reflect.Value) (e
 
 		if fieldTag.BinaryExtension {
 			seenBinaryExtensionField = true
-			// FIXME: This works only if what is in `d.data` is the actual full data buffer that
-			//        needs to be decoded. If there is for example two structs in the buffer, this
-			//        will not work as we would continue into the next struct.
-			//
-			//        But at the same time, does it make sense otherwise? What would be the inference
-			//        rule in the case of extra bytes available? Continue decoding and revert if it's
-			//        not working? But how to detect valid errors?
-			if len(dec.data[dec.pos:]) <= 0 {
+			// Without a struct boundary (see DecodeSized), this assumes that the data
+			// ends with the struct: with extra bytes available, we would continue
+			// into what follows the struct.
+			if dec.isExtensionAbsent(fieldTag.Padded) {
 				continue
 			}
 		}
+		decodeField, err := dec.decodeFieldTags(fields, i, fieldTag, fieldTag.Order, dec.decodeCompactU16)
+		if err != nil {
+			return err
+		}
+		if !decodeField {
+			continue
+		}
+
 		v := rv.Field(i)
 		if !v.CanSet() {
 			// This means that the field cannot be set, to fix this
@@ -289,14 +300,7 @@ func (dec *Decoder) decodeStructCompactU16(rt reflect.Type, rv reflect.Value) (e
 			continue
 		}
 
-		option := &option{
-			OptionalField: fieldTag.Optional,
-			Order:         fieldTag.Order,
-		}
-
-		if s, ok := sizeOfMap[structField.Name]; ok {
-			option.setSizeOfSlice(s)
-		}
+		option := fields.option(i, fieldTag, fieldTag.Order)
 
 		if traceEnabled {
 			zlog.Debug("decode: struct field",
@@ -311,15 +315,8 @@ func (dec *Decoder) decodeStructCompactU16(rt reflect.Type, rv reflect.Value) (e
 			return fmt.Errorf("error while decoding %q field: %w", structField.Name, err)
 		}
 
-		if fieldTag.SizeOf != "" {
-			size := sizeof(structField.Type, v)
-			if traceEnabled {
-				zlog.Debug("setting size of field",
-					zap.String("field_name", fieldTag.SizeOf),
-					zap.Int("size", size),
-				)
-			}
-			sizeOfMap[fieldTag.SizeOf] = size
+		if err := fields.setSizeOf(i, fieldTag, v); err != nil {
+			return err
 		}
 	}
 	return
@@ -0,0 +1,278 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+// decodeWithOptionEVMABI decodes the tuple starting at the current position;
+// the position is then moved after the last byte read (head or tail).
+func (dec *Decoder) decodeWithOptionEVMABI(v interface{}, opt *option) (err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidDecoderError{reflect.TypeOf(v)}
+	}
+	if opt == nil {
+		opt = newDefaultOption()
+	}
+	dec.currentFieldOpt = opt
+
+	// Custom unmarshalers (e.g. of variants) can read a selector before the arguments:
+	if unmarshaler, ok := v.(BinaryUnmarshaler); ok && !isEVMABIBuiltinType(rv.Type()) {
+		return unmarshaler.UnmarshalWithDecoder(dec)
+	}
+	rv = rv.Elem()
+
+	if traceEnabled {
+		zlog.Debug("decode: abi", zap.Stringer("type", rv.Type()), zap.Int("pos", dec.pos))
+	}
+
+	var end int
+	if rv.Kind() == reflect.Struct && !isEVMABIBuiltinType(rv.Type()) {
+		end, err = evmABIDecodeValue(dec.data, dec.pos, rv)
+	} else {
+		end, err = evmABIDecodeTuple(dec.data, dec.pos, []reflect.Value{rv})
+	}
+	if err != nil {
+		return err
+	}
+	dec.pos = end
+	return nil
+}
+
+// evmABIDecodeTuple decodes the values of the tuple starting at base, and
+// returns the position following the last byte read.
+func evmABIDecodeTuple(data []byte, base int, values []reflect.Value) (end int, err error) {
+	pos := base
+	for _, v := range values {
+		if !evmABIIsDynamic(v.Type()) {
+			if pos, err = evmABIDecodeValue(data, pos, v); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		offset, err := evmABIReadLength(data, pos)
+		if err != nil {
+			return 0, fmt.Errorf("abi: offset: %w", err)
+		}
+		valueEnd, err := evmABIDecodeValue(data, base+offset, v)
+		if err != nil {
+			return 0, err
+		}
+		if valueEnd > end {
+			end = valueEnd
+		}
+		pos += 32
+	}
+	if pos > end {
+		end = pos
+	}
+	return end, nil
+}
+
+func evmABIDecodeValue(data []byte, at int, rv reflect.Value) (end int, err error) {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	rt := rv.Type()
+
+	if evmABIIsWord(rt) {
+		word, err := evmABIWord(data, at)
+		if err != nil {
+			return 0, err
+		}
+		if err := evmABISetWord(word, rv); err != nil {
+			return 0, err
+		}
+		return at + 32, nil
+	}
+
+	switch rt.Kind() {
+	case reflect.String, reflect.Slice:
+		length, err := evmABIReadLength(data, at)
+		if err != nil {
+			return 0, fmt.Errorf("abi: length: %w", err)
+		}
+		if rt.Kind() == reflect.String || rt.Elem().Kind() == reflect.Uint8 {
+			start := at + 32
+			if length > len(data)-start {
+				return 0, fmt.Errorf("abi: %d bytes required, remaining %d", length, len(data)-start)
+			}
+			content := make([]byte, length)
+			copy(content, data[start:])
+			if rt.Kind() == reflect.String {
+				rv.SetString(string(content))
+			} else if length > 0 {
+				rv.SetBytes(content)
+			}
+			end := start + evmABIPaddedLength(length)
+			if end > len(data) {
+				end = len(data)
+			}
+			return end, nil
+		}
+		// Each element takes at least one word in the head:
+		if length > (len(data)-at-32)/32 {
+			return 0, fmt.Errorf("abi: array length %d exceeds the data", length)
+		}
+		if length == 0 {
+			// Empty slices are left nil
+			return at + 32, nil
+		}
+		rv.Set(reflect.MakeSlice(rt, length, length))
+		return evmABIDecodeTuple(data, at+32, evmABIElements(rv))
+	case reflect.Array:
+		return evmABIDecodeTuple(data, at, evmABIElements(rv))
+	case reflect.Struct:
+		if isComplexEnumType(rt) {
+			return 0, fmt.Errorf("abi: complex enum %s is not supported", rt)
+		}
+		fields, err := evmABIFields(rt)
+		if err != nil {
+			return 0, fmt.Errorf("abi: type %s: %w", rt, err)
+		}
+		values := make([]reflect.Value, len(fields))
+		for i, index := range fields {
+			values[i] = rv.Field(index)
+		}
+		return evmABIDecodeTuple(data, at, values)
+	}
+	return 0, fmt.Errorf("abi: unsupported type %q", rt)
+}
+
+// evmABIIsWord returns true if the values of type rt are encoded in a single word.
+func evmABIIsWord(rt reflect.Type) bool {
+	if isEVMABIBuiltinType(rt) {
+		return true
+	}
+	switch rt.Kind() {
+	case reflect.Bool,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	case reflect.Array:
+		return rt.Elem().Kind() == reflect.Uint8 && rt.Len() >= 1 && rt.Len() <= 32
+	}
+	return false
+}
+
+// evmABISetWord sets rv from a word, checking that the padding
+// is valid for the type of rv.
+func evmABISetWord(word []byte, rv reflect.Value) error {
+	rt := rv.Type()
+	switch rt {
+	case uint128Type:
+		if !isFilledWith(word[:16], 0) {
+			return fmt.Errorf("abi: value overflows uint128")
+		}
+		rv.Set(reflect.ValueOf(Uint128{
+			Hi: binary.BigEndian.Uint64(word[16:24]),
+			Lo: binary.BigEndian.Uint64(word[24:]),
+		}))
+		return nil
+	case int128Type:
+		if !isSignExtension(word, 16) {
+			return fmt.Errorf("abi: value overflows int128")
+		}
+		rv.Set(reflect.ValueOf(Int128{
+			Hi: binary.BigEndian.Uint64(word[16:24]),
+			Lo: binary.BigEndian.Uint64(word[24:]),
+		}))
+		return nil
+	case uint256Type, int256Type:
+		reflect.Copy(rv, reflect.ValueOf(word))
+		return nil
+	case evmAddressType:
+		if !isFilledWith(word[:12], 0) {
+			return errors.New("abi: invalid address padding")
+		}
+		reflect.Copy(rv, reflect.ValueOf(word[12:]))
+		return nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		if !isFilledWith(word[:31], 0) || word[31] > 1 {
+			return errors.New("abi: invalid bool value")
+		}
+		rv.SetBool(word[31] == 1)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size := rt.Bits() / 8
+		if !isFilledWith(word[:32-size], 0) {
+			return fmt.Errorf("abi: value overflows uint%d", rt.Bits())
+		}
+		rv.SetUint(binary.BigEndian.Uint64(word[24:]))
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size := rt.Bits() / 8
+		if !isSignExtension(word, size) {
+			return fmt.Errorf("abi: value overflows int%d", rt.Bits())
+		}
+		rv.SetInt(int64(binary.BigEndian.Uint64(word[24:])))
+	case reflect.Array:
+		// bytesN values are right-padded:
+		reflect.Copy(rv, reflect.ValueOf(word))
+	default:
+		return fmt.Errorf("abi: unsupported type %q", rt)
+	}
+	return nil
+}
+
+func evmABIWord(data []byte, at int) ([]byte, error) {
+	if at < 0 || at+32 > len(data) {
+		return nil, fmt.Errorf("abi: word at %d out of bounds (data size %d)", at, len(data))
+	}
+	return data[at : at+32], nil
+}
+
+// evmABIReadLength reads a length or an offset, which must fit in the data.
+func evmABIReadLength(data []byte, at int) (int, error) {
+	word, err := evmABIWord(data, at)
+	if err != nil {
+		return 0, err
+	}
+	value := binary.BigEndian.Uint64(word[24:])
+	if !isFilledWith(word[:24], 0) || value > uint64(len(data)) {
+		return 0, fmt.Errorf("value at %d too large", at)
+	}
+	return int(value), nil
+}
+
+func isFilledWith(buf []byte, b byte) bool {
+	for _, v := range buf {
+		if v != b {
+			return false
+		}
+	}
+	return true
+}
+
+// isSignExtension returns true if the word holds a two's complement
+// integer of the provided size in bytes, sign-extended to 32 bytes.
+func isSignExtension(word []byte, size int) bool {
+	var ext byte
+	if word[32-size]&0x80 != 0 {
+		ext = 0xff
+	}
+	return isFilledWith(word[:32-size], ext)
+}
@@ -0,0 +1,381 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+func (dec *Decoder) decodeWithOptionRegistered(def EncodingDefinition, v interface{}, option *option) (err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return &InvalidDecoderError{reflect.TypeOf(v)}
+	}
+
+	// We decode rv not rv.Elem because the Unmarshaler interface
+	// test must be applied at the top level of the value.
+	return dec.decodeRegistered(def, rv, option)
+}
+
+// decodeRegistered decodes rv with the rules of an encoding definition:
+// a built-in one (see builtinEncodings), or one registered with RegisterEncoding.
+func (dec *Decoder) decodeRegistered(def EncodingDefinition, rv reflect.Value, opt *option) (err error) {
+	if opt == nil {
+		opt = &option{Order: def.ByteOrder()}
+	}
+	dec.currentFieldOpt = opt
+
+	unmarshaler, rv := indirect(rv, opt.isOptional())
+
+	if traceEnabled {
+		zlog.Debug("decode: type",
+			zap.String("encoding", def.Name()),
+			zap.Stringer("value_kind", rv.Kind()),
+			zap.Bool("has_unmarshaler", (unmarshaler != nil)),
+			zap.Reflect("options", opt),
+		)
+	}
+
+	rules := rulesOf(def)
+	if opt.isOptional() {
+		if done, err := rules.decodeOptional(dec, rv); done {
+			return err
+		}
+		isPresent, e := def.ReadOptionTag(dec)
+		if e != nil {
+			return fmt.Errorf("decode: %s isPresent, %w", rv.Type(), e)
+		}
+
+		if !isPresent {
+			if traceEnabled {
+				zlog.Debug("decode: skipping optional value", zap.Stringer("type", rv.Kind()))
+			}
+			rv.Set(reflect.Zero(rv.Type()))
+			return
+		}
+
+		// we have ptr here we should not go get the element
+		unmarshaler, rv = indirect(rv, false)
+	}
+	// Reset optionality so it won't propagate to child types:
+	opt = opt.clone().setIsOptional(false)
+
+	if opt.hasFixedString() {
+		return dec.readFixedString(rv, opt)
+	}
+
+	value := rv
+	if unmarshaler != nil {
+		// The value itself is needed for the named primitive types:
+		if ptr := reflect.ValueOf(unmarshaler); ptr.Kind() == reflect.Ptr {
+			value = ptr.Elem()
+		}
+	}
+	if value.IsValid() {
+		if done, err := rules.decodeValue(dec, value, opt, unmarshaler != nil); done {
+			return err
+		}
+	}
+
+	if unmarshaler != nil {
+		if traceEnabled {
+			zlog.Debug("decode: using UnmarshalWithDecoder method to decode type")
+		}
+		return unmarshaler.UnmarshalWithDecoder(dec)
+	}
+
+	rt := rv.Type()
+	switch rv.Kind() {
+	case reflect.String:
+		if opt.hasLenPrefix() {
+			var data []byte
+			data, err = dec.readStringWithLengthPrefix(opt)
+			rv.SetString(string(data))
+			return
+		}
+		var s string
+		s, err = def.ReadString(dec)
+		rv.SetString(s)
+		return
+	case reflect.Bool:
+		var b bool
+		b, err = def.ReadBool(dec)
+		rv.SetBool(b)
+		return
+	case reflect.Uint8:
+		var n byte
+		n, err = dec.ReadByte()
+		rv.SetUint(uint64(n))
+		return
+	case reflect.Int8:
+		var n int8
+		n, err = dec.ReadInt8()
+		rv.SetInt(int64(n))
+		return
+	case reflect.Int16:
+		var n int16
+		n, err = dec.ReadInt16(opt.Order)
+		rv.SetInt(int64(n))
+		return
+	case reflect.Uint16:
+		var n uint16
+		n, err = dec.ReadUint16(opt.Order)
+		rv.SetUint(uint64(n))
+		return
+	case reflect.Int32:
+		var n int32
+		n, err = dec.ReadInt32(opt.Order)
+		rv.SetInt(int64(n))
+		return
+	case reflect.Uint32:
+		var n uint32
+		n, err = dec.ReadUint32(opt.Order)
+		rv.SetUint(uint64(n))
+		return
+	case reflect.Int64, reflect.Int:
+		var n int64
+		n, err = dec.ReadInt64(opt.Order)
+		rv.SetInt(n)
+		return
+	case reflect.Uint64, reflect.Uint:
+		var n uint64
+		n, err = dec.ReadUint64(opt.Order)
+		rv.SetUint(n)
+		return
+	case reflect.Float32:
+		var f float32
+		f, err = dec.ReadFloat32(opt.Order)
+		rv.SetFloat(float64(f))
+		return
+	case reflect.Float64:
+		var f float64
+		f, err = dec.ReadFloat64(opt.Order)
+		rv.SetFloat(f)
+		return
+	case reflect.Interface:
+		// Skip: cannot know the concrete type of the interface.
+		// The parent container should implement a custom decoder.
+		return nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Array:
+		length := rt.Len()
+		if traceEnabled {
+			zlog.Debug("decoding: reading array", zap.Int("length", length))
+		}
+		if rt.Elem().Kind() == reflect.Uint8 {
+			var data []byte
+			if data, err = dec.ReadNBytes(length); err != nil {
+				return
+			}
+			reflect.Copy(rv, reflect.ValueOf(data))
+			return
+		}
+		for i := 0; i < length; i++ {
+			if err = dec.decodeRegistered(def, rv.Index(i), nil); err != nil {
+				return
+			}
+		}
+		return
+	case reflect.Slice:
+		var l int
+		if opt.hasSizeOfSlice() {
+			l = opt.getSizeOfSlice()
+		} else {
+			if l, err = dec.readRegisteredLength(def, opt); err != nil {
+				return
+			}
+		}
+
+		if traceEnabled {
+			zlog.Debug("reading slice", zap.Int("len", l), typeField("type", rv))
+		}
+
+		if l == 0 {
+			// Empty slices are left nil
+			return
+		}
+
+		rv.Set(reflect.MakeSlice(rt, l, l))
+		for i := 0; i < l; i++ {
+			if err = dec.decodeRegistered(def, rv.Index(i), nil); err != nil {
+				return
+			}
+		}
+	case reflect.Struct:
+		if err = dec.decodeStructRegistered(def, rt, rv); err != nil {
+			return
+		}
+	case reflect.Map:
+		l, err := dec.readRegisteredLength(def, opt)
+		if err != nil {
+			return err
+		}
+		if l == 0 {
+			// If the map has no content, keep it nil.
+			return nil
+		}
+		rv.Set(reflect.MakeMap(rt))
+		var prevKey []byte
+		for i := 0; i < l; i++ {
+			start := dec.pos
+			key := reflect.New(rt.Key())
+			if err := dec.decodeRegistered(def, key.Elem(), nil); err != nil {
+				return err
+			}
+			if err := rules.checkMapKey(prevKey, dec.data[start:dec.pos]); err != nil {
+				return err
+			}
+			prevKey = dec.data[start:dec.pos]
+			val := reflect.New(rt.Elem())
+			if err := dec.decodeRegistered(def, val.Elem(), nil); err != nil {
+				return err
+			}
+			rv.SetMapIndex(key.Elem(), val.Elem())
+		}
+		return nil
+	default:
+		return fmt.Errorf("decode: unsupported type %q", rt)
+	}
+	return
+}
+
+func (dec *Decoder) decodeComplexEnumRegistered(def EncodingDefinition, rv reflect.Value) error {
+	rt := rv.Type()
+	// read enum identifier
+	tmp, err := def.ReadEnumTag(dec)
+	if err != nil {
+		return err
+	}
+	if uint64(tmp)+1 >= uint64(rt.NumField()) {
+		return errors.New("complex enum too large")
+	}
+	enum := BorshEnum(tmp)
+	rv.Field(0).Set(reflect.ValueOf(enum).Convert(rv.Field(0).Type()))
+
+	// read enum field
+	field := rv.Field(int(enum) + 1)
+	return dec.decodeRegistered(def, field, nil)
+}
+
+func (dec *Decoder) decodeStructRegistered(def EncodingDefinition, rt reflect.Type, rv reflect.Value) (err error) {
+	l := rv.NumField()
+
+	if traceEnabled {
+		zlog.Debug("decode: struct", zap.Int("fields", l), zap.Stringer("type", rv.Kind()))
+	}
+
+	// Handle complex enum:
+	if isComplexEnumType(rt) {
+		return dec.decodeComplexEnumRegistered(def, rv)
+	}
+
+	rules := rulesOf(def)
+	decode := func(rv reflect.Value, opt *option) error { return dec.decodeRegistered(def, rv, opt) }
+	fields := newStructFields(rv)
+	seenBinaryExtensionField := false
+	for i := 0; i < l; i++ {
+		structField := rt.Field(i)
+		fieldTag, err := parseStructFieldTag(structField)
+		if err != nil {
+			return err
+		}
+
+		if fieldTag.Skip {
+			if traceEnabled {
+				zlog.Debug("decode: skipping struct field with skip flag",
+					zap.String("struct_field_name", structField.Name),
+				)
+			}
+			continue
+		}
+
+		if !fieldTag.BinaryExtension && seenBinaryExtensionField {
+			panic(fmt.Sprintf("the `bin:\"binary_extension\"` tags must be packed together at the end of struct fields, problematic field %q", structField.Name))
+		}
+
+		if fieldTag.BinaryExtension {
+			seenBinaryExtensionField = true
+			if dec.isExtensionAbsent(fieldTag.Padded) {
+				continue
+			}
+		}
+
+		option := fields.option(i, fieldTag, registeredFieldOrder(def, structField.Tag))
+		if err := rules.fieldOption(fieldTag, option); err != nil {
+			return fmt.Errorf("error while decoding %q field: %w", structField.Name, err)
+		}
+
+		decodeField, err := dec.decodeFieldTags(fields, i, fieldTag, option.Order, decode)
+		if err != nil {
+			return err
+		}
+		if !decodeField {
+			continue
+		}
+
+		v := rv.Field(i)
+		if !v.CanSet() {
+			if traceEnabled {
+				zlog.Debug("skipping struct field that cannot be addressed",
+					zap.String("struct_field_name", structField.Name),
+					zap.Stringer("struct_value_type", v.Kind()),
+				)
+			}
+			continue
+		}
+
+		if traceEnabled {
+			zlog.Debug("decode: struct field",
+				zap.Stringer("struct_field_value_type", v.Kind()),
+				zap.String("struct_field_name", structField.Name),
+				zap.Reflect("struct_field_tags", fieldTag),
+				zap.Reflect("struct_field_option", option),
+			)
+		}
+
+		if err = dec.decodeRegistered(def, v, option); err != nil {
+			return fmt.Errorf("error while decoding %q field: %w", structField.Name, err)
+		}
+
+		if err := fields.setSizeOf(i, fieldTag, v); err != nil {
+			return err
+		}
+	}
+	return
+}
+
+// readRegisteredLength reads a length with the prefix of the `len=` tag
+// of the field, or else with the one of the encoding, and bounds it by the
+// remaining bytes (as every element or entry takes at least one).
+func (dec *Decoder) readRegisteredLength(def EncodingDefinition, opt *option) (int, error) {
+	if opt.hasLenPrefix() {
+		return dec.readLengthPrefix(opt)
+	}
+	length, err := def.ReadLength(dec)
+	if err != nil {
+		return 0, err
+	}
+	if length < 0 || length > dec.Remaining() {
+		return 0, fmt.Errorf("%s: length %d exceeds the remaining %d bytes", strings.ToLower(def.Name()), length, dec.Remaining())
+	}
+	return length, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+func (dec *Decoder) decodeWithOptionReprC(v interface{}, opt *option) (err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidDecoderError{reflect.TypeOf(v)}
+	}
+	if opt == nil {
+		opt = newDefaultOption()
+	}
+	dec.currentFieldOpt = opt
+
+	rv = rv.Elem()
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+
+	t, err := reprCTypeOf(rv.Type(), false, opt.Order)
+	if err != nil {
+		return err
+	}
+	if traceEnabled {
+		zlog.Debug("decode: reprc", zap.Stringer("type", rv.Type()), zap.Int("size", t.size), zap.Int("pos", dec.pos))
+	}
+
+	data, err := dec.ReadNBytes(t.size)
+	if err != nil {
+		return fmt.Errorf("reprc: %w", err)
+	}
+	return reprCGet(data, rv, t)
+}
@@ -0,0 +1,246 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+// decodeWithOptionRLP decodes the item at the current position,
+// and moves the position after it.
+func (dec *Decoder) decodeWithOptionRLP(v interface{}, opt *option) (err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidDecoderError{reflect.TypeOf(v)}
+	}
+	if opt == nil {
+		opt = newDefaultOption()
+	}
+	dec.currentFieldOpt = opt
+
+	// Custom unmarshalers (e.g. of variants) can read their items directly:
+	if rv.Elem().Kind() == reflect.Struct {
+		if unmarshaler, ok := v.(BinaryUnmarshaler); ok && !isRLPBuiltinType(rv.Type()) {
+			return unmarshaler.UnmarshalWithDecoder(dec)
+		}
+	}
+	rv = rv.Elem()
+
+	if traceEnabled {
+		zlog.Debug("decode: rlp", zap.Stringer("type", rv.Type()), zap.Int("pos", dec.pos))
+	}
+
+	rest, err := rlpDecodeValue(dec.data[dec.pos:], rv)
+	if err != nil {
+		return rlpWrapError(err, rlpRootName(rv.Type()))
+	}
+	dec.pos = len(dec.data) - len(rest)
+	return nil
+}
+
+// rlpDecodeValue decodes the first item of data into rv,
+// and returns the bytes following the item.
+func rlpDecodeValue(data []byte, rv reflect.Value) (rest []byte, err error) {
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return rlpDecodeValue(data, rv.Elem())
+	}
+	rt := rv.Type()
+
+	isList, content, rest, err := rlpSplit(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if rt == rlpRawType {
+		raw := make([]byte, len(data)-len(rest))
+		copy(raw, data)
+		rv.SetBytes(raw)
+		return rest, nil
+	}
+	if !rlpIsList(rt) && isList {
+		return nil, ErrRLPExpectedString
+	}
+
+	switch rt {
+	case bigIntType:
+		if err := rlpCheckInteger(content, len(content)); err != nil {
+			return nil, err
+		}
+		rv.Set(reflect.ValueOf(*new(big.Int).SetBytes(content)))
+		return rest, nil
+	case uint128Type:
+		if err := rlpCheckInteger(content, 16); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 16)
+		copy(buf[16-len(content):], content)
+		rv.Set(reflect.ValueOf(Uint128{
+			Hi: binary.BigEndian.Uint64(buf),
+			Lo: binary.BigEndian.Uint64(buf[8:]),
+		}))
+		return rest, nil
+	case uint256Type:
+		if err := rlpCheckInteger(content, 32); err != nil {
+			return nil, err
+		}
+		var value Uint256
+		copy(value[32-len(content):], content)
+		rv.Set(reflect.ValueOf(value))
+		return rest, nil
+	case int128Type, int256Type, float128Type:
+		return nil, fmt.Errorf("unsupported type %q", rt)
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		switch {
+		case len(content) == 0:
+			rv.SetBool(false)
+		case len(content) == 1 && content[0] == 0x01:
+			rv.SetBool(true)
+		default:
+			return nil, fmt.Errorf("invalid bool value 0x%x", content)
+		}
+		return rest, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if err := rlpCheckInteger(content, int(rt.Size())); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 8)
+		copy(buf[8-len(content):], content)
+		rv.SetUint(binary.BigEndian.Uint64(buf))
+		return rest, nil
+	case reflect.String:
+		rv.SetString(string(content))
+		return rest, nil
+	case reflect.Slice:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			if len(content) == 0 {
+				// Empty slices are left nil
+				rv.Set(reflect.Zero(rt))
+				return rest, nil
+			}
+			buf := make([]byte, len(content))
+			copy(buf, content)
+			rv.SetBytes(buf)
+			return rest, nil
+		}
+	case reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			if len(content) != rt.Len() {
+				return nil, fmt.Errorf("expected %d bytes, got %d", rt.Len(), len(content))
+			}
+			reflect.Copy(rv, reflect.ValueOf(content))
+			return rest, nil
+		}
+	}
+
+	if rv.CanAddr() {
+		if unmarshaler, ok := rv.Addr().Interface().(BinaryUnmarshaler); ok {
+			sub := NewRLPDecoder(data)
+			if err := unmarshaler.UnmarshalWithDecoder(sub); err != nil {
+				return nil, err
+			}
+			return data[sub.pos:], nil
+		}
+	}
+
+	if !isList {
+		return nil, ErrRLPExpectedList
+	}
+	switch rt.Kind() {
+	case reflect.Slice:
+		rv.Set(reflect.Zero(rt))
+		for i := 0; len(content) > 0; i++ {
+			elem := reflect.New(rt.Elem()).Elem()
+			if content, err = rlpDecodeValue(content, elem); err != nil {
+				return nil, rlpWrapError(err, fmt.Sprintf("[%d]", i))
+			}
+			rv.Set(reflect.Append(rv, elem))
+		}
+		return rest, nil
+	case reflect.Array:
+		for i := 0; i < rt.Len(); i++ {
+			if len(content) == 0 {
+				return nil, fmt.Errorf("expected %d elements, got %d", rt.Len(), i)
+			}
+			if content, err = rlpDecodeValue(content, rv.Index(i)); err != nil {
+				return nil, rlpWrapError(err, fmt.Sprintf("[%d]", i))
+			}
+		}
+		if len(content) > 0 {
+			return nil, fmt.Errorf("expected %d elements, got more", rt.Len())
+		}
+		return rest, nil
+	case reflect.Struct:
+		if isComplexEnumType(rt) {
+			return nil, fmt.Errorf("complex enum %s is not supported", rt)
+		}
+		if err := rlpDecodeStruct(content, rv); err != nil {
+			return nil, err
+		}
+		return rest, nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", rt)
+}
+
+// rlpDecodeStruct decodes the items of the list content into the fields of rv;
+// the trailing `binary_extension` fields are left to their zero value when
+// the list has no item for them.
+func rlpDecodeStruct(content []byte, rv reflect.Value) (err error) {
+	rt := rv.Type()
+	fields, err := rlpFields(rt)
+	if err != nil {
+		return err
+	}
+	for _, index := range fields {
+		structField := rt.Field(index)
+		if len(content) == 0 {
+			if parseFieldTag(structField.Tag).BinaryExtension {
+				rv.Field(index).Set(reflect.Zero(structField.Type))
+				continue
+			}
+			return rlpWrapError(errors.New("too few elements"), "."+structField.Name)
+		}
+		if content, err = rlpDecodeValue(content, rv.Field(index)); err != nil {
+			return rlpWrapError(err, "."+structField.Name)
+		}
+	}
+	if len(content) > 0 {
+		return errors.New("input list has too many elements")
+	}
+	return nil
+}
+
+// rlpCheckInteger checks that content is the canonical encoding
+// of an integer of up to size bytes.
+func rlpCheckInteger(content []byte, size int) error {
+	if len(content) > size {
+		return fmt.Errorf("integer of %d bytes overflows %d bytes", len(content), size)
+	}
+	if len(content) > 0 && content[0] == 0 {
+		return ErrRLPNonCanonicalInteger
+	}
+	return nil
+}
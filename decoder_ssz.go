@@ -0,0 +1,229 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+// decodeWithOptionSSZ decodes all the remaining bytes, as SSZ values are
+// not self-delimiting.
+func (dec *Decoder) decodeWithOptionSSZ(v interface{}, opt *option) (err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidDecoderError{reflect.TypeOf(v)}
+	}
+	if opt == nil {
+		opt = newDefaultOption()
+	}
+	dec.currentFieldOpt = opt
+	rv = rv.Elem()
+
+	if traceEnabled {
+		zlog.Debug("decode: ssz", zap.Stringer("type", rv.Type()), zap.Int("pos", dec.pos))
+	}
+
+	t, err := sszTypeOf(rv.Type(), sszBounds{})
+	if err != nil {
+		return fmt.Errorf("ssz: %w", err)
+	}
+	if err := sszDecode(dec.data[dec.pos:], rv, t); err != nil {
+		return fmt.Errorf("ssz: %w", err)
+	}
+	dec.pos = len(dec.data)
+	return nil
+}
+
+// sszDecode decodes data, which must be exactly the serialization of a value, into rv.
+func sszDecode(data []byte, rv reflect.Value, t *sszType) error {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	if t.fixed && len(data) != t.fixedLength {
+		return fmt.Errorf("expected %d bytes, got %d", t.fixedLength, len(data))
+	}
+
+	switch t.kind {
+	case sszBasic:
+		return sszDecodeBasic(data, rv, t)
+	case sszBitvector:
+		bits := sszUnpackBits(data, t.length)
+		if t.length%8 != 0 && data[len(data)-1]>>uint(t.length%8) != 0 {
+			return errors.New("bitvector has bits set beyond its length")
+		}
+		rv.Set(reflect.ValueOf(Bitvector(bits)))
+		return nil
+	case sszBitlist:
+		if len(data) == 0 || data[len(data)-1] == 0 {
+			return errors.New("bitlist is missing its length bit")
+		}
+		last := data[len(data)-1]
+		length := (len(data) - 1) * 8
+		for last > 1 {
+			last >>= 1
+			length++
+		}
+		if length > t.length {
+			return fmt.Errorf("bitlist of %d bits exceeds the limit of %d", length, t.length)
+		}
+		rv.Set(reflect.ValueOf(Bitlist(sszUnpackBits(data, length))))
+		return nil
+	case sszContainer:
+		values, types := t.elements(rv)
+		return sszDecodeSequence(data, t, values, types)
+	}
+
+	count := t.length
+	if t.kind == sszList {
+		switch {
+		case len(data) == 0:
+			count = 0
+		case t.elem.fixed:
+			if len(data)%t.elem.fixedLength != 0 {
+				return fmt.Errorf("list of %d bytes is not a multiple of the element size %d", len(data), t.elem.fixedLength)
+			}
+			count = len(data) / t.elem.fixedLength
+		default:
+			if len(data) < sszOffsetSize {
+				return fmt.Errorf("list of %d bytes is too short for an offset", len(data))
+			}
+			first := binary.LittleEndian.Uint32(data)
+			if first%sszOffsetSize != 0 || first == 0 || int(first) > len(data) {
+				return fmt.Errorf("invalid first offset %d", first)
+			}
+			count = int(first / sszOffsetSize)
+		}
+		if count > t.length {
+			return fmt.Errorf("list of %d elements exceeds the limit of %d", count, t.length)
+		}
+	}
+
+	if rv.Kind() == reflect.String {
+		rv.SetString(string(data))
+		return nil
+	}
+	if rv.Kind() == reflect.Slice {
+		if count == 0 {
+			// Empty slices are left nil
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		rv.Set(reflect.MakeSlice(rv.Type(), count, count))
+	}
+	if t.elem.rt == sszByteType {
+		reflect.Copy(rv, reflect.ValueOf(data))
+		return nil
+	}
+	values, types := t.elements(rv)
+	return sszDecodeSequence(data, t, values, types)
+}
+
+// sszDecodeSequence decodes the fields of a container or the elements of a vector
+// or a list, whose variable-size parts are referenced by offsets.
+func sszDecodeSequence(data []byte, t *sszType, values []reflect.Value, types []*sszType) error {
+	fixedLength := 0
+	for _, elemType := range types {
+		fixedLength += elemType.headLength()
+	}
+	if len(data) < fixedLength {
+		return fmt.Errorf("expected at least %d bytes, got %d", fixedLength, len(data))
+	}
+
+	// The offsets of the variable-size values, followed by the end of the data:
+	var offsets []int
+	var variable []int
+	pos := 0
+	for i, elemType := range types {
+		if elemType.fixed {
+			if err := sszDecode(data[pos:pos+elemType.fixedLength], values[i], elemType); err != nil {
+				return sszWrapError(t, i, err)
+			}
+			pos += elemType.fixedLength
+			continue
+		}
+		offset := int(binary.LittleEndian.Uint32(data[pos:]))
+		switch {
+		case len(offsets) == 0 && offset != fixedLength:
+			return sszWrapError(t, i, fmt.Errorf("offset %d, expected %d", offset, fixedLength))
+		case len(offsets) > 0 && offset < offsets[len(offsets)-1]:
+			return sszWrapError(t, i, fmt.Errorf("offset %d is lower than the previous one", offset))
+		case offset > len(data):
+			return sszWrapError(t, i, fmt.Errorf("offset %d is out of bounds", offset))
+		}
+		offsets = append(offsets, offset)
+		variable = append(variable, i)
+		pos += sszOffsetSize
+	}
+	if len(offsets) == 0 && len(data) != fixedLength {
+		return fmt.Errorf("expected %d bytes, got %d", fixedLength, len(data))
+	}
+	offsets = append(offsets, len(data))
+
+	for j, i := range variable {
+		if err := sszDecode(data[offsets[j]:offsets[j+1]], values[i], types[i]); err != nil {
+			return sszWrapError(t, i, err)
+		}
+	}
+	return nil
+}
+
+func sszDecodeBasic(data []byte, rv reflect.Value, t *sszType) error {
+	switch t.rt {
+	case uint128Type:
+		rv.Set(reflect.ValueOf(Uint128{
+			Lo: binary.LittleEndian.Uint64(data),
+			Hi: binary.LittleEndian.Uint64(data[8:]),
+		}))
+		return nil
+	case uint256Type:
+		var value Uint256
+		copy(value[:], data)
+		ReverseBytes(value[:])
+		rv.Set(reflect.ValueOf(value))
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		if data[0] > 1 {
+			return fmt.Errorf("invalid bool value %d", data[0])
+		}
+		rv.SetBool(data[0] == 1)
+	case reflect.Uint8:
+		rv.SetUint(uint64(data[0]))
+	case reflect.Uint16:
+		rv.SetUint(uint64(binary.LittleEndian.Uint16(data)))
+	case reflect.Uint32:
+		rv.SetUint(uint64(binary.LittleEndian.Uint32(data)))
+	case reflect.Uint64:
+		rv.SetUint(binary.LittleEndian.Uint64(data))
+	}
+	return nil
+}
+
+func sszUnpackBits(data []byte, count int) []bool {
+	bits := make([]bool, count)
+	for i := range bits {
+		bits[i] = data[i/8]&(1<<uint(i%8)) != 0
+	}
+	return bits
+}
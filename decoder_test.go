@@ -756,3 +756,86 @@ func TestDecoder_SkipBytes(t *testing.T) {
 	require.Equal(t, 0, decoder.Remaining())
 
 }
+
+type paddedExtensionAccount struct {
+	Key    uint8
+	Amount uint64  `bin:"binary_extension padded"`
+	Owner  *uint32 `bin:"optional binary_extension padded"`
+}
+
+type strictExtensionAccount struct {
+	Key    uint8
+	Amount uint64 `bin:"binary_extension"`
+}
+
+func TestDecoder_PaddedBinaryExtension(t *testing.T) {
+	// The account is zero-padded after its first field:
+	buf := []byte{0x07, 0x00, 0x00, 0x00}
+
+	var strict strictExtensionAccount
+	require.Error(t, NewBorshDecoder(buf).Decode(&strict))
+
+	var padded paddedExtensionAccount
+	require.NoError(t, NewBorshDecoder(buf).Decode(&padded))
+	assert.Equal(t, paddedExtensionAccount{Key: 7}, padded)
+
+	// Present extension fields are decoded, followed by padding:
+	buf = []byte{
+		0x07,
+		0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x02, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00,
+	}
+	padded = paddedExtensionAccount{}
+	require.NoError(t, NewBorshDecoder(buf).Decode(&padded))
+	owner := uint32(2)
+	assert.Equal(t, paddedExtensionAccount{Key: 7, Amount: 5, Owner: &owner}, padded)
+}
+
+func TestDecoder_DecodeSized(t *testing.T) {
+	buf := []byte{
+		// First struct, without its extension field:
+		0x01,
+		// Second struct, with its extension field:
+		0x02,
+		0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	// Without a boundary, the first struct reads into the second one:
+	var first strictExtensionAccount
+	require.NoError(t, NewBorshDecoder(buf).Decode(&first))
+	assert.Equal(t, strictExtensionAccount{Key: 1, Amount: 0x0902}, first)
+
+	decoder := NewBorshDecoder(buf)
+	first = strictExtensionAccount{}
+	require.NoError(t, decoder.DecodeSized(&first, 1))
+	assert.Equal(t, strictExtensionAccount{Key: 1}, first)
+	assert.Equal(t, uint(1), decoder.Position())
+
+	var second strictExtensionAccount
+	require.NoError(t, decoder.DecodeSized(&second, 9))
+	assert.Equal(t, strictExtensionAccount{Key: 2, Amount: 9}, second)
+	assert.False(t, decoder.HasRemaining())
+
+	// Unread padding is skipped:
+	decoder = NewBorshDecoder([]byte{0x03, 0x00, 0x00, 0x04})
+	var padded paddedExtensionAccount
+	require.NoError(t, decoder.DecodeSized(&padded, 3))
+	assert.Equal(t, paddedExtensionAccount{Key: 3}, padded)
+	assert.Equal(t, 1, decoder.Remaining())
+
+	// Fields which are not extensions cannot cross the boundary:
+	var entry struct {
+		A uint8
+		B uint16
+	}
+	require.EqualError(t,
+		NewBorshDecoder([]byte{0x01, 0x02, 0x03}).DecodeSized(&entry, 2),
+		"decode *struct { A uint8; B uint16 } of 2 bytes: error while decoding \"B\" field: uint16 required [2] bytes, remaining [1]",
+	)
+	require.EqualError(t,
+		NewBorshDecoder([]byte{0x01, 0x02, 0x03, 0x04}).DecodeSized(&first, 3),
+		"decode *bin.strictExtensionAccount of 3 bytes: error while decoding \"Amount\" field: decode: uint64 required [8] bytes, remaining [2]",
+	)
+	require.EqualError(t, NewBorshDecoder([]byte{0x01}).DecodeSized(&entry, 2), "decode: struct size 2 out of bounds, remaining bytes 1")
+}
@@ -34,7 +34,8 @@ type Encoder struct {
 
 	currentFieldOpt *option
 
-	encoding Encoding
+	encoding   Encoding
+	sizeOfMode SizeOfMode
 }
 
 func (enc *Encoder) IsBorsh() bool {
@@ -49,6 +50,46 @@ func (enc *Encoder) IsCompactU16() bool {
 	return enc.encoding.IsCompactU16()
 }
 
+func (enc *Encoder) IsBCS() bool {
+	return enc.encoding.IsBCS()
+}
+
+func (enc *Encoder) IsSCALE() bool {
+	return enc.encoding.IsSCALE()
+}
+
+func (enc *Encoder) IsBincode() bool {
+	return enc.encoding.IsBincode()
+}
+
+func (enc *Encoder) IsEVMABI() bool {
+	return enc.encoding.IsEVMABI()
+}
+
+func (enc *Encoder) IsRLP() bool {
+	return enc.encoding.IsRLP()
+}
+
+func (enc *Encoder) IsSSZ() bool {
+	return enc.encoding.IsSSZ()
+}
+
+func (enc *Encoder) IsXDR() bool {
+	return enc.encoding.IsXDR()
+}
+
+func (enc *Encoder) IsBitcoin() bool {
+	return enc.encoding.IsBitcoin()
+}
+
+func (enc *Encoder) IsPostcard() bool {
+	return enc.encoding.IsPostcard()
+}
+
+func (enc *Encoder) IsReprC() bool {
+	return enc.encoding.IsReprC()
+}
+
 func NewEncoderWithEncoding(writer io.Writer, enc Encoding) *Encoder {
 	if !isValidEncoding(enc) {
 		panic(fmt.Sprintf("provided encoding is not valid: %s", enc))
@@ -72,6 +113,70 @@ func NewCompactU16Encoder(writer io.Writer) *Encoder {
 	return NewEncoderWithEncoding(writer, EncodingCompactU16)
 }
 
+func NewBCSEncoder(writer io.Writer) *Encoder {
+	return NewEncoderWithEncoding(writer, EncodingBCS)
+}
+
+func NewSCALEEncoder(writer io.Writer) *Encoder {
+	return NewEncoderWithEncoding(writer, EncodingSCALE)
+}
+
+// NewBincodeEncoder returns an encoder using the default configuration
+// of bincode (1.x): fixed-size integers, u64 lengths and u32 enum tags.
+func NewBincodeEncoder(writer io.Writer) *Encoder {
+	return NewEncoderWithEncoding(writer, EncodingBincode)
+}
+
+// NewBincodeVarintEncoder returns an encoder using the varint configuration
+// of bincode, where integers, lengths and enum tags are varint-encoded.
+func NewBincodeVarintEncoder(writer io.Writer) *Encoder {
+	return NewEncoderWithEncoding(writer, EncodingBincodeVarint)
+}
+
+// NewEVMABIEncoder returns an encoder of the solidity contract ABI,
+// where each call to Encode writes the `abi.encode` of the value.
+func NewEVMABIEncoder(writer io.Writer) *Encoder {
+	return NewEncoderWithEncoding(writer, EncodingEVMABI)
+}
+
+// NewRLPEncoder returns an encoder of the Ethereum recursive length prefix
+// serialization, where each call to Encode writes one item.
+func NewRLPEncoder(writer io.Writer) *Encoder {
+	return NewEncoderWithEncoding(writer, EncodingRLP)
+}
+
+// NewSSZEncoder returns an encoder of the Simple Serialize format of
+// the Ethereum consensus layer, whose types are defined by the
+// `ssz_size` and `ssz_max` tags of the struct fields.
+func NewSSZEncoder(writer io.Writer) *Encoder {
+	return NewEncoderWithEncoding(writer, EncodingSSZ)
+}
+
+// NewXDREncoder returns an encoder of the External Data Representation
+// (RFC 4506) used by Stellar: big-endian items aligned on 4 bytes.
+func NewXDREncoder(writer io.Writer) *Encoder {
+	return NewEncoderWithEncoding(writer, EncodingXDR)
+}
+
+// NewBitcoinEncoder returns an encoder of the Bitcoin consensus serialization:
+// little-endian integers, and CompactSize lengths.
+func NewBitcoinEncoder(writer io.Writer) *Encoder {
+	return NewEncoderWithEncoding(writer, EncodingBitcoin)
+}
+
+// NewPostcardEncoder returns an encoder of the postcard format of Rust: varint
+// integers (zigzag for the signed ones), varint lengths and enum discriminants.
+func NewPostcardEncoder(writer io.Writer) *Encoder {
+	return NewEncoderWithEncoding(writer, EncodingPostcard)
+}
+
+// NewReprCEncoder returns an encoder of the `#[repr(C)]` memory layout
+// (e.g. of the anchor zero_copy accounts): the fields are naturally aligned,
+// with zeroed padding, and there are no length prefixes.
+func NewReprCEncoder(writer io.Writer) *Encoder {
+	return NewEncoderWithEncoding(writer, EncodingReprC)
+}
+
 func (e *Encoder) Encode(v interface{}) (err error) {
 	switch e.encoding {
 	case EncodingBin:
@@ -80,7 +185,18 @@ func (e *Encoder) Encode(v interface{}) (err error) {
 		return e.encodeBorsh(reflect.ValueOf(v), nil)
 	case EncodingCompactU16:
 		return e.encodeCompactU16(reflect.ValueOf(v), nil)
+	case EncodingEVMABI:
+		return e.encodeEVMABI(reflect.ValueOf(v), nil)
+	case EncodingRLP:
+		return e.encodeRLP(reflect.ValueOf(v), nil)
+	case EncodingSSZ:
+		return e.encodeSSZ(reflect.ValueOf(v), nil)
+	case EncodingReprC:
+		return e.encodeReprC(reflect.ValueOf(v), nil)
 	default:
+		if def, ok := encodingDefinition(e.encoding); ok {
+			return e.encodeRegistered(def, reflect.ValueOf(v), nil)
+		}
 		panic(fmt.Errorf("encoding not implemented: %s", e.encoding))
 	}
 }
@@ -135,8 +251,53 @@ func (e *Encoder) WriteLength(length int) error {
 		if err := e.WriteBytes(buf, false); err != nil {
 			return err
 		}
+	case EncodingBCS:
+		if length > BCSMaxSequenceLength {
+			return fmt.Errorf("bcs: sequence length %d exceeds the maximum %d", length, BCSMaxSequenceLength)
+		}
+		if err := e.WriteULEB128(uint32(length)); err != nil {
+			return err
+		}
+	case EncodingSCALE:
+		if err := e.WriteCompact(NewCompact(uint64(length))); err != nil {
+			return err
+		}
+	case EncodingBincode:
+		if err := e.WriteUint64(uint64(length), LE); err != nil {
+			return err
+		}
+	case EncodingBincodeVarint:
+		if err := e.WriteBincodeVarint(uint64(length)); err != nil {
+			return err
+		}
+	case EncodingEVMABI:
+		if err := e.toWriter(evmABIUintWord(uint64(length))); err != nil {
+			return err
+		}
+	case EncodingRLP:
+		return errors.New("rlp: lengths are part of the item headers")
+	case EncodingSSZ:
+		return errors.New("ssz: lengths are defined by the offsets")
+	case EncodingXDR:
+		if err := e.WriteUint32(uint32(length), BE); err != nil {
+			return err
+		}
+	case EncodingBitcoin:
+		if err := e.WriteCompactSize(uint64(length)); err != nil {
+			return err
+		}
+	case EncodingPostcard:
+		if err := e.WritePostcardVarint(Uint128{Lo: uint64(length)}); err != nil {
+			return err
+		}
+	case EncodingReprC:
+		return errors.New("reprc: layouts have no length prefixes")
 	default:
-		panic(fmt.Errorf("encoding not implemented: %s", e.encoding))
+		def, ok := registeredEncoding(e.encoding)
+		if !ok {
+			panic(fmt.Errorf("encoding not implemented: %s", e.encoding))
+		}
+		return def.WriteLength(e, length)
 	}
 	return nil
 }
@@ -57,6 +57,10 @@ func (e *Encoder) encodeBin(rv reflect.Value, opt *option) (err error) {
 		return nil
 	}
 
+	if opt.hasFixedString() {
+		return e.writeFixedString(rv, opt)
+	}
+
 	if marshaler, ok := rv.Interface().(BinaryMarshaler); ok {
 		if traceEnabled {
 			zlog.Debug("encode: using MarshalerBinary method to encode type")
@@ -66,6 +70,9 @@ func (e *Encoder) encodeBin(rv reflect.Value, opt *option) (err error) {
 
 	switch rv.Kind() {
 	case reflect.String:
+		if opt.hasLenPrefix() {
+			return e.writeStringWithLengthPrefix(opt, []byte(rv.String()))
+		}
 		return e.WriteRustString(rv.String())
 	case reflect.Uint8:
 		return e.WriteByte(byte(rv.Uint()))
@@ -132,7 +139,7 @@ func (e *Encoder) encodeBin(rv reflect.Value, opt *option) (err error) {
 			}
 		} else {
 			l = rv.Len()
-			if err = e.WriteUVarInt(l); err != nil {
+			if err = e.writeLengthPrefix(opt, l); err != nil {
 				return
 			}
 		}
@@ -167,7 +174,7 @@ func (e *Encoder) encodeBin(rv reflect.Value, opt *option) (err error) {
 			zlog = zlog.Named("struct")
 		}
 
-		if err = e.WriteUVarInt(keyCount); err != nil {
+		if err = e.writeLengthPrefix(opt, keyCount); err != nil {
 			return
 		}
 
@@ -194,10 +201,13 @@ func (e *Encoder) encodeStructBin(rt reflect.Type, rv reflect.Value) (err error)
 		zlog.Debug("encode: struct", zap.Int("fields", l), zap.Stringer("type", rv.Kind()))
 	}
 
-	sizeOfMap := map[string]int{}
+	fields := newStructFields(rv)
 	for i := 0; i < l; i++ {
 		structField := rt.Field(i)
-		fieldTag := parseFieldTag(structField.Tag)
+		fieldTag, err := parseStructFieldTag(structField)
+		if err != nil {
+			return err
+		}
 
 		if fieldTag.Skip {
 			if traceEnabled {
@@ -208,16 +218,12 @@ func (e *Encoder) encodeStructBin(rt reflect.Type, rv reflect.Value) (err error)
 			continue
 		}
 
-		rv := rv.Field(i)
-
-		if fieldTag.SizeOf != "" {
-			if traceEnabled {
-				zlog.Debug("encode: struct field has sizeof tag",
-					zap.String("sizeof_field_name", fieldTag.SizeOf),
-					zap.String("struct_field_name", structField.Name),
-				)
-			}
-			sizeOfMap[fieldTag.SizeOf] = sizeof(structField.Type, rv)
+		rv, err := e.encodeFieldTags(fields, i, fieldTag, fieldTag.Order, e.encodeBin)
+		if err != nil {
+			return err
+		}
+		if !rv.IsValid() {
+			continue
 		}
 
 		if !rv.CanInterface() {
@@ -230,17 +236,7 @@ func (e *Encoder) encodeStructBin(rt reflect.Type, rv reflect.Value) (err error)
 			continue
 		}
 
-		option := &option{
-			OptionalField: fieldTag.Optional,
-			Order:         fieldTag.Order,
-		}
-
-		if s, ok := sizeOfMap[structField.Name]; ok {
-			if traceEnabled {
-				zlog.Debug("setting sizeof option", zap.String("of", structField.Name), zap.Int("size", s))
-			}
-			option.setSizeOfSlice(s)
-		}
+		option := fields.option(i, fieldTag, fieldTag.Order)
 
 		if traceEnabled {
 			zlog.Debug("encode: struct field",
@@ -60,6 +60,10 @@ func (e *Encoder) encodeBorsh(rv reflect.Value, opt *option) (err error) {
 		return nil
 	}
 
+	if opt.hasFixedString() {
+		return e.writeFixedString(rv, opt)
+	}
+
 	if marshaler, ok := rv.Interface().(BinaryMarshaler); ok {
 		if rv.Kind() == reflect.Ptr && rv.IsZero() {
 			return nil
@@ -76,7 +80,7 @@ func (e *Encoder) encodeBorsh(rv reflect.Value, opt *option) (err error) {
 	// case reflect.Uint:
 	// 	return e.WriteUint64(rv.Uint(), LE)
 	case reflect.String:
-		return e.WriteString(rv.String())
+		return e.writeStringWithLengthPrefix(opt, []byte(rv.String()))
 	case reflect.Uint8:
 		return e.WriteByte(byte(rv.Uint()))
 	case reflect.Int8:
@@ -149,7 +153,7 @@ func (e *Encoder) encodeBorsh(rv reflect.Value, opt *option) (err error) {
 			}
 		} else {
 			l = rv.Len()
-			if err = e.WriteUint32(uint32(l), LE); err != nil {
+			if err = e.writeLengthPrefix(opt, l); err != nil {
 				return
 			}
 		}
@@ -186,7 +190,7 @@ func (e *Encoder) encodeBorsh(rv reflect.Value, opt *option) (err error) {
 			zlog = zlog.Named("struct")
 		}
 
-		if err = e.WriteUint32(uint32(keyCount), LE); err != nil {
+		if err = e.writeLengthPrefix(opt, keyCount); err != nil {
 			return
 		}
 
@@ -253,10 +257,13 @@ func (e *Encoder) encodeStructBorsh(rt reflect.Type, rv reflect.Value) (err erro
 		}
 	}
 
-	sizeOfMap := map[string]int{}
+	fields := newStructFields(rv)
 	for i := 0; i < l; i++ {
 		structField := rt.Field(i)
-		fieldTag := parseFieldTag(structField.Tag)
+		fieldTag, err := parseStructFieldTag(structField)
+		if err != nil {
+			return err
+		}
 
 		if fieldTag.Skip {
 			if traceEnabled {
@@ -267,16 +274,12 @@ func (e *Encoder) encodeStructBorsh(rt reflect.Type, rv reflect.Value) (err erro
 			continue
 		}
 
-		rv := rv.Field(i)
-
-		if fieldTag.SizeOf != "" {
-			if traceEnabled {
-				zlog.Debug("encode: struct field has sizeof tag",
-					zap.String("sizeof_field_name", fieldTag.SizeOf),
-					zap.String("struct_field_name", structField.Name),
-				)
-			}
-			sizeOfMap[fieldTag.SizeOf] = sizeof(structField.Type, rv)
+		rv, err := e.encodeFieldTags(fields, i, fieldTag, fieldTag.Order, e.encodeBorsh)
+		if err != nil {
+			return err
+		}
+		if !rv.IsValid() {
+			continue
 		}
 
 		if !rv.CanInterface() {
@@ -289,17 +292,7 @@ func (e *Encoder) encodeStructBorsh(rt reflect.Type, rv reflect.Value) (err erro
 			continue
 		}
 
-		option := &option{
-			OptionalField: fieldTag.Optional,
-			Order:         fieldTag.Order,
-		}
-
-		if s, ok := sizeOfMap[structField.Name]; ok {
-			if traceEnabled {
-				zlog.Debug("setting sizeof option", zap.String("of", structField.Name), zap.Int("size", s))
-			}
-			option.setSizeOfSlice(s)
-		}
+		option := fields.option(i, fieldTag, fieldTag.Order)
 
 		if traceEnabled {
 			zlog.Debug("encode: struct field",
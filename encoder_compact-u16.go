@@ -56,6 +56,10 @@ func (e *Encoder) encodeCompactU16(rv reflect.Value, opt *option) (err error) {
 		return nil
 	}
 
+	if opt.hasFixedString() {
+		return e.writeFixedString(rv, opt)
+	}
+
 	if marshaler, ok := rv.Interface().(BinaryMarshaler); ok {
 		if traceEnabled {
 			zlog.Debug("encode: using MarshalerBinary method to encode type")
@@ -65,7 +69,7 @@ func (e *Encoder) encodeCompactU16(rv reflect.Value, opt *option) (err error) {
 
 	switch rv.Kind() {
 	case reflect.String:
-		return e.WriteString(rv.String())
+		return e.writeStringWithLengthPrefix(opt, []byte(rv.String()))
 	case reflect.Uint8:
 		return e.WriteByte(byte(rv.Uint()))
 	case reflect.Int8:
@@ -131,7 +135,7 @@ func (e *Encoder) encodeCompactU16(rv reflect.Value, opt *option) (err error) {
 			}
 		} else {
 			l = rv.Len()
-			if err = e.WriteCompactU16Length(l); err != nil {
+			if err = e.writeLengthPrefix(opt, l); err != nil {
 				return
 			}
 		}
@@ -166,7 +170,7 @@ func (e *Encoder) encodeCompactU16(rv reflect.Value, opt *option) (err error) {
 			zlog = zlog.Named("struct")
 		}
 
-		if err = e.WriteCompactU16Length(keyCount); err != nil {
+		if err = e.writeLengthPrefix(opt, keyCount); err != nil {
 			return
 		}
 
@@ -193,10 +197,13 @@ func (e *Encoder) encodeStructCompactU16(rt reflect.Type, rv reflect.Value) (err
 		zlog.Debug("encode: struct", zap.Int("fields", l), zap.Stringer("type", rv.Kind()))
 	}
 
-	sizeOfMap := map[string]int{}
+	fields := newStructFields(rv)
 	for i := 0; i < l; i++ {
 		structField := rt.Field(i)
-		fieldTag := parseFieldTag(structField.Tag)
+		fieldTag, err := parseStructFieldTag(structField)
+		if err != nil {
+			return err
+		}
 
 		if fieldTag.Skip {
 			if traceEnabled {
@@ -207,16 +214,12 @@ func (e *Encoder) encodeStructCompactU16(rt reflect.Type, rv reflect.Value) (err
 			continue
 		}
 
-		rv := rv.Field(i)
-
-		if fieldTag.SizeOf != "" {
-			if traceEnabled {
-				zlog.Debug("encode: struct field has sizeof tag",
-					zap.String("sizeof_field_name", fieldTag.SizeOf),
-					zap.String("struct_field_name", structField.Name),
-				)
-			}
-			sizeOfMap[fieldTag.SizeOf] = sizeof(structField.Type, rv)
+		rv, err := e.encodeFieldTags(fields, i, fieldTag, fieldTag.Order, e.encodeCompactU16)
+		if err != nil {
+			return err
+		}
+		if !rv.IsValid() {
+			continue
 		}
 
 		if !rv.CanInterface() {
@@ -229,17 +232,7 @@ func (e *Encoder) encodeStructCompactU16(rt reflect.Type, rv reflect.Value) (err
 			continue
 		}
 
-		option := &option{
-			OptionalField: fieldTag.Optional,
-			Order:         fieldTag.Order,
-		}
-
-		if s, ok := sizeOfMap[structField.Name]; ok {
-			if traceEnabled {
-				zlog.Debug("setting sizeof option", zap.String("of", structField.Name), zap.Int("size", s))
-			}
-			option.setSizeOfSlice(s)
-		}
+		option := fields.option(i, fieldTag, fieldTag.Order)
 
 		if traceEnabled {
 			zlog.Debug("encode: struct field",
@@ -0,0 +1,220 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+// encodeEVMABI writes v like solidity's `abi.encode`: a struct is encoded as
+// the tuple of its fields (e.g. the arguments of a function call), any other
+// value as a tuple of one element.
+func (e *Encoder) encodeEVMABI(rv reflect.Value, opt *option) (err error) {
+	if opt == nil {
+		opt = newDefaultOption()
+	}
+	e.currentFieldOpt = opt
+
+	if isZero(rv) {
+		return nil
+	}
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		// Custom marshalers (e.g. of variants) can write a selector before the arguments:
+		if marshaler, ok := rv.Interface().(BinaryMarshaler); ok && !isEVMABIBuiltinType(rv.Type()) {
+			return marshaler.MarshalWithEncoder(e)
+		}
+		rv = rv.Elem()
+	}
+	if marshaler, ok := rv.Interface().(BinaryMarshaler); ok && !isEVMABIBuiltinType(rv.Type()) {
+		return marshaler.MarshalWithEncoder(e)
+	}
+
+	if traceEnabled {
+		zlog.Debug("encode: abi", zap.Stringer("type", rv.Type()))
+	}
+
+	var data []byte
+	if rv.Kind() == reflect.Struct && !isEVMABIBuiltinType(rv.Type()) {
+		data, err = evmABIEncodeValue(rv)
+	} else {
+		data, err = evmABIEncodeTuple([]reflect.Value{rv})
+	}
+	if err != nil {
+		return err
+	}
+	return e.toWriter(data)
+}
+
+func isEVMABIBuiltinType(rt reflect.Type) bool {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	switch rt {
+	case uint128Type, int128Type, uint256Type, int256Type, evmAddressType:
+		return true
+	}
+	return false
+}
+
+// evmABIEncodeTuple encodes the values with the head/tail layout:
+// static values are encoded in place, dynamic values are encoded after
+// all the static parts, and referenced by their offset from the tuple start.
+func evmABIEncodeTuple(values []reflect.Value) ([]byte, error) {
+	headSize := 0
+	for _, v := range values {
+		headSize += evmABIHeadSize(v.Type())
+	}
+
+	head := make([]byte, 0, headSize)
+	var tail []byte
+	for _, v := range values {
+		enc, err := evmABIEncodeValue(v)
+		if err != nil {
+			return nil, err
+		}
+		if evmABIIsDynamic(v.Type()) {
+			head = append(head, evmABIUintWord(uint64(headSize+len(tail)))...)
+			tail = append(tail, enc...)
+		} else {
+			head = append(head, enc...)
+		}
+	}
+	return append(head, tail...), nil
+}
+
+func evmABIEncodeValue(rv reflect.Value) ([]byte, error) {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv = reflect.Zero(rv.Type().Elem())
+		} else {
+			rv = rv.Elem()
+		}
+	}
+
+	switch v := rv.Interface().(type) {
+	case Uint128:
+		word := make([]byte, 32)
+		binary.BigEndian.PutUint64(word[16:], v.Hi)
+		binary.BigEndian.PutUint64(word[24:], v.Lo)
+		return word, nil
+	case Int128:
+		word := make([]byte, 32)
+		if int64(v.Hi) < 0 {
+			fill(word[:16], 0xff)
+		}
+		binary.BigEndian.PutUint64(word[16:], v.Hi)
+		binary.BigEndian.PutUint64(word[24:], v.Lo)
+		return word, nil
+	case Uint256:
+		return v[:], nil
+	case Int256:
+		return v[:], nil
+	case EVMAddress:
+		word := make([]byte, 32)
+		copy(word[12:], v[:])
+		return word, nil
+	}
+
+	rt := rv.Type()
+	switch rt.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return evmABIUintWord(1), nil
+		}
+		return evmABIUintWord(0), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return evmABIUintWord(rv.Uint()), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		word := evmABIUintWord(uint64(rv.Int()))
+		if rv.Int() < 0 {
+			fill(word[:24], 0xff)
+		}
+		return word, nil
+	case reflect.String:
+		return evmABIEncodeBytes([]byte(rv.String())), nil
+	case reflect.Slice:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			return evmABIEncodeBytes(rv.Bytes()), nil
+		}
+		elems, err := evmABIEncodeTuple(evmABIElements(rv))
+		if err != nil {
+			return nil, err
+		}
+		return append(evmABIUintWord(uint64(rv.Len())), elems...), nil
+	case reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 && rt.Len() >= 1 && rt.Len() <= 32 {
+			// bytesN values are right-padded:
+			word := make([]byte, 32)
+			reflect.Copy(reflect.ValueOf(word), rv)
+			return word, nil
+		}
+		return evmABIEncodeTuple(evmABIElements(rv))
+	case reflect.Struct:
+		if isComplexEnumType(rt) {
+			return nil, fmt.Errorf("abi: complex enum %s is not supported", rt)
+		}
+		fields, err := evmABIFields(rt)
+		if err != nil {
+			return nil, fmt.Errorf("abi: type %s: %w", rt, err)
+		}
+		values := make([]reflect.Value, len(fields))
+		for i, index := range fields {
+			values[i] = rv.Field(index)
+		}
+		return evmABIEncodeTuple(values)
+	default:
+		return nil, fmt.Errorf("abi: unsupported type %q", rt)
+	}
+}
+
+func evmABIElements(rv reflect.Value) []reflect.Value {
+	out := make([]reflect.Value, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i)
+	}
+	return out
+}
+
+// evmABIEncodeBytes encodes the length of data,
+// followed by data right-padded to a multiple of 32 bytes.
+func evmABIEncodeBytes(data []byte) []byte {
+	out := make([]byte, 32+evmABIPaddedLength(len(data)))
+	binary.BigEndian.PutUint64(out[24:32], uint64(len(data)))
+	copy(out[32:], data)
+	return out
+}
+
+func evmABIPaddedLength(n int) int {
+	return (n + 31) / 32 * 32
+}
+
+func evmABIUintWord(v uint64) []byte {
+	word := make([]byte, 32)
+	binary.BigEndian.PutUint64(word[24:], v)
+	return word
+}
+
+func fill(buf []byte, b byte) {
+	for i := range buf {
+		buf[i] = b
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+// encodeRegistered encodes rv with the rules of an encoding definition:
+// a built-in one (see builtinEncodings), or one registered with RegisterEncoding.
+func (e *Encoder) encodeRegistered(def EncodingDefinition, rv reflect.Value, opt *option) (err error) {
+	if opt == nil {
+		opt = &option{Order: def.ByteOrder()}
+	}
+	e.currentFieldOpt = opt
+
+	if traceEnabled {
+		zlog.Debug("encode: type",
+			zap.String("encoding", def.Name()),
+			zap.Stringer("value_kind", rv.Kind()),
+			zap.Reflect("options", opt),
+		)
+	}
+
+	rules := rulesOf(def)
+	if opt.isOptional() {
+		if done, err := rules.encodeOptional(e, rv); done {
+			return err
+		}
+		if rv.IsZero() {
+			if traceEnabled {
+				zlog.Debug("encode: skipping optional value with", zap.Stringer("type", rv.Kind()))
+			}
+			return def.WriteOptionTag(e, false)
+		}
+		if err := def.WriteOptionTag(e, true); err != nil {
+			return err
+		}
+	}
+	// Reset optionality so it won't propagate to child types:
+	opt = opt.clone().setIsOptional(false)
+
+	if isZero(rv) {
+		return nil
+	}
+
+	if opt.hasFixedString() {
+		return e.writeFixedString(rv, opt)
+	}
+
+	if done, err := rules.encodeValue(e, rv, opt); done {
+		return err
+	}
+
+	if marshaler, ok := rv.Interface().(BinaryMarshaler); ok {
+		if rv.Kind() == reflect.Ptr && rv.IsZero() {
+			return nil
+		}
+		if traceEnabled {
+			zlog.Debug("encode: using MarshalerBinary method to encode type")
+		}
+		return marshaler.MarshalWithEncoder(e)
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		if opt.hasLenPrefix() {
+			return e.writeStringWithLengthPrefix(opt, []byte(rv.String()))
+		}
+		return def.WriteString(e, rv.String())
+	case reflect.Bool:
+		return def.WriteBool(e, rv.Bool())
+	case reflect.Uint8:
+		return e.WriteByte(byte(rv.Uint()))
+	case reflect.Int8:
+		return e.WriteByte(byte(rv.Int()))
+	case reflect.Int16:
+		return e.WriteInt16(int16(rv.Int()), opt.Order)
+	case reflect.Uint16:
+		return e.WriteUint16(uint16(rv.Uint()), opt.Order)
+	case reflect.Int32:
+		return e.WriteInt32(int32(rv.Int()), opt.Order)
+	case reflect.Uint32:
+		return e.WriteUint32(uint32(rv.Uint()), opt.Order)
+	case reflect.Int64, reflect.Int:
+		return e.WriteInt64(rv.Int(), opt.Order)
+	case reflect.Uint64, reflect.Uint:
+		return e.WriteUint64(rv.Uint(), opt.Order)
+	case reflect.Float32:
+		return e.WriteFloat32(float32(rv.Float()), opt.Order)
+	case reflect.Float64:
+		return e.WriteFloat64(rv.Float(), opt.Order)
+	case reflect.Ptr:
+		if rv.IsNil() {
+			el := reflect.New(rv.Type().Elem()).Elem()
+			return e.encodeRegistered(def, el, opt)
+		}
+		return e.encodeRegistered(def, rv.Elem(), opt)
+	case reflect.Interface:
+		// skip
+		return nil
+	}
+
+	rt := rv.Type()
+	switch rt.Kind() {
+	case reflect.Array:
+		l := rt.Len()
+		if traceEnabled {
+			defer func(prev *zap.Logger) { zlog = prev }(zlog)
+			zlog = zlog.Named("array")
+			zlog.Debug("encode: array", zap.Int("length", l), zap.Stringer("type", rv.Kind()))
+		}
+
+		if rt.Elem().Kind() == reflect.Uint8 {
+			// if it's a [n]byte, accumulate and write in one command:
+			arr := make([]byte, l)
+			reflect.Copy(reflect.ValueOf(arr), rv)
+			return e.WriteBytes(arr, false)
+		}
+		for i := 0; i < l; i++ {
+			if err = e.encodeRegistered(def, rv.Index(i), nil); err != nil {
+				return
+			}
+		}
+	case reflect.Slice:
+		var l int
+		if opt.hasSizeOfSlice() {
+			l = opt.getSizeOfSlice()
+			if traceEnabled {
+				zlog.Debug("encode: slice with sizeof set", zap.Int("size_of", l))
+			}
+		} else {
+			l = rv.Len()
+			if err = e.writeRegisteredLength(def, opt, l); err != nil {
+				return
+			}
+		}
+		if traceEnabled {
+			defer func(prev *zap.Logger) { zlog = prev }(zlog)
+			zlog = zlog.Named("slice")
+			zlog.Debug("encode: slice", zap.Int("length", l), zap.Stringer("type", rv.Kind()))
+		}
+
+		for i := 0; i < l; i++ {
+			if err = e.encodeRegistered(def, rv.Index(i), nil); err != nil {
+				return
+			}
+		}
+	case reflect.Struct:
+		if err = e.encodeStructRegistered(def, rt, rv); err != nil {
+			return
+		}
+	case reflect.Map:
+		keys := rv.MapKeys()
+		def.SortMapKeys(keys)
+
+		keyCount := rv.Len()
+		if traceEnabled {
+			zlog.Debug("encode: map",
+				zap.Int("key_count", keyCount),
+				zap.String("key_type", rt.String()),
+				typeField("value_type", rv),
+			)
+			defer func(prev *zap.Logger) { zlog = prev }(zlog)
+			zlog = zlog.Named("struct")
+		}
+
+		if err = e.writeRegisteredLength(def, opt, keyCount); err != nil {
+			return
+		}
+
+		for _, mapKey := range keys {
+			if err = e.encodeRegistered(def, mapKey, nil); err != nil {
+				return
+			}
+			if err = e.encodeRegistered(def, rv.MapIndex(mapKey), nil); err != nil {
+				return
+			}
+		}
+	default:
+		return fmt.Errorf("encode: unsupported type %q", rt)
+	}
+	return
+}
+
+func (e *Encoder) encodeComplexEnumRegistered(def EncodingDefinition, rv reflect.Value) error {
+	t := rv.Type()
+	enum := BorshEnum(rv.Field(0).Uint())
+	if int(enum)+1 >= t.NumField() {
+		return errors.New("complex enum too large")
+	}
+	// write enum identifier
+	if err := def.WriteEnumTag(e, uint32(enum)); err != nil {
+		return err
+	}
+	// write enum field
+	return e.encodeRegistered(def, rv.Field(int(enum)+1), nil)
+}
+
+func (e *Encoder) encodeStructRegistered(def EncodingDefinition, rt reflect.Type, rv reflect.Value) (err error) {
+	l := rv.NumField()
+
+	if traceEnabled {
+		zlog.Debug("encode: struct", zap.Int("fields", l), zap.Stringer("type", rv.Kind()))
+	}
+
+	// Handle complex enum:
+	if isComplexEnumType(rt) {
+		return e.encodeComplexEnumRegistered(def, rv)
+	}
+
+	rules := rulesOf(def)
+	encode := func(rv reflect.Value, opt *option) error { return e.encodeRegistered(def, rv, opt) }
+	fields := newStructFields(rv)
+	for i := 0; i < l; i++ {
+		structField := rt.Field(i)
+		fieldTag, err := parseStructFieldTag(structField)
+		if err != nil {
+			return err
+		}
+
+		if fieldTag.Skip {
+			if traceEnabled {
+				zlog.Debug("encode: skipping struct field with skip flag",
+					zap.String("struct_field_name", structField.Name),
+				)
+			}
+			continue
+		}
+
+		option := fields.option(i, fieldTag, registeredFieldOrder(def, structField.Tag))
+		if err := rules.fieldOption(fieldTag, option); err != nil {
+			return fmt.Errorf("error while encoding %q field: %w", structField.Name, err)
+		}
+
+		rv, err := e.encodeFieldTags(fields, i, fieldTag, option.Order, encode)
+		if err != nil {
+			return err
+		}
+		if !rv.IsValid() {
+			continue
+		}
+
+		if !rv.CanInterface() {
+			if traceEnabled {
+				zlog.Debug("encode:  skipping field: unable to interface field, probably since field is not exported",
+					zap.String("struct_field_name", structField.Name),
+				)
+			}
+			continue
+		}
+
+		if traceEnabled {
+			zlog.Debug("encode: struct field",
+				zap.Stringer("struct_field_value_type", rv.Kind()),
+				zap.String("struct_field_name", structField.Name),
+				zap.Reflect("struct_field_tags", fieldTag),
+				zap.Reflect("struct_field_option", option),
+			)
+		}
+
+		if err := e.encodeRegistered(def, rv, option); err != nil {
+			return fmt.Errorf("error while encoding %q field: %w", structField.Name, err)
+		}
+	}
+	return nil
+}
+
+// writeRegisteredLength writes a length with the prefix of the `len=` tag
+// of the field, or else with the one of the encoding.
+func (e *Encoder) writeRegisteredLength(def EncodingDefinition, opt *option, length int) error {
+	if opt.hasLenPrefix() {
+		return e.writeLengthPrefix(opt, length)
+	}
+	return def.WriteLength(e, length)
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+func (e *Encoder) encodeReprC(rv reflect.Value, opt *option) (err error) {
+	if opt == nil {
+		opt = newDefaultOption()
+	}
+	e.currentFieldOpt = opt
+
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv = reflect.New(rv.Type().Elem()).Elem()
+			break
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+
+	t, err := reprCTypeOf(rv.Type(), false, opt.Order)
+	if err != nil {
+		return err
+	}
+	if traceEnabled {
+		zlog.Debug("encode: reprc", zap.Stringer("type", rv.Type()), zap.Int("size", t.size))
+	}
+
+	// The padding is zeroed:
+	buf := make([]byte, t.size)
+	reprCPut(buf, rv, t)
+	if err := e.toWriter(buf); err != nil {
+		return fmt.Errorf("reprc: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+func (e *Encoder) encodeRLP(rv reflect.Value, opt *option) (err error) {
+	if opt == nil {
+		opt = newDefaultOption()
+	}
+	e.currentFieldOpt = opt
+
+	if !rv.IsValid() {
+		return nil
+	}
+	if traceEnabled {
+		zlog.Debug("encode: rlp", zap.Stringer("type", rv.Type()))
+	}
+
+	// Custom marshalers (e.g. of variants) can write their items directly:
+	if rv.Kind() == reflect.Struct || rv.Kind() == reflect.Ptr && rv.Type().Elem().Kind() == reflect.Struct {
+		if marshaler, ok := rv.Interface().(BinaryMarshaler); ok && !isRLPBuiltinType(rv.Type()) {
+			if rv.Kind() != reflect.Ptr || !rv.IsNil() {
+				return marshaler.MarshalWithEncoder(e)
+			}
+		}
+	}
+
+	data, err := rlpEncodeValue(rv)
+	if err != nil {
+		return rlpWrapError(err, rlpRootName(rv.Type()))
+	}
+	return e.toWriter(data)
+}
+
+func rlpEncodeValue(rv reflect.Value) ([]byte, error) {
+	if rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, errors.New("nil interface")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			// Nil pointers are encoded as the empty value of their kind:
+			if rlpIsList(rv.Type().Elem()) {
+				return rlpEncodeList(nil), nil
+			}
+			return rlpEncodeString(nil), nil
+		}
+		return rlpEncodeValue(rv.Elem())
+	}
+	rt := rv.Type()
+
+	switch rt {
+	case rlpRawType:
+		return rv.Bytes(), nil
+	case bigIntType:
+		value := rv.Interface().(big.Int)
+		if value.Sign() < 0 {
+			return nil, fmt.Errorf("cannot encode negative big.Int %s", &value)
+		}
+		return rlpEncodeString(value.Bytes()), nil
+	case uint128Type:
+		value := rv.Interface().(Uint128)
+		buf := make([]byte, 16)
+		binary.BigEndian.PutUint64(buf, value.Hi)
+		binary.BigEndian.PutUint64(buf[8:], value.Lo)
+		return rlpEncodeString(rlpTrimLeadingZeros(buf)), nil
+	case uint256Type:
+		value := rv.Interface().(Uint256)
+		return rlpEncodeString(rlpTrimLeadingZeros(value[:])), nil
+	case int128Type, int256Type, float128Type:
+		return nil, fmt.Errorf("unsupported type %q", rt)
+	}
+
+	// Named primitive types (e.g. Uint64, HexBytes) are encoded by kind,
+	// whatever their binary marshaler:
+	switch rt.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return []byte{0x01}, nil
+		}
+		return rlpEncodeString(nil), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rlpEncodeString(rlpTrimLeadingZeros(rlpUint64Bytes(rv.Uint()))), nil
+	case reflect.String:
+		return rlpEncodeString([]byte(rv.String())), nil
+	case reflect.Slice:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			return rlpEncodeString(rv.Bytes()), nil
+		}
+	case reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			buf := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(buf), rv)
+			return rlpEncodeString(buf), nil
+		}
+	}
+
+	if marshaler, ok := rv.Interface().(BinaryMarshaler); ok {
+		buf := new(bytes.Buffer)
+		if err := marshaler.MarshalWithEncoder(NewRLPEncoder(buf)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Slice, reflect.Array:
+		var content []byte
+		for i := 0; i < rv.Len(); i++ {
+			item, err := rlpEncodeValue(rv.Index(i))
+			if err != nil {
+				return nil, rlpWrapError(err, fmt.Sprintf("[%d]", i))
+			}
+			content = append(content, item...)
+		}
+		return rlpEncodeList(content), nil
+	case reflect.Struct:
+		if isComplexEnumType(rt) {
+			return nil, fmt.Errorf("complex enum %s is not supported", rt)
+		}
+		return rlpEncodeStruct(rv)
+	}
+	return nil, fmt.Errorf("unsupported type %q", rt)
+}
+
+// rlpEncodeStruct encodes the fields of rv as a list. The trailing
+// `binary_extension` fields are omitted while they have a zero value.
+func rlpEncodeStruct(rv reflect.Value) ([]byte, error) {
+	rt := rv.Type()
+	fields, err := rlpFields(rt)
+	if err != nil {
+		return nil, err
+	}
+	count := len(fields)
+	for count > 0 {
+		index := fields[count-1]
+		if !parseFieldTag(rt.Field(index).Tag).BinaryExtension || !rv.Field(index).IsZero() {
+			break
+		}
+		count--
+	}
+
+	var content []byte
+	for _, index := range fields[:count] {
+		item, err := rlpEncodeValue(rv.Field(index))
+		if err != nil {
+			return nil, rlpWrapError(err, "."+rt.Field(index).Name)
+		}
+		content = append(content, item...)
+	}
+	return rlpEncodeList(content), nil
+}
+
+// rlpFields returns the struct fields that are items of the RLP list.
+func rlpFields(rt reflect.Type) ([]int, error) {
+	var out []int
+	for i := 0; i < rt.NumField(); i++ {
+		structField := rt.Field(i)
+		fieldTag, err := parseStructFieldTag(structField)
+		if err != nil {
+			return nil, err
+		}
+		if fieldTag.Skip || structField.PkgPath != "" {
+			continue
+		}
+		if fieldTag.Optional {
+			return nil, fmt.Errorf("field %s: optional fields are not supported, use binary_extension", structField.Name)
+		}
+		if fieldTag.LenPrefix != "" {
+			return nil, fmt.Errorf("field %s: len tags are not supported, lengths are part of the item headers", structField.Name)
+		}
+		if fieldTag.Fixed > 0 || fieldTag.CString {
+			return nil, fmt.Errorf("field %s: fixed and cstring tags are not supported", structField.Name)
+		}
+		if fieldTag.Bitfield != "" || fieldTag.Bits > 0 {
+			return nil, fmt.Errorf("field %s: bitfields are not supported", structField.Name)
+		}
+		if fieldTag.If != "" {
+			return nil, fmt.Errorf("field %s: conditional fields are not supported", structField.Name)
+		}
+		out = append(out, i)
+	}
+	return out, nil
+}
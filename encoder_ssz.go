@@ -0,0 +1,130 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+func (e *Encoder) encodeSSZ(rv reflect.Value, opt *option) (err error) {
+	if opt == nil {
+		opt = newDefaultOption()
+	}
+	e.currentFieldOpt = opt
+
+	if !rv.IsValid() {
+		return nil
+	}
+	if traceEnabled {
+		zlog.Debug("encode: ssz", zap.Stringer("type", rv.Type()))
+	}
+
+	t, err := sszTypeOf(rv.Type(), sszBounds{})
+	if err != nil {
+		return fmt.Errorf("ssz: %w", err)
+	}
+	data, err := sszEncode(rv, t)
+	if err != nil {
+		return fmt.Errorf("ssz: %w", err)
+	}
+	return e.toWriter(data)
+}
+
+func sszEncode(rv reflect.Value, t *sszType) ([]byte, error) {
+	rv = sszIndirect(rv, t)
+
+	switch t.kind {
+	case sszBasic:
+		return sszEncodeBasic(rv, t), nil
+	case sszBitvector:
+		bits, err := sszBits(rv, t)
+		if err != nil {
+			return nil, err
+		}
+		return sszPackBits(bits), nil
+	case sszBitlist:
+		bits, err := sszBits(rv, t)
+		if err != nil {
+			return nil, err
+		}
+		// The bit following the last one marks the length:
+		return sszPackBits(append(bits, true)), nil
+	}
+
+	values, types := t.elements(rv)
+	if t.kind != sszContainer {
+		if err := t.checkLength(len(values)); err != nil {
+			return nil, err
+		}
+	}
+
+	fixedLength := 0
+	for _, elemType := range types {
+		fixedLength += elemType.headLength()
+	}
+	out := make([]byte, 0, fixedLength)
+	var variable []byte
+	for i, value := range values {
+		data, err := sszEncode(value, types[i])
+		if err != nil {
+			return nil, sszWrapError(t, i, err)
+		}
+		if types[i].fixed {
+			out = append(out, data...)
+			continue
+		}
+		offset := make([]byte, sszOffsetSize)
+		binary.LittleEndian.PutUint32(offset, uint32(fixedLength+len(variable)))
+		out = append(out, offset...)
+		variable = append(variable, data...)
+	}
+	return append(out, variable...), nil
+}
+
+// sszEncodeBasic returns the little-endian bytes of a basic value.
+func sszEncodeBasic(rv reflect.Value, t *sszType) []byte {
+	buf := make([]byte, t.size)
+	switch t.rt {
+	case uint128Type:
+		value := rv.Interface().(Uint128)
+		binary.LittleEndian.PutUint64(buf, value.Lo)
+		binary.LittleEndian.PutUint64(buf[8:], value.Hi)
+		return buf
+	case uint256Type:
+		value := rv.Interface().(Uint256)
+		copy(buf, value[:])
+		ReverseBytes(buf)
+		return buf
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			buf[0] = 1
+		}
+	case reflect.Uint8:
+		buf[0] = uint8(rv.Uint())
+	case reflect.Uint16:
+		binary.LittleEndian.PutUint16(buf, uint16(rv.Uint()))
+	case reflect.Uint32:
+		binary.LittleEndian.PutUint32(buf, uint32(rv.Uint()))
+	case reflect.Uint64:
+		binary.LittleEndian.PutUint64(buf, rv.Uint())
+	}
+	return buf
+}
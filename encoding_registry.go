@@ -0,0 +1,284 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// EncodingDefinition defines the rules of an encoding registered with
+// RegisterEncoding. The values are walked like with the built-in encodings
+// (struct fields in order, `sizeof`, `optional` and `-` tags, complex enums,
+// arrays without length, and the BinaryMarshaler/BinaryUnmarshaler types),
+// and the definition encodes the parts that differ between the formats.
+type EncodingDefinition interface {
+	// Name is the name of the encoding, returned by Encoding.String.
+	Name() string
+	// ByteOrder is the order of the integers and floats, unless
+	// overridden by the `big` and `little` field tags.
+	ByteOrder() binary.ByteOrder
+
+	// WriteLength and ReadLength encode the lengths of the slices,
+	// strings and maps (also used by Encoder.WriteLength and Decoder.ReadLength).
+	WriteLength(enc *Encoder, length int) error
+	ReadLength(dec *Decoder) (int, error)
+
+	// WriteOptionTag and ReadOptionTag encode the presence of
+	// the fields with the `optional` tag.
+	WriteOptionTag(enc *Encoder, isPresent bool) error
+	ReadOptionTag(dec *Decoder) (bool, error)
+
+	// WriteEnumTag and ReadEnumTag encode the index of the variant of a complex enum.
+	WriteEnumTag(enc *Encoder, index uint32) error
+	ReadEnumTag(dec *Decoder) (uint32, error)
+
+	WriteString(enc *Encoder, s string) error
+	ReadString(dec *Decoder) (string, error)
+
+	WriteBool(enc *Encoder, b bool) error
+	ReadBool(dec *Decoder) (bool, error)
+
+	// SortMapKeys orders the keys of a map before its entries are encoded
+	// (e.g. with SortMapKeysByValue); it can leave them in iteration order.
+	SortMapKeys(keys []reflect.Value)
+}
+
+// encodingRules are the rules of the built-in encodings walked like the
+// registered ones that EncodingDefinition doesn't express; the definitions
+// embed noEncodingRules for the ones they don't need.
+type encodingRules interface {
+	// encodeValue encodes the values that the encoding represents its own
+	// way (e.g. the integers as varints), once their option tag is written,
+	// and before their BinaryMarshaler; it returns false for the values
+	// left to the walker.
+	encodeValue(enc *Encoder, rv reflect.Value, opt *option) (bool, error)
+	// decodeValue is the decoding counterpart of encodeValue; hasUnmarshaler
+	// tells whether the value implements BinaryUnmarshaler.
+	decodeValue(dec *Decoder, rv reflect.Value, opt *option, hasUnmarshaler bool) (bool, error)
+
+	// encodeOptional and decodeOptional encode the optional values that
+	// have no option tag (e.g. the SCALE Option<bool>); they return false
+	// for the values left to the walker.
+	encodeOptional(enc *Encoder, rv reflect.Value) (bool, error)
+	decodeOptional(dec *Decoder, rv reflect.Value) (bool, error)
+
+	// fieldOption completes the option of a struct field with the tags
+	// of the encoding, and rejects the tags it doesn't support.
+	fieldOption(fieldTag *fieldTag, opt *option) error
+
+	// checkMapKey checks the order of the serialized keys of a map,
+	// prev being nil for the first one.
+	checkMapKey(prev, key []byte) error
+}
+
+type noEncodingRules struct{}
+
+func (noEncodingRules) encodeValue(*Encoder, reflect.Value, *option) (bool, error) {
+	return false, nil
+}
+
+func (noEncodingRules) decodeValue(*Decoder, reflect.Value, *option, bool) (bool, error) {
+	return false, nil
+}
+
+func (noEncodingRules) encodeOptional(*Encoder, reflect.Value) (bool, error) { return false, nil }
+func (noEncodingRules) decodeOptional(*Decoder, reflect.Value) (bool, error) { return false, nil }
+func (noEncodingRules) fieldOption(*fieldTag, *option) error                 { return nil }
+func (noEncodingRules) checkMapKey(prev, key []byte) error                   { return nil }
+
+// builtinEncodings are the built-in encodings walked like the registered ones.
+var builtinEncodings = map[Encoding]EncodingDefinition{
+	EncodingBCS:           bcsEncoding{},
+	EncodingBincode:       bincodeEncoding{},
+	EncodingBincodeVarint: bincodeEncoding{varint: true},
+	EncodingBitcoin:       bitcoinEncoding{},
+	EncodingPostcard:      postcardEncoding{},
+	EncodingSCALE:         scaleEncoding{},
+	EncodingXDR:           xdrEncoding{},
+}
+
+// encodingDefinition returns the definition of a built-in encoding walked
+// like the registered ones, or of an encoding registered with RegisterEncoding.
+func encodingDefinition(enc Encoding) (EncodingDefinition, bool) {
+	if def, ok := builtinEncodings[enc]; ok {
+		return def, true
+	}
+	return registeredEncoding(enc)
+}
+
+// rulesOf returns the rules of the definition beyond EncodingDefinition.
+func rulesOf(def EncodingDefinition) encodingRules {
+	if rules, ok := def.(encodingRules); ok {
+		return rules
+	}
+	return noEncodingRules{}
+}
+
+// writeLittleEndian writes the integers and floats in little-endian, whatever
+// the `big` tag of their field; it returns false for the other values.
+func (e *Encoder) writeLittleEndian(rv reflect.Value) (bool, error) {
+	switch rv.Kind() {
+	case reflect.Int16:
+		return true, e.WriteInt16(int16(rv.Int()), LE)
+	case reflect.Uint16:
+		return true, e.WriteUint16(uint16(rv.Uint()), LE)
+	case reflect.Int32:
+		return true, e.WriteInt32(int32(rv.Int()), LE)
+	case reflect.Uint32:
+		return true, e.WriteUint32(uint32(rv.Uint()), LE)
+	case reflect.Int64, reflect.Int:
+		return true, e.WriteInt64(rv.Int(), LE)
+	case reflect.Uint64, reflect.Uint:
+		return true, e.WriteUint64(rv.Uint(), LE)
+	case reflect.Float32:
+		return true, e.WriteFloat32(float32(rv.Float()), LE)
+	case reflect.Float64:
+		return true, e.WriteFloat64(rv.Float(), LE)
+	}
+	return false, nil
+}
+
+// readLittleEndian is the decoding counterpart of writeLittleEndian.
+func (dec *Decoder) readLittleEndian(rv reflect.Value) (bool, error) {
+	switch rv.Kind() {
+	case reflect.Int16:
+		n, err := dec.ReadInt16(LE)
+		rv.SetInt(int64(n))
+		return true, err
+	case reflect.Uint16:
+		n, err := dec.ReadUint16(LE)
+		rv.SetUint(uint64(n))
+		return true, err
+	case reflect.Int32:
+		n, err := dec.ReadInt32(LE)
+		rv.SetInt(int64(n))
+		return true, err
+	case reflect.Uint32:
+		n, err := dec.ReadUint32(LE)
+		rv.SetUint(uint64(n))
+		return true, err
+	case reflect.Int64, reflect.Int:
+		n, err := dec.ReadInt64(LE)
+		rv.SetInt(n)
+		return true, err
+	case reflect.Uint64, reflect.Uint:
+		n, err := dec.ReadUint64(LE)
+		rv.SetUint(n)
+		return true, err
+	case reflect.Float32:
+		f, err := dec.ReadFloat32(LE)
+		rv.SetFloat(float64(f))
+		return true, err
+	case reflect.Float64:
+		f, err := dec.ReadFloat64(LE)
+		rv.SetFloat(f)
+		return true, err
+	}
+	return false, nil
+}
+
+// isBinaryMarshaler tells whether rv implements BinaryMarshaler.
+func isBinaryMarshaler(rv reflect.Value) bool {
+	_, ok := rv.Interface().(BinaryMarshaler)
+	return ok
+}
+
+// firstRegisteredEncoding leaves room for the built-in encodings.
+const firstRegisteredEncoding Encoding = 1 << 16
+
+var (
+	encodingRegistryMu     sync.RWMutex
+	encodingRegistry       = map[Encoding]EncodingDefinition{}
+	nextRegisteredEncoding = firstRegisteredEncoding
+)
+
+// RegisterEncoding registers the encoding of the definition, and returns its
+// Encoding, to be used with NewEncoderWithEncoding and NewDecoderWithEncoding.
+// It panics if the name of the definition is empty or already used.
+func RegisterEncoding(def EncodingDefinition) Encoding {
+	encodingRegistryMu.Lock()
+	defer encodingRegistryMu.Unlock()
+
+	name := def.Name()
+	if name == "" {
+		panic("bin: RegisterEncoding: empty encoding name")
+	}
+	for enc := EncodingBin; enc < firstRegisteredEncoding && isValidEncoding(enc); enc++ {
+		if enc.String() == name {
+			panic(fmt.Sprintf("bin: RegisterEncoding: %q is a built-in encoding", name))
+		}
+	}
+	for _, registered := range encodingRegistry {
+		if registered.Name() == name {
+			panic(fmt.Sprintf("bin: RegisterEncoding: encoding %q already registered", name))
+		}
+	}
+
+	enc := nextRegisteredEncoding
+	nextRegisteredEncoding++
+	encodingRegistry[enc] = def
+	return enc
+}
+
+// registeredEncoding returns the definition of an encoding registered with RegisterEncoding.
+func registeredEncoding(enc Encoding) (EncodingDefinition, bool) {
+	if enc < firstRegisteredEncoding {
+		return nil, false
+	}
+	encodingRegistryMu.RLock()
+	defer encodingRegistryMu.RUnlock()
+	def, ok := encodingRegistry[enc]
+	return def, ok
+}
+
+// SortMapKeysByValue sorts the keys of a map by value, like the built-in
+// encodings do (the keys must be numbers or strings).
+func SortMapKeysByValue(keys []reflect.Value) {
+	sort.Slice(keys, vComp(keys))
+}
+
+// registeredFieldOrder returns the byte order of a struct field:
+// the one of its `big` or `little` tag, or the one of the encoding.
+func registeredFieldOrder(def EncodingDefinition, tag reflect.StructTag) binary.ByteOrder {
+	for _, s := range strings.Split(tag.Get("bin"), " ") {
+		switch s {
+		case "big":
+			return binary.BigEndian
+		case "little":
+			return binary.LittleEndian
+		}
+	}
+	return def.ByteOrder()
+}
+
+// readUTF8String reads a string after its length, with the prefix of the
+// `len=` tag of opt or else of the encoding, and checks that it's valid
+// UTF-8 (as the strings of Rust are).
+func (dec *Decoder) readUTF8String(encoding string, opt *option) (string, error) {
+	data, err := dec.readStringWithLengthPrefix(opt)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("%s: string is not valid utf-8", encoding)
+	}
+	return string(data), nil
+}
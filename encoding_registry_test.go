@@ -0,0 +1,233 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEncoding has big-endian u16 lengths, u32 enum tags,
+// and "T"/"F" option tags and bools.
+type testEncoding struct{}
+
+func (testEncoding) Name() string                { return "Test" }
+func (testEncoding) ByteOrder() binary.ByteOrder { return binary.BigEndian }
+
+func (testEncoding) WriteLength(enc *Encoder, length int) error {
+	if length > 0xffff {
+		return fmt.Errorf("test: length %d too large", length)
+	}
+	return enc.WriteUint16(uint16(length), BE)
+}
+
+func (testEncoding) ReadLength(dec *Decoder) (int, error) {
+	l, err := dec.ReadUint16(BE)
+	if err != nil {
+		return 0, err
+	}
+	if int(l) > dec.Remaining() {
+		return 0, fmt.Errorf("test: length %d larger than remaining %d bytes", l, dec.Remaining())
+	}
+	return int(l), nil
+}
+
+func (def testEncoding) WriteOptionTag(enc *Encoder, isPresent bool) error {
+	return def.WriteBool(enc, isPresent)
+}
+
+func (def testEncoding) ReadOptionTag(dec *Decoder) (bool, error) {
+	return def.ReadBool(dec)
+}
+
+func (testEncoding) WriteEnumTag(enc *Encoder, index uint32) error {
+	return enc.WriteUint32(index, BE)
+}
+
+func (testEncoding) ReadEnumTag(dec *Decoder) (uint32, error) {
+	return dec.ReadUint32(BE)
+}
+
+func (def testEncoding) WriteString(enc *Encoder, s string) error {
+	if err := def.WriteLength(enc, len(s)); err != nil {
+		return err
+	}
+	return enc.WriteBytes([]byte(s), false)
+}
+
+func (def testEncoding) ReadString(dec *Decoder) (string, error) {
+	l, err := def.ReadLength(dec)
+	if err != nil {
+		return "", err
+	}
+	data, err := dec.ReadNBytes(l)
+	return string(data), err
+}
+
+func (testEncoding) WriteBool(enc *Encoder, b bool) error {
+	if b {
+		return enc.WriteByte('T')
+	}
+	return enc.WriteByte('F')
+}
+
+func (testEncoding) ReadBool(dec *Decoder) (bool, error) {
+	b, err := dec.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	switch b {
+	case 'T':
+		return true, nil
+	case 'F':
+		return false, nil
+	}
+	return false, fmt.Errorf("test: invalid bool value %d", b)
+}
+
+func (testEncoding) SortMapKeys(keys []reflect.Value) {
+	SortMapKeysByValue(keys)
+}
+
+var encodingTest = RegisterEncoding(testEncoding{})
+
+type registryShape struct {
+	Enum   BorshEnum `borsh_enum:"true"`
+	Empty  struct{}
+	Square uint16
+}
+
+type registryRecord struct {
+	ID      uint32
+	Nonce   Uint64 `bin:"little"`
+	Name    string
+	Active  bool
+	Note    *string `bin:"optional"`
+	Count   uint8   `bin:"sizeof=Points"`
+	Points  []int16
+	Shapes  []registryShape
+	Tags    map[string]uint8
+	Hash    [2]byte
+	Ignored int `bin:"-"`
+}
+
+func TestRegisterEncoding(t *testing.T) {
+	assert.Equal(t, "Test", encodingTest.String())
+	assert.True(t, isValidEncoding(encodingTest))
+	assert.False(t, isValidEncoding(encodingTest+1000))
+
+	require.PanicsWithValue(t, `bin: RegisterEncoding: encoding "Test" already registered`, func() {
+		RegisterEncoding(testEncoding{})
+	})
+	require.PanicsWithValue(t, `bin: RegisterEncoding: "Borsh" is a built-in encoding`, func() {
+		RegisterEncoding(namedTestEncoding{name: "Borsh"})
+	})
+	require.PanicsWithValue(t, "bin: RegisterEncoding: empty encoding name", func() {
+		RegisterEncoding(namedTestEncoding{})
+	})
+}
+
+type namedTestEncoding struct {
+	testEncoding
+	name string
+}
+
+func (def namedTestEncoding) Name() string { return def.name }
+
+func TestRegisteredEncoding_Serialization(t *testing.T) {
+	note := "hi"
+	record := registryRecord{
+		ID:     7,
+		Nonce:  1,
+		Name:   "abc",
+		Active: true,
+		Note:   &note,
+		Count:  2,
+		Points: []int16{-1, 2},
+		Shapes: []registryShape{{Enum: 1, Square: 3}, {Enum: 0}},
+		Tags:   map[string]uint8{"b": 2, "a": 1},
+		Hash:   [2]byte{0xca, 0xfe},
+	}
+	expected := "00000007" + "0100000000000000" + "0003616263" + "54" + "54" + "00026869" +
+		"02" + "ffff0002" +
+		"0002" + "000000010003" + "00000000" +
+		"0002" + "00016101" + "00016202" +
+		"cafe"
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, NewEncoderWithEncoding(buf, encodingTest).Encode(record))
+	assert.Equal(t, expected, hex.EncodeToString(buf.Bytes()))
+
+	var got registryRecord
+	require.NoError(t, NewDecoderWithEncoding(buf.Bytes(), encodingTest).Decode(&got))
+	assert.Equal(t, record, got)
+
+	{
+		record.Note = nil
+		buf := new(bytes.Buffer)
+		require.NoError(t, NewEncoderWithEncoding(buf, encodingTest).Encode(record))
+
+		var got registryRecord
+		require.NoError(t, NewDecoderWithEncoding(buf.Bytes(), encodingTest).Decode(&got))
+		assert.Equal(t, record, got)
+	}
+	{
+		buf := new(bytes.Buffer)
+		require.NoError(t, NewEncoderWithEncoding(buf, encodingTest).WriteLength(300))
+		assert.Equal(t, []byte{0x01, 0x2c}, buf.Bytes())
+
+		l, err := NewDecoderWithEncoding([]byte{0x00, 0x01, 0xff}, encodingTest).ReadLength()
+		require.NoError(t, err)
+		assert.Equal(t, 1, l)
+	}
+
+	require.EqualError(t,
+		NewDecoderWithEncoding(mustHex("0000000701000000000000000003616263"+"58"), encodingTest).Decode(new(registryRecord)),
+		`error while decoding "Active" field: test: invalid bool value 88`,
+	)
+}
+
+// unboundedTestEncoding reads the lengths without checking them.
+type unboundedTestEncoding struct{ testEncoding }
+
+func (unboundedTestEncoding) Name() string { return "TestUnbounded" }
+
+func (unboundedTestEncoding) ReadLength(dec *Decoder) (int, error) {
+	l, err := dec.ReadUint16(BE)
+	return int(l), err
+}
+
+func TestRegisteredEncoding_LengthBound(t *testing.T) {
+	encoding := RegisterEncoding(unboundedTestEncoding{})
+
+	var slice struct{ B [][4096]byte }
+	require.EqualError(t,
+		NewDecoderWithEncoding(mustHex("ffff"), encoding).Decode(&slice),
+		`error while decoding "B" field: testunbounded: length 65535 exceeds the remaining 0 bytes`,
+	)
+
+	var m map[uint16]uint16
+	require.EqualError(t,
+		NewDecoderWithEncoding(mustHex("0005"+"00010002"), encoding).Decode(&m),
+		"testunbounded: length 5 exceeds the remaining 4 bytes",
+	)
+}
@@ -0,0 +1,319 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Uint256 is an unsigned 256 bits integer (the `uint256` of solidity),
+// stored as big-endian bytes.
+type Uint256 [32]byte
+
+// Int256 is a signed 256 bits integer (the `int256` of solidity),
+// stored as big-endian two's complement bytes.
+type Int256 [32]byte
+
+// EVMAddress is a 20 bytes EVM account address.
+type EVMAddress [20]byte
+
+var (
+	uint256Type    = reflect.TypeOf(Uint256{})
+	int256Type     = reflect.TypeOf(Int256{})
+	evmAddressType = reflect.TypeOf(EVMAddress{})
+
+	twoPow256 = new(big.Int).Lsh(big.NewInt(1), 256)
+	twoPow255 = new(big.Int).Lsh(big.NewInt(1), 255)
+)
+
+// NewUint256FromBigInt returns the Uint256 of n, which must be in [0, 2^256).
+func NewUint256FromBigInt(n *big.Int) (out Uint256, err error) {
+	if n.Sign() < 0 || n.Cmp(twoPow256) >= 0 {
+		return out, fmt.Errorf("%s overflows uint256", n)
+	}
+	n.FillBytes(out[:])
+	return out, nil
+}
+
+func (u Uint256) BigInt() *big.Int {
+	return new(big.Int).SetBytes(u[:])
+}
+
+func (u Uint256) String() string {
+	return u.BigInt().String()
+}
+
+// NewInt256FromBigInt returns the Int256 of n, which must be in [-2^255, 2^255).
+func NewInt256FromBigInt(n *big.Int) (out Int256, err error) {
+	if n.Cmp(new(big.Int).Neg(twoPow255)) < 0 || n.Cmp(twoPow255) >= 0 {
+		return out, fmt.Errorf("%s overflows int256", n)
+	}
+	if n.Sign() < 0 {
+		n = new(big.Int).Add(n, twoPow256)
+	}
+	n.FillBytes(out[:])
+	return out, nil
+}
+
+func (i Int256) BigInt() *big.Int {
+	value := new(big.Int).SetBytes(i[:])
+	if i[0]&0x80 != 0 {
+		value.Sub(value, twoPow256)
+	}
+	return value
+}
+
+func (i Int256) String() string {
+	return i.BigInt().String()
+}
+
+func (u Uint256) MarshalWithEncoder(enc *Encoder) error {
+	return enc.writeInt256Bytes(u[:])
+}
+
+func (u *Uint256) UnmarshalWithDecoder(dec *Decoder) error {
+	return dec.readInt256Bytes(u[:])
+}
+
+func (i Int256) MarshalWithEncoder(enc *Encoder) error {
+	return enc.writeInt256Bytes(i[:])
+}
+
+func (i *Int256) UnmarshalWithDecoder(dec *Decoder) error {
+	return dec.readInt256Bytes(i[:])
+}
+
+// writeInt256Bytes writes the big-endian 256 bits integer
+// in the byte order of the current field (little-endian by default).
+func (e *Encoder) writeInt256Bytes(be []byte) error {
+	buf := make([]byte, 32)
+	copy(buf, be)
+	if e.currentFieldOpt == nil || e.currentFieldOpt.Order != binary.BigEndian {
+		ReverseBytes(buf)
+	}
+	return e.WriteBytes(buf, false)
+}
+
+func (dec *Decoder) readInt256Bytes(out []byte) error {
+	data, err := dec.ReadNBytes(32)
+	if err != nil {
+		return err
+	}
+	copy(out, data)
+	if dec.currentFieldOpt == nil || dec.currentFieldOpt.Order != binary.BigEndian {
+		ReverseBytes(out)
+	}
+	return nil
+}
+
+func (a EVMAddress) String() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// EVMSelector returns the 4 bytes selector of a solidity function, i.e. the first
+// bytes of the keccak256 of its canonical signature (e.g. "transfer(address,uint256)").
+func EVMSelector(signature string) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(signature))
+	return hash.Sum(nil)[:4]
+}
+
+func EVMSelectorTypeID(signature string) TypeID {
+	return TypeIDFromBytes(EVMSelector(signature))
+}
+
+// EVMSignature returns the canonical signature of the solidity function
+// `name` whose arguments are the fields of the args struct.
+func EVMSignature(name string, args interface{}) (string, error) {
+	rt := reflect.TypeOf(args)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return "", fmt.Errorf("abi: arguments of %q must be a struct, got %T", name, args)
+	}
+	tuple, err := evmABITypeName(rt)
+	if err != nil {
+		return "", fmt.Errorf("abi: function %q: %w", name, err)
+	}
+	return name + tuple, nil
+}
+
+// evmABIFields returns the struct fields that are part of the ABI tuple.
+func evmABIFields(rt reflect.Type) ([]int, error) {
+	var out []int
+	for i := 0; i < rt.NumField(); i++ {
+		structField := rt.Field(i)
+		fieldTag, err := parseStructFieldTag(structField)
+		if err != nil {
+			return nil, err
+		}
+		if fieldTag.Skip || structField.PkgPath != "" {
+			continue
+		}
+		if fieldTag.Optional || fieldTag.BinaryExtension {
+			return nil, fmt.Errorf("field %s: optional fields are not supported", structField.Name)
+		}
+		if fieldTag.LenPrefix != "" {
+			return nil, fmt.Errorf("field %s: len tags are not supported, lengths are 32-byte words", structField.Name)
+		}
+		if fieldTag.Fixed > 0 || fieldTag.CString {
+			return nil, fmt.Errorf("field %s: fixed and cstring tags are not supported", structField.Name)
+		}
+		if fieldTag.Bitfield != "" || fieldTag.Bits > 0 {
+			return nil, fmt.Errorf("field %s: bitfields are not supported", structField.Name)
+		}
+		if fieldTag.If != "" {
+			return nil, fmt.Errorf("field %s: conditional fields are not supported", structField.Name)
+		}
+		out = append(out, i)
+	}
+	return out, nil
+}
+
+// evmABITypeName returns the solidity type of rt.
+func evmABITypeName(rt reflect.Type) (string, error) {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	switch rt {
+	case uint128Type:
+		return "uint128", nil
+	case int128Type:
+		return "int128", nil
+	case uint256Type:
+		return "uint256", nil
+	case int256Type:
+		return "int256", nil
+	case evmAddressType:
+		return "address", nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		return "bool", nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("uint%d", rt.Bits()), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("int%d", rt.Bits()), nil
+	case reflect.String:
+		return "string", nil
+	case reflect.Slice:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			return "bytes", nil
+		}
+		elem, err := evmABITypeName(rt.Elem())
+		if err != nil {
+			return "", err
+		}
+		return elem + "[]", nil
+	case reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 && rt.Len() >= 1 && rt.Len() <= 32 {
+			return fmt.Sprintf("bytes%d", rt.Len()), nil
+		}
+		elem, err := evmABITypeName(rt.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s[%d]", elem, rt.Len()), nil
+	case reflect.Struct:
+		if isComplexEnumType(rt) {
+			return "", fmt.Errorf("complex enum %s is not supported", rt)
+		}
+		fields, err := evmABIFields(rt)
+		if err != nil {
+			return "", err
+		}
+		names := make([]string, len(fields))
+		for i, index := range fields {
+			if names[i], err = evmABITypeName(rt.Field(index).Type); err != nil {
+				return "", fmt.Errorf("field %s: %w", rt.Field(index).Name, err)
+			}
+		}
+		return "(" + strings.Join(names, ",") + ")", nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", rt)
+	}
+}
+
+// evmABIIsDynamic returns true if the values of type rt are
+// encoded in the tail of the enclosing tuple.
+func evmABIIsDynamic(rt reflect.Type) bool {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	switch rt {
+	case uint128Type, int128Type, uint256Type, int256Type, evmAddressType:
+		return false
+	}
+	switch rt.Kind() {
+	case reflect.String, reflect.Slice:
+		return true
+	case reflect.Array:
+		return rt.Len() > 0 && evmABIIsDynamic(rt.Elem())
+	case reflect.Struct:
+		for i := 0; i < rt.NumField(); i++ {
+			structField := rt.Field(i)
+			if parseFieldTag(structField.Tag).Skip || structField.PkgPath != "" {
+				continue
+			}
+			if evmABIIsDynamic(structField.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// evmABIHeadSize returns the size of a value of type rt in the head of
+// the enclosing tuple: the size of its encoding if it's static,
+// or the size of an offset.
+func evmABIHeadSize(rt reflect.Type) int {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if evmABIIsDynamic(rt) {
+		return 32
+	}
+	switch rt {
+	case uint128Type, int128Type, uint256Type, int256Type, evmAddressType:
+		return 32
+	}
+	switch rt.Kind() {
+	case reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 && rt.Len() >= 1 && rt.Len() <= 32 {
+			return 32
+		}
+		return rt.Len() * evmABIHeadSize(rt.Elem())
+	case reflect.Struct:
+		size := 0
+		for i := 0; i < rt.NumField(); i++ {
+			structField := rt.Field(i)
+			if parseFieldTag(structField.Tag).Skip || structField.PkgPath != "" {
+				continue
+			}
+			size += evmABIHeadSize(structField.Type)
+		}
+		return size
+	}
+	return 32
+}
@@ -0,0 +1,284 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// evmWords concatenates the hex-encoded 32 bytes words.
+func evmWords(t *testing.T, words ...string) []byte {
+	var out []byte
+	for _, word := range words {
+		buf := mustHex(word)
+		require.Len(t, buf, 32, word)
+		out = append(out, buf...)
+	}
+	return out
+}
+
+func evmUintWord(v string) string {
+	return strings.Repeat("0", 64-len(v)) + v
+}
+
+func evmBytesWord(v string) string {
+	return v + strings.Repeat("0", 64-len(v))
+}
+
+func TestEVMSelector(t *testing.T) {
+	assert.Equal(t, mustHex("a9059cbb"), EVMSelector("transfer(address,uint256)"))
+	assert.Equal(t, mustHex("cdcd77c0"), EVMSelector("baz(uint32,bool)"))
+	assert.Equal(t, mustHex("8be65246"), EVMSelector("f(uint256,uint32[],bytes10,bytes)"))
+
+	type transferArgs struct {
+		To    EVMAddress
+		Value Uint256
+	}
+	signature, err := EVMSignature("transfer", transferArgs{})
+	require.NoError(t, err)
+	assert.Equal(t, "transfer(address,uint256)", signature)
+
+	type fArgs struct {
+		A uint64
+		B []uint32
+		C [10]byte
+		D []byte
+		E struct {
+			X int8
+			Y []string
+		}
+		F [2]Int256
+	}
+	signature, err = EVMSignature("f", &fArgs{})
+	require.NoError(t, err)
+	assert.Equal(t, "f(uint64,uint32[],bytes10,bytes,(int8,string[]),int256[2])", signature)
+
+	type optionalArgs struct {
+		A *uint64 `bin:"optional"`
+	}
+	_, err = EVMSignature("f", optionalArgs{})
+	require.Error(t, err)
+}
+
+func TestEVMABI_StaticArguments(t *testing.T) {
+	type bazArgs struct {
+		X uint32
+		Y bool
+	}
+	data, err := MarshalEVMABI(bazArgs{X: 69, Y: true})
+	require.NoError(t, err)
+	assert.Equal(t, evmWords(t, evmUintWord("45"), evmUintWord("1")), data)
+
+	var got bazArgs
+	require.NoError(t, UnmarshalEVMABI(&got, data))
+	assert.Equal(t, bazArgs{X: 69, Y: true}, got)
+	assert.Equal(t, uint64(64), MustEVMABIByteCount(bazArgs{}))
+}
+
+func TestEVMABI_DynamicArguments(t *testing.T) {
+	// Examples of the solidity documentation.
+	type fArgs struct {
+		A Uint256
+		B []uint32
+		C [10]byte
+		D []byte
+	}
+	a, err := NewUint256FromBigInt(big.NewInt(0x123))
+	require.NoError(t, err)
+	args := fArgs{
+		A: a,
+		B: []uint32{0x456, 0x789},
+		D: []byte("Hello, world!"),
+	}
+	copy(args.C[:], "1234567890")
+
+	expected := evmWords(t,
+		evmUintWord("123"),
+		evmUintWord("80"),
+		evmBytesWord("31323334353637383930"),
+		evmUintWord("e0"),
+		evmUintWord("2"),
+		evmUintWord("456"),
+		evmUintWord("789"),
+		evmUintWord("d"),
+		evmBytesWord("48656c6c6f2c20776f726c6421"),
+	)
+	data, err := MarshalEVMABI(args)
+	require.NoError(t, err)
+	assert.Equal(t, expected, data)
+
+	var got fArgs
+	require.NoError(t, UnmarshalEVMABI(&got, data))
+	assert.Equal(t, args, got)
+
+	type gArgs struct {
+		A [][]Uint256
+		B []string
+	}
+	uint256 := func(v int64) Uint256 {
+		out, err := NewUint256FromBigInt(big.NewInt(v))
+		require.NoError(t, err)
+		return out
+	}
+	nested := gArgs{
+		A: [][]Uint256{{uint256(1), uint256(2)}, {uint256(3)}},
+		B: []string{"one", "two", "three"},
+	}
+	expected = evmWords(t,
+		evmUintWord("40"),
+		evmUintWord("140"),
+		evmUintWord("2"),
+		evmUintWord("40"),
+		evmUintWord("a0"),
+		evmUintWord("2"),
+		evmUintWord("1"),
+		evmUintWord("2"),
+		evmUintWord("1"),
+		evmUintWord("3"),
+		evmUintWord("3"),
+		evmUintWord("60"),
+		evmUintWord("a0"),
+		evmUintWord("e0"),
+		evmUintWord("3"),
+		evmBytesWord("6f6e65"),
+		evmUintWord("3"),
+		evmBytesWord("74776f"),
+		evmUintWord("5"),
+		evmBytesWord("7468726565"),
+	)
+	data, err = MarshalEVMABI(nested)
+	require.NoError(t, err)
+	assert.Equal(t, expected, data)
+
+	var gotNested gArgs
+	require.NoError(t, UnmarshalEVMABI(&gotNested, data))
+	assert.Equal(t, nested, gotNested)
+}
+
+func TestEVMABI_SingleValue(t *testing.T) {
+	data, err := MarshalEVMABI("dave")
+	require.NoError(t, err)
+	assert.Equal(t, evmWords(t, evmUintWord("20"), evmUintWord("4"), evmBytesWord("64617665")), data)
+
+	var got string
+	require.NoError(t, UnmarshalEVMABI(&got, data))
+	assert.Equal(t, "dave", got)
+
+	data, err = MarshalEVMABI(int16(-2))
+	require.NoError(t, err)
+	assert.Equal(t, evmWords(t, strings.Repeat("f", 63)+"e"), data)
+
+	var gotInt int16
+	require.NoError(t, UnmarshalEVMABI(&gotInt, data))
+	assert.Equal(t, int16(-2), gotInt)
+}
+
+func TestEVMABI_Integers(t *testing.T) {
+	minusOne, err := NewInt256FromBigInt(big.NewInt(-1))
+	require.NoError(t, err)
+	assert.Equal(t, "-1", minusOne.String())
+
+	data, err := MarshalEVMABI(minusOne)
+	require.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{0xff}, 32), data)
+
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	u, err := NewUint256FromBigInt(maxUint256)
+	require.NoError(t, err)
+	assert.Equal(t, maxUint256.String(), u.String())
+
+	_, err = NewUint256FromBigInt(new(big.Int).Add(maxUint256, big.NewInt(1)))
+	require.Error(t, err)
+	_, err = NewInt256FromBigInt(new(big.Int).Lsh(big.NewInt(1), 255))
+	require.Error(t, err)
+
+	{
+		var got Int128
+		require.NoError(t, UnmarshalEVMABI(&got, data))
+		assert.Equal(t, Int128{Lo: math.MaxUint64, Hi: math.MaxUint64}, got)
+	}
+	{
+		// Out of range and non-canonical values are rejected:
+		var gotUint32 uint32
+		require.Error(t, UnmarshalEVMABI(&gotUint32, evmWords(t, evmUintWord("100000000"))))
+		var gotInt8 int8
+		require.Error(t, UnmarshalEVMABI(&gotInt8, evmWords(t, evmUintWord("80"))))
+		var gotBool bool
+		require.Error(t, UnmarshalEVMABI(&gotBool, evmWords(t, evmUintWord("2"))))
+		var gotAddress EVMAddress
+		require.Error(t, UnmarshalEVMABI(&gotAddress, evmWords(t, "01"+strings.Repeat("0", 62))))
+	}
+
+	// With the other encodings, 256 bits integers are little-endian by default:
+	one, err := NewUint256FromBigInt(big.NewInt(1))
+	require.NoError(t, err)
+	data, err = MarshalBorsh(one)
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte{1}, make([]byte, 31)...), data)
+
+	var got Uint256
+	require.NoError(t, UnmarshalBorsh(&got, data))
+	assert.Equal(t, one, got)
+}
+
+type evmTransfer struct {
+	To    EVMAddress
+	Value Uint256
+}
+
+type evmApprove struct {
+	Spender EVMAddress
+	Value   Uint256
+}
+
+func TestEVMSelectorTypeIDEncoding(t *testing.T) {
+	def := NewVariantDefinition(
+		EVMSelectorTypeIDEncoding,
+		[]VariantType{
+			{Name: "transfer", Type: (*evmTransfer)(nil)},
+			{Name: "approve(address,uint256)", Type: (*evmApprove)(nil)},
+		},
+	)
+	require.Equal(t, EVMSelectorTypeID("transfer(address,uint256)"), def.TypeID("transfer"))
+	require.Equal(t, EVMSelectorTypeID("approve(address,uint256)"), def.TypeID("approve(address,uint256)"))
+
+	value, err := NewUint256FromBigInt(big.NewInt(1000))
+	require.NoError(t, err)
+	transfer := &evmTransfer{Value: value}
+	transfer.To[19] = 0x01
+
+	args, err := MarshalEVMABI(transfer)
+	require.NoError(t, err)
+	buf := append(mustHex("a9059cbb"), args...)
+
+	var got BaseVariant
+	require.NoError(t, got.UnmarshalBinaryVariant(NewEVMABIDecoder(buf), def))
+	assert.Equal(t, def.TypeID("transfer"), got.TypeID)
+	assert.Equal(t, transfer, got.Impl)
+
+	require.Panics(t, func() {
+		NewVariantDefinition(EVMSelectorTypeIDEncoding, []VariantType{
+			{Name: "transfer", Type: (*evmTransfer)(nil)},
+			{Name: "transfer(address,uint256)", Type: (*evmApprove)(nil)},
+		})
+	})
+}
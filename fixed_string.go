@@ -0,0 +1,124 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+)
+
+// The string and byte slice fields with the `fixed=N` tag are stored
+// in N bytes, zero-padded (e.g. a `[u8; 32]` name), and the ones with
+// the `cstring` tag are terminated by a NUL byte. With both tags, the
+// NUL-terminated string is zero-padded to N bytes.
+//
+// The padding and the terminator are trimmed on decode.
+//
+// XDR and SSZ size their values with their own `xdr_size=N` and
+// `ssz_size=N` tags and reject these ones; a field with both `fixed=N`
+// and one of these size tags is an error with every encoding.
+
+func (o *option) hasFixedString() bool {
+	return o != nil && (o.FixedSize > 0 || o.CString)
+}
+
+// fixedStringBytes returns the bytes of a string or byte slice
+// (the value of a pointer to one of them).
+func fixedStringBytes(rv reflect.Value) ([]byte, error) {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv = reflect.Zero(rv.Type().Elem())
+		} else {
+			rv = rv.Elem()
+		}
+	}
+	switch {
+	case rv.Kind() == reflect.String:
+		return []byte(rv.String()), nil
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		return rv.Bytes(), nil
+	}
+	return nil, fmt.Errorf("fixed and cstring tags require a string or byte slice, got %s", rv.Type())
+}
+
+// writeFixedString writes a string or byte slice with the
+// `fixed=N` and `cstring` tags of its field.
+func (e *Encoder) writeFixedString(rv reflect.Value, opt *option) error {
+	data, err := fixedStringBytes(rv)
+	if err != nil {
+		return err
+	}
+	if opt.CString && bytes.IndexByte(data, 0) >= 0 {
+		return fmt.Errorf("cstring: %q contains a NUL byte", data)
+	}
+
+	size := len(data)
+	if opt.CString {
+		size++
+	}
+	if opt.FixedSize > 0 {
+		if size > opt.FixedSize {
+			return fmt.Errorf("%d bytes overflow the fixed size of %d bytes", size, opt.FixedSize)
+		}
+		size = opt.FixedSize
+	}
+
+	buf := make([]byte, size)
+	copy(buf, data)
+	return e.WriteBytes(buf, false)
+}
+
+// readFixedString reads a string or byte slice with the
+// `fixed=N` and `cstring` tags of its field into rv.
+func (dec *Decoder) readFixedString(rv reflect.Value, opt *option) error {
+	if _, err := fixedStringBytes(rv); err != nil {
+		return err
+	}
+
+	var data []byte
+	if opt.FixedSize > 0 {
+		buf, err := dec.ReadNBytes(opt.FixedSize)
+		if err != nil {
+			return err
+		}
+		if opt.CString {
+			end := bytes.IndexByte(buf, 0)
+			if end < 0 {
+				return fmt.Errorf("cstring: missing NUL terminator in %d bytes", opt.FixedSize)
+			}
+			data = buf[:end]
+		} else {
+			data = bytes.TrimRight(buf, "\x00")
+		}
+	} else {
+		end := bytes.IndexByte(dec.data[dec.pos:], 0)
+		if end < 0 {
+			return fmt.Errorf("cstring: missing NUL terminator in %d bytes", dec.Remaining())
+		}
+		data = dec.data[dec.pos : dec.pos+end]
+		dec.pos += end + 1
+	}
+
+	if rv.Kind() == reflect.String {
+		rv.SetString(string(data))
+	} else if len(data) > 0 {
+		rv.SetBytes(append([]byte(nil), data...))
+	} else {
+		// Empty slices are left nil
+		rv.Set(reflect.Zero(rv.Type()))
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedStrings struct {
+	Name   string  `bin:"fixed=8"`
+	Symbol []byte  `bin:"fixed=4"`
+	Label  string  `bin:"cstring"`
+	Tag    string  `bin:"fixed=4 cstring"`
+	URI    *string `bin:"fixed=6"`
+	Empty  []byte  `bin:"fixed=2"`
+	Value  uint8
+}
+
+func TestFixedString(t *testing.T) {
+	uri := "ipfs"
+	value := fixedStrings{
+		Name:   "token",
+		Symbol: []byte{0xaa, 0xbb},
+		Label:  "hi",
+		Tag:    "abc",
+		URI:    &uri,
+		Value:  7,
+	}
+	expected := "746f6b656e000000" + "aabb0000" + "686900" + "61626300" + "697066730000" + "0000" + "07"
+
+	tests := []struct {
+		name      string
+		marshal   func(v interface{}) ([]byte, error)
+		unmarshal func(v interface{}, b []byte) error
+		byteCount func(v interface{}) (uint64, error)
+	}{
+		{"borsh", MarshalBorsh, UnmarshalBorsh, BorshByteCount},
+		{"bin", MarshalBin, UnmarshalBin, BinByteCount},
+		{"compact-u16", MarshalCompactU16, UnmarshalCompactU16, CompactU16ByteCount},
+		{"bincode", MarshalBincode, UnmarshalBincode, BincodeByteCount},
+		{"scale", MarshalSCALE, UnmarshalSCALE, SCALEByteCount},
+		{"bcs", MarshalBCS, UnmarshalBCS, BCSByteCount},
+		{"postcard", MarshalPostcard, UnmarshalPostcard, PostcardByteCount},
+		{"bitcoin", MarshalBitcoin, UnmarshalBitcoin, BitcoinByteCount},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := test.marshal(value)
+			require.NoError(t, err)
+			assert.Equal(t, expected, hex.EncodeToString(data))
+
+			count, err := test.byteCount(value)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(len(data)), count)
+
+			var got fixedStrings
+			require.NoError(t, test.unmarshal(&got, data))
+			assert.Equal(t, value, got)
+		})
+	}
+}
+
+func TestFixedString_Errors(t *testing.T) {
+	_, err := MarshalBorsh(struct {
+		Name string `bin:"fixed=4"`
+	}{Name: "tokens"})
+	require.EqualError(t, err, `error while encoding "Name" field: 6 bytes overflow the fixed size of 4 bytes`)
+
+	_, err = MarshalBorsh(struct {
+		Name string `bin:"fixed=4 cstring"`
+	}{Name: "toke"})
+	require.EqualError(t, err, `error while encoding "Name" field: 5 bytes overflow the fixed size of 4 bytes`)
+
+	_, err = MarshalBorsh(struct {
+		Name string `bin:"cstring"`
+	}{Name: "a\x00b"})
+	require.EqualError(t, err, `error while encoding "Name" field: cstring: "a\x00b" contains a NUL byte`)
+
+	_, err = MarshalBorsh(struct {
+		Count uint32 `bin:"fixed=4"`
+	}{})
+	require.EqualError(t, err, `error while encoding "Count" field: fixed and cstring tags require a string or byte slice, got uint32`)
+
+	err = UnmarshalBorsh(&struct {
+		Name string `bin:"cstring"`
+	}{}, []byte("abc"))
+	require.EqualError(t, err, `error while decoding "Name" field: cstring: missing NUL terminator in 3 bytes`)
+
+	err = UnmarshalBorsh(&struct {
+		Name string `bin:"fixed=4 cstring"`
+	}{}, []byte("abcd"))
+	require.EqualError(t, err, `error while decoding "Name" field: cstring: missing NUL terminator in 4 bytes`)
+
+	err = UnmarshalBorsh(&struct {
+		Name string `bin:"fixed=4"`
+	}{}, []byte("abc"))
+	require.Error(t, err)
+
+	_, err = MarshalXDR(struct {
+		Name string `bin:"fixed=4"`
+	}{Name: "a"})
+	require.EqualError(t, err, `error while encoding "Name" field: xdr: fixed and cstring tags are not supported`)
+
+	// The invalid and conflicting tags are rejected by all the encodings:
+	_, err = MarshalBorsh(struct {
+		Name string `bin:"fixed=four"`
+	}{})
+	require.EqualError(t, err, `field Name: invalid fixed=four: expected a positive integer`)
+
+	err = UnmarshalBincode(&struct {
+		Name string `bin:"fixed=0"`
+	}{}, []byte("abc"))
+	require.EqualError(t, err, `field Name: invalid fixed=0: expected a positive integer`)
+
+	_, err = MarshalXDR(struct {
+		Name []byte `bin:"fixed=4 xdr_size=4"`
+	}{})
+	require.EqualError(t, err, `field Name: fixed=4 conflicts with the size tag of the encoding (xdr_size= or ssz_size=)`)
+
+	_, err = MarshalSSZ(struct {
+		Name []byte `bin:"fixed=4 ssz_size=4"`
+	}{})
+	require.EqualError(t, err, `ssz: field Name: fixed=4 conflicts with the size tag of the encoding (xdr_size= or ssz_size=)`)
+}
@@ -0,0 +1,162 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Flags names the bits of a flags word, to encode it in JSON as the array
+// of the names of its set bits. The flags word is an unsigned integer type,
+// and so is encoded as such in every Encoding:
+//
+//	type AccountFlags uint8
+//
+//	var accountFlags = bin.NewFlags("Initialized", "Frozen", "Native")
+//
+//	func (f AccountFlags) MarshalJSON() ([]byte, error) {
+//		return accountFlags.MarshalJSONOf(f)
+//	}
+//
+//	func (f *AccountFlags) UnmarshalJSON(data []byte) error {
+//		return accountFlags.UnmarshalJSONOf(data, f)
+//	}
+//
+// The set bits without name are named "bit<N>" (e.g. "bit7").
+type Flags struct {
+	names  []string
+	byName map[string]uint
+}
+
+// NewFlags returns the flags whose i-th bit is named names[i]
+// (an empty name leaves the bit unnamed).
+// It panics if a name is used twice.
+func NewFlags(names ...string) *Flags {
+	if len(names) > 64 {
+		panic(fmt.Sprintf("bin: NewFlags: %d names for 64 bits", len(names)))
+	}
+	f := &Flags{names: names, byName: map[string]uint{}}
+	for bit, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, ok := f.byName[name]; ok {
+			panic(fmt.Sprintf("bin: NewFlags: flag %q defined twice", name))
+		}
+		f.byName[name] = uint(bit)
+	}
+	return f
+}
+
+func (f *Flags) name(bit uint) string {
+	if int(bit) < len(f.names) && f.names[bit] != "" {
+		return f.names[bit]
+	}
+	return "bit" + strconv.Itoa(int(bit))
+}
+
+// Names returns the names of the set bits of bits, from the least significant one.
+func (f *Flags) Names(bits uint64) []string {
+	out := []string{}
+	for bit := uint(0); bit < 64; bit++ {
+		if bits&(1<<bit) != 0 {
+			out = append(out, f.name(bit))
+		}
+	}
+	return out
+}
+
+// Bits returns the flags word with the bits of names set.
+func (f *Flags) Bits(names ...string) (uint64, error) {
+	var bits uint64
+	for _, name := range names {
+		bit, ok := f.byName[name]
+		if !ok {
+			n, err := strconv.ParseUint(strings.TrimPrefix(name, "bit"), 10, 8)
+			if !strings.HasPrefix(name, "bit") || err != nil || n >= 64 || f.name(uint(n)) != name {
+				return 0, fmt.Errorf("unknown flag %q", name)
+			}
+			bit = uint(n)
+		}
+		bits |= 1 << bit
+	}
+	return bits, nil
+}
+
+// Has returns whether the bit of name is set in bits.
+func (f *Flags) Has(bits uint64, name string) bool {
+	bit, ok := f.byName[name]
+	return ok && bits&(1<<bit) != 0
+}
+
+// String returns the names of the set bits of bits, separated by "|".
+func (f *Flags) String(bits uint64) string {
+	return strings.Join(f.Names(bits), "|")
+}
+
+// EncodeJSON encodes bits as the array of the names of its set bits.
+func (f *Flags) EncodeJSON(bits uint64) ([]byte, error) {
+	return json.Marshal(f.Names(bits))
+}
+
+// DecodeJSON decodes an array of flag names.
+func (f *Flags) DecodeJSON(data []byte) (uint64, error) {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return 0, err
+	}
+	return f.Bits(names...)
+}
+
+// MarshalJSONOf encodes the flags word as the array of the names of its set
+// bits, for the MarshalJSON method of its type.
+func (f *Flags) MarshalJSONOf(word interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(word)
+	switch rv.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return f.EncodeJSON(rv.Uint())
+	}
+	return nil, fmt.Errorf("flags word must be an unsigned integer, got %T", word)
+}
+
+// UnmarshalJSONOf decodes an array of flag names into the flags word
+// pointed to by word, for the UnmarshalJSON method of its type.
+// It fails when a flag does not fit in the word.
+func (f *Flags) UnmarshalJSONOf(data []byte, word interface{}) error {
+	rv := reflect.ValueOf(word)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("flags word must be a non-nil pointer, got %T", word)
+	}
+	rv = rv.Elem()
+	switch rv.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+	default:
+		return fmt.Errorf("flags word must be an unsigned integer, got %s", rv.Type())
+	}
+
+	bits, err := f.DecodeJSON(data)
+	if err != nil {
+		return err
+	}
+	if rv.OverflowUint(bits) {
+		return fmt.Errorf("flags %s overflow %s", f.String(bits), rv.Type())
+	}
+	rv.SetUint(bits)
+	return nil
+}
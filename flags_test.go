@@ -0,0 +1,105 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAccountFlags uint8
+
+var testAccountFlagNames = NewFlags("Initialized", "Frozen", "", "Native")
+
+func (f testAccountFlags) MarshalJSON() ([]byte, error) {
+	return testAccountFlagNames.MarshalJSONOf(f)
+}
+
+func (f *testAccountFlags) UnmarshalJSON(data []byte) error {
+	return testAccountFlagNames.UnmarshalJSONOf(data, f)
+}
+
+type testFlaggedAccount struct {
+	Flags  testAccountFlags
+	Amount uint64
+}
+
+func TestFlags(t *testing.T) {
+	flags := NewFlags("Initialized", "Frozen", "", "Native")
+	assert.Equal(t, []string{"Initialized", "Native"}, flags.Names(0b1001))
+	assert.Equal(t, []string{"Frozen", "bit2", "bit7"}, flags.Names(0b10000110))
+	assert.Equal(t, []string{}, flags.Names(0))
+	assert.Equal(t, "Initialized|Frozen", flags.String(0b11))
+	assert.True(t, flags.Has(0b1000, "Native"))
+	assert.False(t, flags.Has(0b1000, "Frozen"))
+	assert.False(t, flags.Has(0b1000, "Unknown"))
+
+	bits, err := flags.Bits("Native", "bit2", "Initialized")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0b1101), bits)
+
+	_, err = flags.Bits("Unknown")
+	require.EqualError(t, err, `unknown flag "Unknown"`)
+	_, err = flags.Bits("bit3")
+	require.EqualError(t, err, `unknown flag "bit3"`)
+
+	require.PanicsWithValue(t, `bin: NewFlags: flag "A" defined twice`, func() {
+		NewFlags("A", "A")
+	})
+}
+
+func TestFlags_Encodings(t *testing.T) {
+	account := testFlaggedAccount{Flags: 0b1001, Amount: 5}
+
+	data, err := json.Marshal(account)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Flags":["Initialized","Native"],"Amount":5}`, string(data))
+
+	var got testFlaggedAccount
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, account, got)
+
+	require.EqualError(t, json.Unmarshal([]byte(`{"Flags":["Unknown"]}`), &got), `unknown flag "Unknown"`)
+	require.EqualError(t,
+		json.Unmarshal([]byte(`{"Flags":["Frozen","bit8"]}`), &got),
+		"flags Frozen|bit8 overflow bin.testAccountFlags",
+	)
+
+	_, err = testAccountFlagNames.MarshalJSONOf(int8(1))
+	require.EqualError(t, err, "flags word must be an unsigned integer, got int8")
+	require.EqualError(t,
+		testAccountFlagNames.UnmarshalJSONOf([]byte(`[]`), new(string)),
+		"flags word must be an unsigned integer, got string",
+	)
+
+	for _, enc := range []Encoding{
+		EncodingBin, EncodingCompactU16, EncodingBorsh, EncodingBCS, EncodingSCALE,
+		EncodingBincode, EncodingBincodeVarint, EncodingEVMABI, EncodingRLP, EncodingSSZ,
+		EncodingXDR, EncodingBitcoin, EncodingPostcard, EncodingReprC,
+	} {
+		t.Run(enc.String(), func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			require.NoError(t, NewEncoderWithEncoding(buf, enc).Encode(account))
+
+			var got testFlaggedAccount
+			require.NoError(t, NewDecoderWithEncoding(buf.Bytes(), enc).Decode(&got))
+			assert.Equal(t, account, got)
+		})
+	}
+}
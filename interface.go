@@ -56,6 +56,76 @@ func MarshalCompactU16(v interface{}) ([]byte, error) {
 	return buf.Bytes(), err
 }
 
+func MarshalBCS(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := NewBCSEncoder(buf)
+	err := encoder.Encode(v)
+	return buf.Bytes(), err
+}
+
+func MarshalSCALE(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := NewSCALEEncoder(buf)
+	err := encoder.Encode(v)
+	return buf.Bytes(), err
+}
+
+func MarshalEVMABI(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := NewEVMABIEncoder(buf)
+	err := encoder.Encode(v)
+	return buf.Bytes(), err
+}
+
+func MarshalRLP(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := NewRLPEncoder(buf)
+	err := encoder.Encode(v)
+	return buf.Bytes(), err
+}
+
+func MarshalSSZ(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := NewSSZEncoder(buf)
+	err := encoder.Encode(v)
+	return buf.Bytes(), err
+}
+
+func MarshalXDR(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := NewXDREncoder(buf)
+	err := encoder.Encode(v)
+	return buf.Bytes(), err
+}
+
+func MarshalBitcoin(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := NewBitcoinEncoder(buf)
+	err := encoder.Encode(v)
+	return buf.Bytes(), err
+}
+
+func MarshalPostcard(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := NewPostcardEncoder(buf)
+	err := encoder.Encode(v)
+	return buf.Bytes(), err
+}
+
+func MarshalReprC(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := NewReprCEncoder(buf)
+	err := encoder.Encode(v)
+	return buf.Bytes(), err
+}
+
+func MarshalBincode(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := NewBincodeEncoder(buf)
+	err := encoder.Encode(v)
+	return buf.Bytes(), err
+}
+
 func UnmarshalBin(v interface{}, b []byte) error {
 	decoder := NewBinDecoder(b)
 	return decoder.Decode(v)
@@ -71,6 +141,56 @@ func UnmarshalCompactU16(v interface{}, b []byte) error {
 	return decoder.Decode(v)
 }
 
+func UnmarshalBCS(v interface{}, b []byte) error {
+	decoder := NewBCSDecoder(b)
+	return decoder.Decode(v)
+}
+
+func UnmarshalSCALE(v interface{}, b []byte) error {
+	decoder := NewSCALEDecoder(b)
+	return decoder.Decode(v)
+}
+
+func UnmarshalEVMABI(v interface{}, b []byte) error {
+	decoder := NewEVMABIDecoder(b)
+	return decoder.Decode(v)
+}
+
+func UnmarshalRLP(v interface{}, b []byte) error {
+	decoder := NewRLPDecoder(b)
+	return decoder.Decode(v)
+}
+
+func UnmarshalSSZ(v interface{}, b []byte) error {
+	decoder := NewSSZDecoder(b)
+	return decoder.Decode(v)
+}
+
+func UnmarshalXDR(v interface{}, b []byte) error {
+	decoder := NewXDRDecoder(b)
+	return decoder.Decode(v)
+}
+
+func UnmarshalBitcoin(v interface{}, b []byte) error {
+	decoder := NewBitcoinDecoder(b)
+	return decoder.Decode(v)
+}
+
+func UnmarshalPostcard(v interface{}, b []byte) error {
+	decoder := NewPostcardDecoder(b)
+	return decoder.Decode(v)
+}
+
+func UnmarshalReprC(v interface{}, b []byte) error {
+	decoder := NewReprCDecoder(b)
+	return decoder.Decode(v)
+}
+
+func UnmarshalBincode(v interface{}, b []byte) error {
+	decoder := NewBincodeDecoder(b)
+	return decoder.Decode(v)
+}
+
 type byteCounter struct {
 	count uint64
 }
@@ -116,6 +236,126 @@ func CompactU16ByteCount(v interface{}) (uint64, error) {
 	return counter.count, nil
 }
 
+// BCSByteCount computes the byte count size for the received populated structure. The reported size
+// is the one for the populated structure received in arguments. Depending on how serialization of
+// your fields is performed, size could vary for different structure.
+func BCSByteCount(v interface{}) (uint64, error) {
+	counter := byteCounter{}
+	err := NewBCSEncoder(&counter).Encode(v)
+	if err != nil {
+		return 0, fmt.Errorf("encode %T: %w", v, err)
+	}
+	return counter.count, nil
+}
+
+// SCALEByteCount computes the byte count size for the received populated structure. The reported size
+// is the one for the populated structure received in arguments. Depending on how serialization of
+// your fields is performed, size could vary for different structure.
+func SCALEByteCount(v interface{}) (uint64, error) {
+	counter := byteCounter{}
+	err := NewSCALEEncoder(&counter).Encode(v)
+	if err != nil {
+		return 0, fmt.Errorf("encode %T: %w", v, err)
+	}
+	return counter.count, nil
+}
+
+// BincodeByteCount computes the byte count size for the received populated structure. The reported size
+// is the one for the populated structure received in arguments. Depending on how serialization of
+// your fields is performed, size could vary for different structure.
+func BincodeByteCount(v interface{}) (uint64, error) {
+	counter := byteCounter{}
+	err := NewBincodeEncoder(&counter).Encode(v)
+	if err != nil {
+		return 0, fmt.Errorf("encode %T: %w", v, err)
+	}
+	return counter.count, nil
+}
+
+// EVMABIByteCount computes the byte count size for the received populated structure. The reported size
+// is the one for the populated structure received in arguments. Depending on how serialization of
+// your fields is performed, size could vary for different structure.
+func EVMABIByteCount(v interface{}) (uint64, error) {
+	counter := byteCounter{}
+	err := NewEVMABIEncoder(&counter).Encode(v)
+	if err != nil {
+		return 0, fmt.Errorf("encode %T: %w", v, err)
+	}
+	return counter.count, nil
+}
+
+// RLPByteCount computes the byte count size for the received populated structure. The reported size
+// is the one for the populated structure received in arguments. Depending on how serialization of
+// your fields is performed, size could vary for different structure.
+func RLPByteCount(v interface{}) (uint64, error) {
+	counter := byteCounter{}
+	err := NewRLPEncoder(&counter).Encode(v)
+	if err != nil {
+		return 0, fmt.Errorf("encode %T: %w", v, err)
+	}
+	return counter.count, nil
+}
+
+// SSZByteCount computes the byte count size for the received populated structure. The reported size
+// is the one for the populated structure received in arguments. Depending on how serialization of
+// your fields is performed, size could vary for different structure.
+func SSZByteCount(v interface{}) (uint64, error) {
+	counter := byteCounter{}
+	err := NewSSZEncoder(&counter).Encode(v)
+	if err != nil {
+		return 0, fmt.Errorf("encode %T: %w", v, err)
+	}
+	return counter.count, nil
+}
+
+// XDRByteCount computes the byte count size for the received populated structure. The reported size
+// is the one for the populated structure received in arguments. Depending on how serialization of
+// your fields is performed, size could vary for different structure.
+func XDRByteCount(v interface{}) (uint64, error) {
+	counter := byteCounter{}
+	err := NewXDREncoder(&counter).Encode(v)
+	if err != nil {
+		return 0, fmt.Errorf("encode %T: %w", v, err)
+	}
+	return counter.count, nil
+}
+
+// BitcoinByteCount computes the byte count size for the received populated structure. The reported size
+// is the one for the populated structure received in arguments. Depending on how serialization of
+// your fields is performed, size could vary for different structure.
+func BitcoinByteCount(v interface{}) (uint64, error) {
+	counter := byteCounter{}
+	err := NewBitcoinEncoder(&counter).Encode(v)
+	if err != nil {
+		return 0, fmt.Errorf("encode %T: %w", v, err)
+	}
+	return counter.count, nil
+}
+
+// PostcardByteCount computes the byte count size for the received populated structure. The reported size
+// is the one for the populated structure received in arguments. Depending on how serialization of
+// your fields is performed, size could vary for different structure.
+func PostcardByteCount(v interface{}) (uint64, error) {
+	counter := byteCounter{}
+	err := NewPostcardEncoder(&counter).Encode(v)
+	if err != nil {
+		return 0, fmt.Errorf("encode %T: %w", v, err)
+	}
+	return counter.count, nil
+}
+
+// ReprCByteCount computes the byte count size for the received populated structure. The reported size
+// is the one for the populated structure received in arguments. Depending on how serialization of
+// your fields is performed, size could vary for different structure.
+func ReprCByteCount(v interface{}) (uint64, error) {
+	counter := byteCounter{}
+	err := NewReprCEncoder(&counter).Encode(v)
+	if err != nil {
+		return 0, fmt.Errorf("encode %T: %w", v, err)
+	}
+	return counter.count, nil
+}
+
 // MustBinByteCount acts just like BinByteCount but panics if it encounters any encoding errors.
 func MustBinByteCount(v interface{}) uint64 {
 	count, err := BinByteCount(v)
@@ -142,3 +382,93 @@ func MustCompactU16ByteCount(v interface{}) uint64 {
 	}
 	return count
 }
+
+// MustBCSByteCount acts just like BCSByteCount but panics if it encounters any encoding errors.
+func MustBCSByteCount(v interface{}) uint64 {
+	count, err := BCSByteCount(v)
+	if err != nil {
+		panic(err)
+	}
+	return count
+}
+
+// MustSCALEByteCount acts just like SCALEByteCount but panics if it encounters any encoding errors.
+func MustSCALEByteCount(v interface{}) uint64 {
+	count, err := SCALEByteCount(v)
+	if err != nil {
+		panic(err)
+	}
+	return count
+}
+
+// MustBincodeByteCount acts just like BincodeByteCount but panics if it encounters any encoding errors.
+func MustBincodeByteCount(v interface{}) uint64 {
+	count, err := BincodeByteCount(v)
+	if err != nil {
+		panic(err)
+	}
+	return count
+}
+
+// MustEVMABIByteCount acts just like EVMABIByteCount but panics if it encounters any encoding errors.
+func MustEVMABIByteCount(v interface{}) uint64 {
+	count, err := EVMABIByteCount(v)
+	if err != nil {
+		panic(err)
+	}
+	return count
+}
+
+// MustRLPByteCount acts just like RLPByteCount but panics if it encounters any encoding errors.
+func MustRLPByteCount(v interface{}) uint64 {
+	count, err := RLPByteCount(v)
+	if err != nil {
+		panic(err)
+	}
+	return count
+}
+
+// MustSSZByteCount acts just like SSZByteCount but panics if it encounters any encoding errors.
+func MustSSZByteCount(v interface{}) uint64 {
+	count, err := SSZByteCount(v)
+	if err != nil {
+		panic(err)
+	}
+	return count
+}
+
+// MustXDRByteCount acts just like XDRByteCount but panics if it encounters any encoding errors.
+func MustXDRByteCount(v interface{}) uint64 {
+	count, err := XDRByteCount(v)
+	if err != nil {
+		panic(err)
+	}
+	return count
+}
+
+// MustBitcoinByteCount acts just like BitcoinByteCount but panics if it encounters any encoding errors.
+func MustBitcoinByteCount(v interface{}) uint64 {
+	count, err := BitcoinByteCount(v)
+	if err != nil {
+		panic(err)
+	}
+	return count
+}
+
+// MustPostcardByteCount acts just like PostcardByteCount but panics if it encounters any encoding errors.
+func MustPostcardByteCount(v interface{}) uint64 {
+	count, err := PostcardByteCount(v)
+	if err != nil {
+		panic(err)
+	}
+	return count
+}
+
+// MustReprCByteCount acts just like ReprCByteCount but panics if it encounters any encoding errors.
+func MustReprCByteCount(v interface{}) uint64 {
+	count, err := ReprCByteCount(v)
+	if err != nil {
+		panic(err)
+	}
+	return count
+}
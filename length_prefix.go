@@ -0,0 +1,158 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// The length prefixes of the `len=` tag, which override the length
+// prefix of the encoding for a slice, string or map field
+// (e.g. `bin:"len=u8"` for a borsh vec with a u8 length).
+// The fixed-width prefixes use the byte order of the field.
+const (
+	LengthPrefixU8       = "u8"
+	LengthPrefixU16      = "u16"
+	LengthPrefixU32      = "u32"
+	LengthPrefixU64      = "u64"
+	LengthPrefixUvarint  = "uvarint"
+	LengthPrefixShortVec = "shortvec" // compact-u16, the solana short_vec
+)
+
+func (o *option) hasLenPrefix() bool {
+	return o != nil && o.LenPrefix != ""
+}
+
+func (o *option) lenPrefixOrder() binary.ByteOrder {
+	if o.Order == nil {
+		return defaultByteOrder
+	}
+	return o.Order
+}
+
+// writeLengthPrefix writes the length of a slice, string or map,
+// with the prefix of the `len=` tag of the field, or else with WriteLength.
+func (e *Encoder) writeLengthPrefix(opt *option, length int) error {
+	if !opt.hasLenPrefix() {
+		return e.WriteLength(length)
+	}
+
+	var max uint64
+	switch opt.LenPrefix {
+	case LengthPrefixU8:
+		max = math.MaxUint8
+	case LengthPrefixU16, LengthPrefixShortVec:
+		max = math.MaxUint16
+	case LengthPrefixU32:
+		max = math.MaxUint32
+	case LengthPrefixU64, LengthPrefixUvarint:
+		max = math.MaxUint64
+	default:
+		return fmt.Errorf("unknown length prefix %q", opt.LenPrefix)
+	}
+	if uint64(length) > max {
+		return fmt.Errorf("length %d overflows the %s length prefix", length, opt.LenPrefix)
+	}
+
+	switch opt.LenPrefix {
+	case LengthPrefixU8:
+		return e.WriteUint8(uint8(length))
+	case LengthPrefixU16:
+		return e.WriteUint16(uint16(length), opt.lenPrefixOrder())
+	case LengthPrefixU32:
+		return e.WriteUint32(uint32(length), opt.lenPrefixOrder())
+	case LengthPrefixU64:
+		return e.WriteUint64(uint64(length), opt.lenPrefixOrder())
+	case LengthPrefixUvarint:
+		return e.WriteUVarInt(length)
+	default:
+		return e.WriteCompactU16Length(length)
+	}
+}
+
+// writeStringWithLengthPrefix writes a string or byte slice after its length,
+// like WriteString does with WriteLength.
+func (e *Encoder) writeStringWithLengthPrefix(opt *option, data []byte) error {
+	if err := e.writeLengthPrefix(opt, len(data)); err != nil {
+		return err
+	}
+	return e.WriteBytes(data, false)
+}
+
+// readLengthPrefix reads the length of a slice, string or map,
+// with the prefix of the `len=` tag of the field, or else with ReadLength.
+func (dec *Decoder) readLengthPrefix(opt *option) (int, error) {
+	if !opt.hasLenPrefix() {
+		return dec.ReadLength()
+	}
+
+	var length uint64
+	switch opt.LenPrefix {
+	case LengthPrefixU8:
+		n, err := dec.ReadUint8()
+		if err != nil {
+			return 0, err
+		}
+		length = uint64(n)
+	case LengthPrefixU16:
+		n, err := dec.ReadUint16(opt.lenPrefixOrder())
+		if err != nil {
+			return 0, err
+		}
+		length = uint64(n)
+	case LengthPrefixU32:
+		n, err := dec.ReadUint32(opt.lenPrefixOrder())
+		if err != nil {
+			return 0, err
+		}
+		length = uint64(n)
+	case LengthPrefixU64:
+		n, err := dec.ReadUint64(opt.lenPrefixOrder())
+		if err != nil {
+			return 0, err
+		}
+		length = n
+	case LengthPrefixUvarint:
+		n, err := dec.ReadUvarint64()
+		if err != nil {
+			return 0, err
+		}
+		length = n
+	case LengthPrefixShortVec:
+		n, err := dec.ReadCompactU16Length()
+		if err != nil {
+			return 0, err
+		}
+		length = uint64(n)
+	default:
+		return 0, fmt.Errorf("unknown length prefix %q", opt.LenPrefix)
+	}
+	if length > uint64(dec.Remaining()) {
+		return 0, fmt.Errorf("length %d exceeds the remaining %d bytes", length, dec.Remaining())
+	}
+	return int(length), nil
+}
+
+// readStringWithLengthPrefix reads a string or byte slice after its length,
+// like ReadByteSlice does with ReadLength.
+func (dec *Decoder) readStringWithLengthPrefix(opt *option) ([]byte, error) {
+	length, err := dec.readLengthPrefix(opt)
+	if err != nil {
+		return nil, err
+	}
+	return dec.ReadNBytes(length)
+}
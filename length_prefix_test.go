@@ -0,0 +1,109 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lengthPrefixed struct {
+	Default []uint8
+	U8      []uint8         `bin:"len=u8"`
+	U16     string          `bin:"len=u16 big"`
+	U32     []uint8         `bin:"len=u32"`
+	U64     string          `bin:"len=u64"`
+	Uvarint map[uint8]uint8 `bin:"len=uvarint"`
+	Short   []byte          `bin:"len=shortvec"`
+}
+
+func TestLengthPrefix(t *testing.T) {
+	value := lengthPrefixed{
+		Default: []uint8{1},
+		U8:      []uint8{2, 3},
+		U16:     "ab",
+		U32:     []uint8{4},
+		U64:     "c",
+		Uvarint: map[uint8]uint8{5: 6},
+		Short:   bytes.Repeat([]byte{7}, 200),
+	}
+	prefixed := "02" + "0203" + "00026162" + "01000000" + "04" + "0100000000000000" + "63" + "01" + "0506" +
+		"c801" + hex.EncodeToString(value.Short)
+
+	tests := []struct {
+		name          string
+		marshal       func(v interface{}) ([]byte, error)
+		unmarshal     func(v interface{}, b []byte) error
+		byteCount     func(v interface{}) (uint64, error)
+		defaultPrefix string
+	}{
+		{"borsh", MarshalBorsh, UnmarshalBorsh, BorshByteCount, "01000000"},
+		{"bin", MarshalBin, UnmarshalBin, BinByteCount, "01"},
+		{"compact-u16", MarshalCompactU16, UnmarshalCompactU16, CompactU16ByteCount, "01"},
+		{"bincode", MarshalBincode, UnmarshalBincode, BincodeByteCount, "0100000000000000"},
+		{"scale", MarshalSCALE, UnmarshalSCALE, SCALEByteCount, "04"},
+		{"bcs", MarshalBCS, UnmarshalBCS, BCSByteCount, "01"},
+		{"postcard", MarshalPostcard, UnmarshalPostcard, PostcardByteCount, "01"},
+		{"bitcoin", MarshalBitcoin, UnmarshalBitcoin, BitcoinByteCount, "01"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected := test.defaultPrefix + "01" + prefixed
+
+			data, err := test.marshal(value)
+			require.NoError(t, err)
+			assert.Equal(t, expected, hex.EncodeToString(data))
+
+			count, err := test.byteCount(value)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(len(data)), count)
+
+			var got lengthPrefixed
+			require.NoError(t, test.unmarshal(&got, data))
+			assert.Equal(t, value, got)
+		})
+	}
+}
+
+func TestLengthPrefix_Errors(t *testing.T) {
+	_, err := MarshalBorsh(struct {
+		A []byte `bin:"len=u8"`
+	}{A: make([]byte, 256)})
+	require.EqualError(t, err, `error while encoding "A" field: length 256 overflows the u8 length prefix`)
+
+	_, err = MarshalBorsh(struct {
+		A []byte `bin:"len=u128"`
+	}{A: []byte{1}})
+	require.EqualError(t, err, `error while encoding "A" field: unknown length prefix "u128"`)
+
+	err = UnmarshalBorsh(&struct {
+		A []byte `bin:"len=u16"`
+	}{}, []byte{0xff, 0xff, 1})
+	require.EqualError(t, err, `error while decoding "A" field: length 65535 exceeds the remaining 1 bytes`)
+
+	_, err = MarshalXDR(struct {
+		A []byte `bin:"len=u8"`
+	}{A: []byte{1}})
+	require.EqualError(t, err, `error while encoding "A" field: xdr: len tags are not supported, lengths are u32`)
+
+	_, err = MarshalRLP(struct {
+		A []byte `bin:"len=u8"`
+	}{A: []byte{1}})
+	require.Error(t, err)
+}
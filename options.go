@@ -23,6 +23,19 @@ type option struct {
 	OptionalField bool
 	SizeOfSlice   *int
 	Order         binary.ByteOrder
+
+	// FixedLength and MaxLength are the fixed length and the
+	// maximum length of the XDR opaque data, strings and arrays.
+	FixedLength int
+	MaxLength   int
+
+	// LenPrefix is the length prefix of the `len=` tag of the field.
+	LenPrefix string
+
+	// FixedSize and CString are the size and the NUL terminator
+	// of the `fixed=N` and `cstring` string fields.
+	FixedSize int
+	CString   bool
 }
 
 var LE binary.ByteOrder = binary.LittleEndian
@@ -42,6 +55,11 @@ func (o *option) clone() *option {
 		OptionalField: o.OptionalField,
 		SizeOfSlice:   o.SizeOfSlice,
 		Order:         o.Order,
+		FixedLength:   o.FixedLength,
+		MaxLength:     o.MaxLength,
+		LenPrefix:     o.LenPrefix,
+		FixedSize:     o.FixedSize,
+		CString:       o.CString,
 	}
 	return out
 }
@@ -73,6 +91,17 @@ const (
 	EncodingBin Encoding = iota
 	EncodingCompactU16
 	EncodingBorsh
+	EncodingBCS
+	EncodingSCALE
+	EncodingBincode
+	EncodingBincodeVarint
+	EncodingEVMABI
+	EncodingRLP
+	EncodingSSZ
+	EncodingXDR
+	EncodingBitcoin
+	EncodingPostcard
+	EncodingReprC
 )
 
 func (enc Encoding) String() string {
@@ -83,7 +112,32 @@ func (enc Encoding) String() string {
 		return "CompactU16"
 	case EncodingBorsh:
 		return "Borsh"
+	case EncodingBCS:
+		return "BCS"
+	case EncodingSCALE:
+		return "SCALE"
+	case EncodingBincode:
+		return "Bincode"
+	case EncodingBincodeVarint:
+		return "BincodeVarint"
+	case EncodingEVMABI:
+		return "EVMABI"
+	case EncodingRLP:
+		return "RLP"
+	case EncodingSSZ:
+		return "SSZ"
+	case EncodingXDR:
+		return "XDR"
+	case EncodingBitcoin:
+		return "Bitcoin"
+	case EncodingPostcard:
+		return "Postcard"
+	case EncodingReprC:
+		return "ReprC"
 	default:
+		if def, ok := registeredEncoding(enc); ok {
+			return def.Name()
+		}
 		return ""
 	}
 }
@@ -100,11 +154,54 @@ func (en Encoding) IsCompactU16() bool {
 	return en == EncodingCompactU16
 }
 
+func (en Encoding) IsBCS() bool {
+	return en == EncodingBCS
+}
+
+func (en Encoding) IsSCALE() bool {
+	return en == EncodingSCALE
+}
+
+// IsBincode returns true for both the fixed-int (default)
+// and the varint configurations of bincode.
+func (en Encoding) IsBincode() bool {
+	return en == EncodingBincode || en == EncodingBincodeVarint
+}
+
+func (en Encoding) IsEVMABI() bool {
+	return en == EncodingEVMABI
+}
+
+func (en Encoding) IsRLP() bool {
+	return en == EncodingRLP
+}
+
+func (en Encoding) IsSSZ() bool {
+	return en == EncodingSSZ
+}
+
+func (en Encoding) IsXDR() bool {
+	return en == EncodingXDR
+}
+
+func (en Encoding) IsBitcoin() bool {
+	return en == EncodingBitcoin
+}
+
+func (en Encoding) IsPostcard() bool {
+	return en == EncodingPostcard
+}
+
+func (en Encoding) IsReprC() bool {
+	return en == EncodingReprC
+}
+
 func isValidEncoding(enc Encoding) bool {
 	switch enc {
-	case EncodingBin, EncodingCompactU16, EncodingBorsh:
+	case EncodingBin, EncodingCompactU16, EncodingBorsh, EncodingBCS, EncodingSCALE, EncodingBincode, EncodingBincodeVarint, EncodingEVMABI, EncodingRLP, EncodingSSZ, EncodingXDR, EncodingBitcoin, EncodingPostcard, EncodingReprC:
 		return true
 	default:
-		return false
+		_, ok := registeredEncoding(enc)
+		return ok
 	}
 }
@@ -19,7 +19,9 @@ package bin
 
 import (
 	"encoding/binary"
+	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -29,8 +31,45 @@ type fieldTag struct {
 	Order           binary.ByteOrder
 	Optional        bool
 	BinaryExtension bool
+	Padded          bool
+
+	// Packed lays out the struct of the field without padding
+	// (`#[repr(C, packed)]`) with EncodingReprC.
+	Packed bool
+
+	// SSZSize and SSZMax are the lengths and limits of the SSZ vectors
+	// and lists, one per dimension (zero when not specified with "?").
+	SSZSize []int
+	SSZMax  []int
+
+	// XDRSize is the length of a fixed-length XDR opaque or array
+	// declared as a slice, and XDRMax the maximum length of a
+	// variable-length one (both zero when not specified).
+	XDRSize int
+	XDRMax  int
+
+	// LenPrefix overrides the length prefix of the encoding for
+	// a slice, string or map field (one of the LengthPrefix constants).
+	LenPrefix string
+
+	// Fixed is the zero-padded size of a string or byte slice field,
+	// and CString makes it NUL-terminated (see fixed_string.go).
+	Fixed   int
+	CString bool
+
+	// Bitfield marks the integer field that packs the following
+	// `bits=N` fields (with the first one in its "lsb" or "msb"),
+	// and Bits is the width of these fields.
+	Bitfield string
+	Bits     int
+
+	// If is the condition on a previous field under which the field is present.
+	If string
 
 	IsBorshEnum bool
+
+	// Err is the error of an invalid or conflicting tag.
+	Err error
 }
 
 func parseFieldTag(tag reflect.StructTag) *fieldTag {
@@ -50,11 +89,41 @@ func parseFieldTag(tag reflect.StructTag) *fieldTag {
 			t.Optional = true
 		} else if s == "binary_extension" {
 			t.BinaryExtension = true
+		} else if s == "padded" {
+			t.Padded = true
+		} else if s == "packed" {
+			t.Packed = true
+		} else if strings.HasPrefix(s, "len=") {
+			t.LenPrefix = strings.TrimPrefix(s, "len=")
+		} else if strings.HasPrefix(s, "fixed=") {
+			t.Fixed = t.parseSize(s)
+		} else if s == "cstring" {
+			t.CString = true
+		} else if s == "bitfield" {
+			t.Bitfield = bitfieldLSBFirst
+		} else if strings.HasPrefix(s, "bitfield=") {
+			t.Bitfield = strings.TrimPrefix(s, "bitfield=")
+		} else if strings.HasPrefix(s, "bits=") {
+			t.Bits = t.parseSize(s)
+		} else if strings.HasPrefix(s, "if=") {
+			t.If = strings.TrimPrefix(s, "if=")
+		} else if strings.HasPrefix(s, "ssz_size=") {
+			t.SSZSize = parseSSZDimensions(strings.TrimPrefix(s, "ssz_size="))
+		} else if strings.HasPrefix(s, "ssz_max=") {
+			t.SSZMax = parseSSZDimensions(strings.TrimPrefix(s, "ssz_max="))
+		} else if strings.HasPrefix(s, "xdr_size=") {
+			t.XDRSize = t.parseSize(s)
+		} else if strings.HasPrefix(s, "xdr_max=") {
+			t.XDRMax = t.parseSize(s)
 		} else if s == "-" {
 			t.Skip = true
 		}
 	}
 
+	if t.Fixed > 0 && (t.XDRSize > 0 || t.SSZSize != nil) && t.Err == nil {
+		t.Err = fmt.Errorf("fixed=%d conflicts with the size tag of the encoding (xdr_size= or ssz_size=)", t.Fixed)
+	}
+
 	// TODO: parse other borsh tags
 	if strings.TrimSpace(tag.Get("borsh_skip")) == "true" {
 		t.Skip = true
@@ -64,3 +133,40 @@ func parseFieldTag(tag reflect.StructTag) *fieldTag {
 	}
 	return t
 }
+
+// parseSize parses the positive size of a "name=N" tag,
+// recording the first invalid one in t.Err.
+func (t *fieldTag) parseSize(s string) int {
+	tmp := strings.SplitN(s, "=", 2)
+	n, err := strconv.Atoi(tmp[1])
+	if err != nil || n <= 0 {
+		if t.Err == nil {
+			t.Err = fmt.Errorf("invalid %s: expected a positive integer", s)
+		}
+		return 0
+	}
+	return n
+}
+
+// parseStructFieldTag parses the tag of a struct field, and returns
+// the error of an invalid tag along with the name of the field.
+func parseStructFieldTag(structField reflect.StructField) (*fieldTag, error) {
+	t := parseFieldTag(structField.Tag)
+	if t.Err != nil {
+		return nil, fmt.Errorf("field %s: %w", structField.Name, t.Err)
+	}
+	return t, nil
+}
+
+// parseSSZDimensions parses comma-separated lengths (e.g. "?,32"),
+// where "?" stands for a dimension without value.
+func parseSSZDimensions(s string) []int {
+	parts := strings.Split(s, ",")
+	out := make([]int, len(parts))
+	for i, part := range parts {
+		if n, err := strconv.Atoi(part); err == nil && n > 0 {
+			out[i] = n
+		}
+	}
+	return out
+}
@@ -68,6 +68,82 @@ func Test_parseFieldTag(t *testing.T) {
 				SizeOf:   "Nodes",
 			},
 		},
+		{
+			name: "with a padded binary extension",
+			tag:  `bin:"binary_extension padded"`,
+			expectValue: &fieldTag{
+				Order:           binary.LittleEndian,
+				BinaryExtension: true,
+				Padded:          true,
+			},
+		},
+		{
+			name: "with ssz lengths",
+			tag:  `bin:"ssz_size=?,32 ssz_max=1024"`,
+			expectValue: &fieldTag{
+				Order:   binary.LittleEndian,
+				SSZSize: []int{0, 32},
+				SSZMax:  []int{1024},
+			},
+		},
+		{
+			name: "with packed",
+			tag:  `bin:"packed"`,
+			expectValue: &fieldTag{
+				Order:  binary.LittleEndian,
+				Packed: true,
+			},
+		},
+		{
+			name: "with xdr lengths",
+			tag:  `bin:"xdr_size=32 xdr_max=64"`,
+			expectValue: &fieldTag{
+				Order:   binary.LittleEndian,
+				XDRSize: 32,
+				XDRMax:  64,
+			},
+		},
+		{
+			name: "with length prefix",
+			tag:  `bin:"len=shortvec"`,
+			expectValue: &fieldTag{
+				Order:     binary.LittleEndian,
+				LenPrefix: "shortvec",
+			},
+		},
+		{
+			name: "with fixed cstring",
+			tag:  `bin:"fixed=32 cstring"`,
+			expectValue: &fieldTag{
+				Order:   binary.LittleEndian,
+				Fixed:   32,
+				CString: true,
+			},
+		},
+		{
+			name: "with bitfield",
+			tag:  `bin:"bitfield=msb"`,
+			expectValue: &fieldTag{
+				Order:    binary.LittleEndian,
+				Bitfield: "msb",
+			},
+		},
+		{
+			name: "with bits",
+			tag:  `bin:"bits=3"`,
+			expectValue: &fieldTag{
+				Order: binary.LittleEndian,
+				Bits:  3,
+			},
+		},
+		{
+			name: "with condition",
+			tag:  `bin:"if=Flags&0x4"`,
+			expectValue: &fieldTag{
+				Order: binary.LittleEndian,
+				If:    "Flags&0x4",
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -77,3 +153,29 @@ func Test_parseFieldTag(t *testing.T) {
 	}
 
 }
+
+func Test_parseFieldTag_Errors(t *testing.T) {
+	tests := []struct {
+		tag         string
+		expectError string
+	}{
+		{`bin:"fixed=x"`, "invalid fixed=x: expected a positive integer"},
+		{`bin:"fixed=-1"`, "invalid fixed=-1: expected a positive integer"},
+		{`bin:"bits=0"`, "invalid bits=0: expected a positive integer"},
+		{`bin:"bits=1x"`, "invalid bits=1x: expected a positive integer"},
+		{`bin:"xdr_size="`, "invalid xdr_size=: expected a positive integer"},
+		{`bin:"xdr_max=big"`, "invalid xdr_max=big: expected a positive integer"},
+		{`bin:"fixed=8 xdr_size=8"`, "fixed=8 conflicts with the size tag of the encoding (xdr_size= or ssz_size=)"},
+		{`bin:"ssz_size=8 fixed=8"`, "fixed=8 conflicts with the size tag of the encoding (xdr_size= or ssz_size=)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.tag, func(t *testing.T) {
+			fieldTag := parseFieldTag(reflect.StructTag(test.tag))
+			assert.EqualError(t, fieldTag.Err, test.expectError)
+		})
+	}
+
+	_, err := parseStructFieldTag(reflect.StructField{Name: "Flags", Tag: `bin:"bits=two"`})
+	assert.EqualError(t, err, "field Flags: invalid bits=two: expected a positive integer")
+}
@@ -0,0 +1,239 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+// ErrPostcardBadVarint is returned when decoding a postcard varint
+// that is longer than its type allows, or overflows it.
+var ErrPostcardBadVarint = errors.New("postcard: bad varint")
+
+// WritePostcardVarint writes v as an unsigned LEB128 varint,
+// as postcard does for the integers wider than 8 bits and the lengths.
+func (e *Encoder) WritePostcardVarint(v Uint128) (err error) {
+	if traceEnabled {
+		zlog.Debug("encode: write postcard varint", zap.Stringer("val", v))
+	}
+	lo, hi := v.Lo, v.Hi
+	buf := make([]byte, 0, 19)
+	for hi != 0 || lo >= 0x80 {
+		buf = append(buf, byte(lo)|0x80)
+		lo = lo>>7 | hi<<57
+		hi >>= 7
+	}
+	buf = append(buf, byte(lo))
+	return e.toWriter(buf)
+}
+
+// ReadPostcardVarint reads an unsigned LEB128 varint of an integer of the given
+// number of bits, which can't be encoded on more than ceil(bits/7) bytes.
+func (dec *Decoder) ReadPostcardVarint(bits uint) (out Uint128, err error) {
+	maxLength := int(bits+6) / 7
+	for i := 0; i < maxLength; i++ {
+		b, err := dec.ReadByte()
+		if err != nil {
+			return out, fmt.Errorf("postcard: varint: %w", err)
+		}
+		shift := uint(i) * 7
+		v := uint64(b & 0x7f)
+		if shift+7 > bits && v>>(bits-shift) != 0 {
+			return out, ErrPostcardBadVarint
+		}
+		if shift < 64 {
+			out.Lo |= v << shift
+			if shift > 57 {
+				out.Hi |= v >> (64 - shift)
+			}
+		} else {
+			out.Hi |= v << (shift - 64)
+		}
+		if b&0x80 == 0 {
+			if traceEnabled {
+				zlog.Debug("decode: read postcard varint", zap.Stringer("val", out))
+			}
+			return out, nil
+		}
+	}
+	return out, ErrPostcardBadVarint
+}
+
+// postcardZigZag maps the signed integers to unsigned ones,
+// so that those of small magnitude have short varints.
+func postcardZigZag(v Uint128) Uint128 {
+	negative := v.Hi>>63 == 1
+	v.Hi = v.Hi<<1 | v.Lo>>63
+	v.Lo <<= 1
+	if negative {
+		v.Hi, v.Lo = ^v.Hi, ^v.Lo
+	}
+	return v
+}
+
+func postcardUnZigZag(v Uint128) Uint128 {
+	negative := v.Lo&1 == 1
+	v.Lo = v.Lo>>1 | v.Hi<<63
+	v.Hi >>= 1
+	if negative {
+		v.Hi, v.Lo = ^v.Hi, ^v.Lo
+	}
+	return v
+}
+
+// postcardInt returns the 128 bits two's complement of n.
+func postcardInt(n int64) Uint128 {
+	v := Uint128{Lo: uint64(n)}
+	if n < 0 {
+		v.Hi = ^uint64(0)
+	}
+	return v
+}
+
+// postcardEncoding is the definition of postcard: varint integers (zigzag
+// for the signed ones), lengths and enum tags, and little-endian floats.
+type postcardEncoding struct{ noEncodingRules }
+
+func (postcardEncoding) Name() string                { return EncodingPostcard.String() }
+func (postcardEncoding) ByteOrder() binary.ByteOrder { return binary.LittleEndian }
+
+func (postcardEncoding) WriteLength(enc *Encoder, length int) error { return enc.WriteLength(length) }
+func (postcardEncoding) ReadLength(dec *Decoder) (int, error)       { return dec.ReadLength() }
+
+func (postcardEncoding) WriteOptionTag(enc *Encoder, isPresent bool) error {
+	return enc.WriteBool(isPresent)
+}
+func (postcardEncoding) ReadOptionTag(dec *Decoder) (bool, error) { return dec.ReadBool() }
+
+func (postcardEncoding) WriteEnumTag(enc *Encoder, index uint32) error {
+	return enc.WritePostcardVarint(Uint128{Lo: uint64(index)})
+}
+
+func (postcardEncoding) ReadEnumTag(dec *Decoder) (uint32, error) {
+	tag, err := dec.ReadPostcardVarint(32)
+	return uint32(tag.Lo), err
+}
+
+func (postcardEncoding) WriteString(enc *Encoder, s string) error { return enc.WriteString(s) }
+func (postcardEncoding) ReadString(dec *Decoder) (string, error) {
+	return dec.readUTF8String("postcard", nil)
+}
+
+func (postcardEncoding) WriteBool(enc *Encoder, b bool) error { return enc.WriteBool(b) }
+
+func (postcardEncoding) ReadBool(dec *Decoder) (bool, error) {
+	b, err := dec.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	if b > 1 {
+		return false, fmt.Errorf("postcard: invalid bool value %d", b)
+	}
+	return b == 1, nil
+}
+
+func (postcardEncoding) SortMapKeys(keys []reflect.Value) { SortMapKeysByValue(keys) }
+
+// encodeValue encodes the primitive types by kind, whatever their marshaler.
+func (def postcardEncoding) encodeValue(enc *Encoder, rv reflect.Value, opt *option) (bool, error) {
+	if iv := reflect.Indirect(rv); iv.IsValid() {
+		switch v := iv.Interface().(type) {
+		case Uint128:
+			return true, enc.WritePostcardVarint(v)
+		case Int128:
+			return true, enc.WritePostcardVarint(postcardZigZag(Uint128(v)))
+		case Float128:
+			return true, fmt.Errorf("encode: postcard does not support type %q", iv.Type())
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return true, enc.writeStringWithLengthPrefix(opt, []byte(rv.String()))
+	case reflect.Uint8:
+		return true, enc.WriteByte(byte(rv.Uint()))
+	case reflect.Int8:
+		return true, enc.WriteByte(byte(rv.Int()))
+	case reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return true, enc.WritePostcardVarint(Uint128{Lo: rv.Uint()})
+	case reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		return true, enc.WritePostcardVarint(postcardZigZag(postcardInt(rv.Int())))
+	case reflect.Float32:
+		return true, enc.WriteFloat32(float32(rv.Float()), LE)
+	case reflect.Float64:
+		return true, enc.WriteFloat64(rv.Float(), LE)
+	case reflect.Bool:
+		return true, def.WriteBool(enc, rv.Bool())
+	}
+	return false, nil
+}
+
+// decodeValue decodes the primitive types by kind, whatever their unmarshaler.
+func (def postcardEncoding) decodeValue(dec *Decoder, rv reflect.Value, opt *option, hasUnmarshaler bool) (bool, error) {
+	rt := rv.Type()
+	switch rt {
+	case uint128Type:
+		v, err := dec.ReadPostcardVarint(128)
+		rv.Set(reflect.ValueOf(v))
+		return true, err
+	case int128Type:
+		v, err := dec.ReadPostcardVarint(128)
+		rv.Set(reflect.ValueOf(Int128(postcardUnZigZag(v))))
+		return true, err
+	case float128Type:
+		return true, fmt.Errorf("decode: postcard does not support type %q", rt)
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		s, err := dec.readUTF8String("postcard", opt)
+		rv.SetString(s)
+		return true, err
+	case reflect.Uint8:
+		n, err := dec.ReadByte()
+		rv.SetUint(uint64(n))
+		return true, err
+	case reflect.Int8:
+		n, err := dec.ReadInt8()
+		rv.SetInt(int64(n))
+		return true, err
+	case reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		v, err := dec.ReadPostcardVarint(uint(rt.Bits()))
+		rv.SetUint(v.Lo)
+		return true, err
+	case reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		v, err := dec.ReadPostcardVarint(uint(rt.Bits()))
+		rv.SetInt(int64(postcardUnZigZag(v).Lo))
+		return true, err
+	case reflect.Float32:
+		f, err := dec.ReadFloat32(LE)
+		rv.SetFloat(float64(f))
+		return true, err
+	case reflect.Float64:
+		f, err := dec.ReadFloat64(LE)
+		rv.SetFloat(f)
+		return true, err
+	case reflect.Bool:
+		b, err := def.ReadBool(dec)
+		rv.SetBool(b)
+		return true, err
+	}
+	return false, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type postcardReading struct {
+	Enum  BorshEnum `borsh_enum:"true"`
+	Idle  struct{}
+	Temp  int16
+	Level struct {
+		Channel uint8
+		Value   uint32
+	}
+}
+
+type postcardTelemetry struct {
+	DeviceID uint64
+	Uptime   Uint64
+	Name     string
+	Battery  *uint16 `bin:"optional"`
+	Readings []postcardReading
+	Scale    float32
+	Serial   [4]byte
+	Counters map[uint8]int64
+}
+
+func TestPostcard_Primitives(t *testing.T) {
+	// Vectors of the postcard wire format specification.
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"bool false", false, "00"},
+		{"bool true", true, "01"},
+		{"u8", uint8(5), "05"},
+		{"i8", int8(-1), "ff"},
+		{"u16 0", uint16(0), "00"},
+		{"u16 127", uint16(127), "7f"},
+		{"u16 128", uint16(128), "8001"},
+		{"u16 16383", uint16(16383), "ff7f"},
+		{"u16 16384", uint16(16384), "808001"},
+		{"u16 max", uint16(math.MaxUint16), "ffff03"},
+		{"i16 0", int16(0), "00"},
+		{"i16 -1", int16(-1), "01"},
+		{"i16 1", int16(1), "02"},
+		{"i16 63", int16(63), "7e"},
+		{"i16 -64", int16(-64), "7f"},
+		{"i16 64", int16(64), "8001"},
+		{"i16 -65", int16(-65), "8101"},
+		{"i16 min", int16(math.MinInt16), "ffff03"},
+		{"u32 max", uint32(math.MaxUint32), "ffffffff0f"},
+		{"u64 max", uint64(math.MaxUint64), "ffffffffffffffffff01"},
+		{"i64 min", int64(math.MinInt64), "ffffffffffffffffff01"},
+		{"u128 max", Uint128{Lo: math.MaxUint64, Hi: math.MaxUint64}, strings.Repeat("ff", 18) + "03"},
+		{"u128 2^64", Uint128{Hi: 1}, "80808080808080808002"},
+		{"i128 -1", Int128{Lo: math.MaxUint64, Hi: math.MaxUint64}, "01"},
+		{"i128 min", Int128{Hi: 1 << 63}, strings.Repeat("ff", 18) + "03"},
+		{"f32", float32(-32.005859375), "000600c2"},
+		{"string", "hi", "026869"},
+		{"seq", []uint8{1, 2, 3}, "03010203"},
+		{"unit variant", postcardReading{Enum: 0}, "00"},
+		{"newtype variant", postcardReading{Enum: 1, Temp: -2}, "0103"},
+		{"struct variant", postcardReading{Enum: 2, Level: struct {
+			Channel uint8
+			Value   uint32
+		}{Channel: 7, Value: 300}}, "0207ac02"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := MarshalPostcard(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, hex.EncodeToString(data))
+
+			got := reflect.New(reflect.TypeOf(test.value))
+			require.NoError(t, UnmarshalPostcard(got.Interface(), data))
+			assert.Equal(t, test.value, got.Elem().Interface())
+		})
+	}
+}
+
+func TestPostcard_Struct(t *testing.T) {
+	battery := uint16(3300)
+	telemetry := postcardTelemetry{
+		DeviceID: 1,
+		Uptime:   86400,
+		Name:     "probe",
+		Battery:  &battery,
+		Readings: []postcardReading{{Enum: 1, Temp: 21}, {Enum: 0}},
+		Scale:    1,
+		Serial:   [4]byte{0xde, 0xad, 0xbe, 0xef},
+		Counters: map[uint8]int64{2: -3, 1: 200},
+	}
+	expected := "01" + "80a305" + "0570726f6265" + "01e419" +
+		"02" + "012a" + "00" +
+		"0000803f" + "deadbeef" +
+		"02" + "019003" + "0205"
+
+	data, err := MarshalPostcard(telemetry)
+	require.NoError(t, err)
+	assert.Equal(t, expected, hex.EncodeToString(data))
+
+	var got postcardTelemetry
+	require.NoError(t, UnmarshalPostcard(&got, data))
+	assert.Equal(t, telemetry, got)
+
+	{
+		telemetry.Battery = nil
+		data, err := MarshalPostcard(telemetry)
+		require.NoError(t, err)
+
+		var got postcardTelemetry
+		require.NoError(t, UnmarshalPostcard(&got, data))
+		assert.Equal(t, telemetry, got)
+	}
+}
+
+func TestPostcard_DecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		target interface{}
+	}{
+		{"u16 overflow", "ffff04", new(uint16)},
+		{"u32 varint too long", "808080808000", new(uint32)},
+		{"u64 overflow", "ffffffffffffffffff02", new(uint64)},
+		{"truncated varint", "ff", new(uint32)},
+		{"invalid bool", "02", new(bool)},
+		{"invalid utf-8", "01ff", new(string)},
+		{"length over the remaining bytes", "0501", new([]byte)},
+		{"enum discriminant", "03", new(postcardReading)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Error(t, UnmarshalPostcard(test.target, mustHex(test.data)))
+		})
+	}
+
+	_, err := NewPostcardDecoder(mustHex("ffff04")).ReadPostcardVarint(16)
+	require.Equal(t, ErrPostcardBadVarint, err)
+}
@@ -0,0 +1,272 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// reprCType is the memory layout of a type with `#[repr(C)]`:
+// the fields are naturally aligned, with padding between them
+// and at the end of the struct. The layouts are the ones of the
+// Solana SBF target, where the 128-bit integers are 8-aligned.
+type reprCType struct {
+	rt     reflect.Type
+	size   int
+	align  int
+	order  binary.ByteOrder
+	elem   *reprCType     // of the arrays
+	fields []reprCField   // of the structs
+	names  map[string]int // indexes of the fields, by name
+}
+
+type reprCField struct {
+	index  int
+	offset int
+	typ    *reprCType
+}
+
+// ReprCSizeOf returns the size of the `#[repr(C)]` layout of the type of v
+// (e.g. the size of an anchor zero_copy account, without its discriminator).
+func ReprCSizeOf(v interface{}) (int, error) {
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt == nil {
+		return 0, fmt.Errorf("reprc: unsupported value %v", v)
+	}
+	t, err := reprCTypeOf(rt, false, defaultByteOrder)
+	if err != nil {
+		return 0, err
+	}
+	return t.size, nil
+}
+
+// reprCIsPacked tells whether the struct is marked with `#[repr(C, packed)]`,
+// by a `_` field with the `packed` tag (e.g. "_ struct{} `bin:\"packed\"`").
+func reprCIsPacked(rt reflect.Type) bool {
+	for i := 0; i < rt.NumField(); i++ {
+		structField := rt.Field(i)
+		if structField.Name == "_" && parseFieldTag(structField.Tag).Packed {
+			return true
+		}
+	}
+	return false
+}
+
+// reprCTypeOf returns the layout of rt; packed applies to the struct rt,
+// or to the structs of the array rt.
+func reprCTypeOf(rt reflect.Type, packed bool, order binary.ByteOrder) (*reprCType, error) {
+	t := &reprCType{rt: rt, order: order}
+	switch rt {
+	case uint128Type, int128Type, float128Type:
+		t.size, t.align = TypeSize.Uint128, 8
+		return t, nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool, reflect.Uint8, reflect.Int8:
+		t.size = TypeSize.Uint8
+	case reflect.Uint16, reflect.Int16:
+		t.size = TypeSize.Uint16
+	case reflect.Uint32, reflect.Int32, reflect.Float32:
+		t.size = TypeSize.Uint32
+	case reflect.Uint64, reflect.Int64, reflect.Float64, reflect.Uint, reflect.Int:
+		t.size = TypeSize.Uint64
+	case reflect.Array:
+		elem, err := reprCTypeOf(rt.Elem(), packed, order)
+		if err != nil {
+			return nil, err
+		}
+		t.elem = elem
+		t.size, t.align = elem.size*rt.Len(), elem.align
+		return t, nil
+	case reflect.Struct:
+		if isComplexEnumType(rt) {
+			return nil, fmt.Errorf("reprc: unsupported enum type %q", rt)
+		}
+		return t, t.layoutStruct(packed || reprCIsPacked(rt))
+	default:
+		return nil, fmt.Errorf("reprc: unsupported type %q", rt)
+	}
+	t.align = t.size
+	return t, nil
+}
+
+func (t *reprCType) layoutStruct(packed bool) error {
+	t.align = 1
+	for i := 0; i < t.rt.NumField(); i++ {
+		structField := t.rt.Field(i)
+		fieldTag, err := parseStructFieldTag(structField)
+		if err != nil {
+			return err
+		}
+		if fieldTag.Skip || structField.PkgPath != "" {
+			// Neither the skipped nor the unexported fields (including the
+			// packed marker) are part of the layout.
+			continue
+		}
+		if fieldTag.Bitfield != "" || fieldTag.Bits > 0 {
+			return fmt.Errorf("field %s: reprc: bitfields are not supported", structField.Name)
+		}
+		if fieldTag.If != "" {
+			return fmt.Errorf("field %s: reprc: conditional fields are not supported", structField.Name)
+		}
+		field, err := reprCTypeOf(structField.Type, fieldTag.Packed, fieldTag.Order)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", structField.Name, err)
+		}
+		if !packed {
+			t.size = reprCAlign(t.size, field.align)
+			if field.align > t.align {
+				t.align = field.align
+			}
+		}
+		if t.names == nil {
+			t.names = map[string]int{}
+		}
+		t.names[structField.Name] = len(t.fields)
+		t.fields = append(t.fields, reprCField{index: i, offset: t.size, typ: field})
+		t.size += field.size
+	}
+	// The trailing padding makes the size a multiple of the alignment,
+	// so that the structs of an array are all aligned:
+	t.size = reprCAlign(t.size, t.align)
+	return nil
+}
+
+func reprCAlign(offset, align int) int {
+	return (offset + align - 1) / align * align
+}
+
+// reprCPut writes rv at the start of buf, whose padding is left zeroed.
+func reprCPut(buf []byte, rv reflect.Value, t *reprCType) {
+	switch t.rt {
+	case uint128Type, int128Type, float128Type:
+		v := rv.Convert(uint128Type).Interface().(Uint128)
+		lo, hi := buf[:8], buf[8:16]
+		if t.order == binary.BigEndian {
+			lo, hi = hi, lo
+		}
+		t.order.PutUint64(lo, v.Lo)
+		t.order.PutUint64(hi, v.Hi)
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			buf[0] = 1
+		}
+	case reflect.Uint8:
+		buf[0] = uint8(rv.Uint())
+	case reflect.Int8:
+		buf[0] = uint8(rv.Int())
+	case reflect.Uint16:
+		t.order.PutUint16(buf, uint16(rv.Uint()))
+	case reflect.Int16:
+		t.order.PutUint16(buf, uint16(rv.Int()))
+	case reflect.Uint32:
+		t.order.PutUint32(buf, uint32(rv.Uint()))
+	case reflect.Int32:
+		t.order.PutUint32(buf, uint32(rv.Int()))
+	case reflect.Uint64, reflect.Uint:
+		t.order.PutUint64(buf, rv.Uint())
+	case reflect.Int64, reflect.Int:
+		t.order.PutUint64(buf, uint64(rv.Int()))
+	case reflect.Float32:
+		t.order.PutUint32(buf, math.Float32bits(float32(rv.Float())))
+	case reflect.Float64:
+		t.order.PutUint64(buf, math.Float64bits(rv.Float()))
+	case reflect.Array:
+		if t.elem.rt == sszByteType {
+			reflect.Copy(reflect.ValueOf(buf[:t.size]), rv)
+			return
+		}
+		for i := 0; i < rv.Len(); i++ {
+			reprCPut(buf[i*t.elem.size:], rv.Index(i), t.elem)
+		}
+	case reflect.Struct:
+		for _, field := range t.fields {
+			reprCPut(buf[field.offset:], rv.Field(field.index), field.typ)
+		}
+	}
+}
+
+// reprCGet reads rv from the start of data, ignoring the padding.
+func reprCGet(data []byte, rv reflect.Value, t *reprCType) error {
+	if len(data) < t.size {
+		return fmt.Errorf("reprc: %s needs %d bytes, got %d", t.rt, t.size, len(data))
+	}
+	switch t.rt {
+	case uint128Type, int128Type, float128Type:
+		lo, hi := data[:8], data[8:16]
+		if t.order == binary.BigEndian {
+			lo, hi = hi, lo
+		}
+		v := Uint128{Lo: t.order.Uint64(lo), Hi: t.order.Uint64(hi)}
+		rv.Set(reflect.ValueOf(v).Convert(t.rt))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		if data[0] > 1 {
+			return fmt.Errorf("reprc: invalid bool value %d", data[0])
+		}
+		rv.SetBool(data[0] == 1)
+	case reflect.Uint8:
+		rv.SetUint(uint64(data[0]))
+	case reflect.Int8:
+		rv.SetInt(int64(int8(data[0])))
+	case reflect.Uint16:
+		rv.SetUint(uint64(t.order.Uint16(data)))
+	case reflect.Int16:
+		rv.SetInt(int64(int16(t.order.Uint16(data))))
+	case reflect.Uint32:
+		rv.SetUint(uint64(t.order.Uint32(data)))
+	case reflect.Int32:
+		rv.SetInt(int64(int32(t.order.Uint32(data))))
+	case reflect.Uint64, reflect.Uint:
+		rv.SetUint(t.order.Uint64(data))
+	case reflect.Int64, reflect.Int:
+		rv.SetInt(int64(t.order.Uint64(data)))
+	case reflect.Float32:
+		rv.SetFloat(float64(math.Float32frombits(t.order.Uint32(data))))
+	case reflect.Float64:
+		rv.SetFloat(math.Float64frombits(t.order.Uint64(data)))
+	case reflect.Array:
+		if t.elem.rt == sszByteType {
+			reflect.Copy(rv, reflect.ValueOf(data[:t.size]))
+			return nil
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := reprCGet(data[i*t.elem.size:], rv.Index(i), t.elem); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for _, field := range t.fields {
+			if err := reprCGet(data[field.offset:], rv.Field(field.index), field.typ); err != nil {
+				return fmt.Errorf("field %s: %w", t.rt.Field(field.index).Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reprCMixed struct {
+	A uint8
+	B uint64
+	C uint16
+}
+
+type reprCPackedMixed struct {
+	_ struct{} `bin:"packed"`
+	A uint8
+	B uint64
+	C uint16
+}
+
+type reprCNested struct {
+	A     uint8
+	Inner reprCMixed
+}
+
+type reprCNestedPacked struct {
+	A     uint8
+	Inner reprCMixed `bin:"packed"`
+}
+
+// Accounts in the style of the anchor zero-copy example program.
+type reprCFoo struct {
+	Authority       [32]byte
+	Data            uint64
+	SecondData      uint64
+	SecondAuthority [32]byte
+}
+
+type reprCEvent struct {
+	From [32]byte
+	Data uint64
+}
+
+type reprCEventQ struct {
+	Head   uint64
+	Events [25000]reprCEvent
+}
+
+func TestReprC_Layout(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		size  int
+	}{
+		{"padding between and after the fields", reprCMixed{}, 24},
+		{"packed", reprCPackedMixed{}, 11},
+		{"nested", reprCNested{}, 32},
+		{"nested packed", reprCNestedPacked{}, 12},
+		{"u128 is 8-aligned", struct {
+			A uint8
+			B Uint128
+		}{}, 24},
+		{"array", struct {
+			A [3]uint16
+			B uint8
+		}{}, 8},
+		{"array of structs", [2]reprCMixed{}, 48},
+		{"float32 and bool", struct {
+			A bool
+			B float32
+			C bool
+		}{}, 12},
+		{"zero_copy account", reprCFoo{}, 80},
+		{"zero_copy event queue", &reprCEventQ{}, 8 + 25000*40},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			size, err := ReprCSizeOf(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.size, size)
+
+			count, err := ReprCByteCount(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(test.size), count)
+		})
+	}
+
+	_, err := ReprCSizeOf(struct{ A []byte }{})
+	require.EqualError(t, err, `field A: reprc: unsupported type "[]uint8"`)
+}
+
+func TestReprC_Serialization(t *testing.T) {
+	value := reprCNested{A: 0xff, Inner: reprCMixed{A: 1, B: 2, C: 3}}
+	expected := "ff00000000000000" + "01000000000000000200000000000000" + "0300000000000000"
+
+	data, err := MarshalReprC(value)
+	require.NoError(t, err)
+	assert.Equal(t, expected, hex.EncodeToString(data))
+
+	var got reprCNested
+	require.NoError(t, UnmarshalReprC(&got, data))
+	assert.Equal(t, value, got)
+
+	{
+		// The padding bytes are ignored:
+		var got reprCNested
+		require.NoError(t, UnmarshalReprC(&got, mustHex("ffaaaaaaaaaaaaaa"+"01bbbbbbbbbbbbbb0200000000000000"+"0300cccccccccccc")))
+		assert.Equal(t, value, got)
+	}
+	{
+		packed := reprCNestedPacked{A: 0xff, Inner: reprCMixed{A: 1, B: 2, C: 3}}
+		data, err := MarshalReprC(packed)
+		require.NoError(t, err)
+		assert.Equal(t, "ff"+"01"+"0200000000000000"+"0300", hex.EncodeToString(data))
+
+		var got reprCNestedPacked
+		require.NoError(t, UnmarshalReprC(&got, data))
+		assert.Equal(t, packed, got)
+	}
+	{
+		value := struct {
+			A int8
+			B Int128
+			C float64
+		}{A: -1, B: Int128{Lo: 1, Hi: 2}, C: 1}
+		data, err := MarshalReprC(value)
+		require.NoError(t, err)
+		assert.Equal(t, "ff"+"00000000000000"+"01000000000000000200000000000000"+"000000000000f03f", hex.EncodeToString(data))
+	}
+
+	require.Error(t, UnmarshalReprC(new(bool), []byte{2}))
+	require.Error(t, UnmarshalReprC(new(reprCMixed), make([]byte, 23)))
+
+	mixed, err := reprCTypeOf(reflect.TypeOf(reprCMixed{}), false, LE)
+	require.NoError(t, err)
+	require.EqualError(t,
+		reprCGet(make([]byte, 23), reflect.New(mixed.rt).Elem(), mixed),
+		"reprc: bin.reprCMixed needs 24 bytes, got 23",
+	)
+}
@@ -0,0 +1,197 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+// RLPRaw is an already RLP-encoded item (e.g. an embedded trie node),
+// which is written as-is and decoded without interpretation.
+type RLPRaw []byte
+
+var (
+	rlpRawType   = reflect.TypeOf(RLPRaw{})
+	bigIntType   = reflect.TypeOf(big.Int{})
+	float128Type = reflect.TypeOf(Float128{})
+)
+
+var (
+	ErrRLPNonCanonicalSize    = errors.New("non-canonical size information")
+	ErrRLPNonCanonicalInteger = errors.New("non-canonical integer (leading zero bytes)")
+	ErrRLPExpectedString      = errors.New("expected string or byte")
+	ErrRLPExpectedList        = errors.New("expected list")
+	ErrRLPValueTooLarge       = errors.New("value size exceeds available input length")
+)
+
+// RLPError is the error of the encoding or decoding of an RLP value;
+// Path is the location of the value in the root type (e.g. "Tx.AccessList[1].Address").
+type RLPError struct {
+	Path string
+	Err  error
+}
+
+func (e *RLPError) Error() string {
+	if e.Path == "" {
+		return "rlp: " + e.Err.Error()
+	}
+	return fmt.Sprintf("rlp: %s: %s", e.Path, e.Err)
+}
+
+func (e *RLPError) Unwrap() error {
+	return e.Err
+}
+
+// rlpWrapError prepends the path segment (e.g. ".Field" or "[2]") to the path of err.
+func rlpWrapError(err error, segment string) error {
+	if rlpErr, ok := err.(*RLPError); ok {
+		rlpErr.Path = segment + rlpErr.Path
+		return rlpErr
+	}
+	return &RLPError{Path: segment, Err: err}
+}
+
+// rlpHeader returns the header of a string (offset 0x80)
+// or a list (offset 0xc0) of the provided size.
+func rlpHeader(offset byte, size int) []byte {
+	if size <= 55 {
+		return []byte{offset + byte(size)}
+	}
+	sizeBytes := rlpTrimLeadingZeros(rlpUint64Bytes(uint64(size)))
+	return append([]byte{offset + 55 + byte(len(sizeBytes))}, sizeBytes...)
+}
+
+func rlpEncodeString(data []byte) []byte {
+	if len(data) == 1 && data[0] < 0x80 {
+		return []byte{data[0]}
+	}
+	return append(rlpHeader(0x80, len(data)), data...)
+}
+
+func rlpEncodeList(content []byte) []byte {
+	return append(rlpHeader(0xc0, len(content)), content...)
+}
+
+func rlpUint64Bytes(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	return buf
+}
+
+func rlpTrimLeadingZeros(buf []byte) []byte {
+	for len(buf) > 0 && buf[0] == 0 {
+		buf = buf[1:]
+	}
+	return buf
+}
+
+// rlpSplit reads the header of the first item of data, and returns its content
+// and the bytes following it. Non-canonical headers are rejected.
+func rlpSplit(data []byte) (isList bool, content []byte, rest []byte, err error) {
+	if len(data) == 0 {
+		return false, nil, nil, ErrRLPValueTooLarge
+	}
+	prefix := data[0]
+	var offset, size int
+	switch {
+	case prefix < 0x80:
+		return false, data[:1], data[1:], nil
+	case prefix <= 0xb7:
+		offset, size = 1, int(prefix-0x80)
+		if size == 1 && len(data) > 1 && data[1] < 0x80 {
+			return false, nil, nil, ErrRLPNonCanonicalSize
+		}
+	case prefix <= 0xbf:
+		offset, size, err = rlpReadSize(data, int(prefix-0xb7))
+	case prefix <= 0xf7:
+		isList = true
+		offset, size = 1, int(prefix-0xc0)
+	default:
+		isList = true
+		offset, size, err = rlpReadSize(data, int(prefix-0xf7))
+	}
+	if err != nil {
+		return false, nil, nil, err
+	}
+	if size > len(data)-offset {
+		return false, nil, nil, ErrRLPValueTooLarge
+	}
+	return isList, data[offset : offset+size], data[offset+size:], nil
+}
+
+// rlpReadSize reads the big-endian size of sizeLength bytes following the prefix.
+func rlpReadSize(data []byte, sizeLength int) (offset int, size int, err error) {
+	if sizeLength > len(data)-1 {
+		return 0, 0, ErrRLPValueTooLarge
+	}
+	sizeBytes := data[1 : 1+sizeLength]
+	if sizeBytes[0] == 0 {
+		return 0, 0, ErrRLPNonCanonicalSize
+	}
+	if sizeLength > 8 {
+		return 0, 0, ErrRLPValueTooLarge
+	}
+	buf := make([]byte, 8)
+	copy(buf[8-sizeLength:], sizeBytes)
+	value := binary.BigEndian.Uint64(buf)
+	if value <= 55 {
+		return 0, 0, ErrRLPNonCanonicalSize
+	}
+	if value > uint64(len(data)) {
+		return 0, 0, ErrRLPValueTooLarge
+	}
+	return 1 + sizeLength, int(value), nil
+}
+
+// rlpIsList returns true if the values of type rt are encoded as lists.
+func rlpIsList(rt reflect.Type) bool {
+	switch rt {
+	case uint128Type, uint256Type, bigIntType:
+		return false
+	}
+	switch rt.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Slice, reflect.Array:
+		return rt.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+func isRLPBuiltinType(rt reflect.Type) bool {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	switch rt {
+	case rlpRawType, bigIntType, uint128Type, int128Type, float128Type, uint256Type, int256Type:
+		return true
+	}
+	return false
+}
+
+// rlpRootName returns the name of the root of the error paths.
+func rlpRootName(rt reflect.Type) string {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Name() != "" {
+		return rt.Name()
+	}
+	return rt.String()
+}
@@ -0,0 +1,258 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRLP_Items(t *testing.T) {
+	lorem := "Lorem ipsum dolor sit amet, consectetur adipisicing elit"
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"string", "dog", "83646f67"},
+		{"empty string", "", "80"},
+		{"single byte", []byte{0x7f}, "7f"},
+		{"single high byte", []byte{0x80}, "8180"},
+		{"long string", lorem, "b838" + hex.EncodeToString([]byte(lorem))},
+		{"list", []string{"cat", "dog"}, "c88363617483646f67"},
+		{"empty list", []uint64{}, "c0"},
+		{"zero", uint64(0), "80"},
+		{"small integer", uint16(15), "0f"},
+		{"integer", uint32(1024), "820400"},
+		{"true", true, "01"},
+		{"false", false, "80"},
+		{"uint128", Uint128{Lo: 1, Hi: 1}, "89010000000000000001"},
+		{"big.Int", big.NewInt(0x0100), "820100"},
+		{"fixed bytes", [4]byte{0, 0, 0, 1}, "8400000001"},
+		{"nested lists", [][]uint16{{}, {1}, {2, 3}}, "c6c0c101c20203"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := MarshalRLP(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, hex.EncodeToString(data))
+		})
+	}
+}
+
+type rlpLegacyTx struct {
+	Nonce    uint64
+	GasPrice *big.Int
+	Gas      uint64
+	To       *EVMAddress
+	Value    *big.Int
+	Data     []byte
+	V        uint64
+	R        *big.Int
+	S        *big.Int
+}
+
+func TestRLP_LegacyTransaction(t *testing.T) {
+	// The example transaction of EIP-155.
+	signed := mustHex("f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a7640000" +
+		"8025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276" +
+		"a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83")
+
+	var tx rlpLegacyTx
+	require.NoError(t, UnmarshalRLP(&tx, signed))
+	assert.Equal(t, uint64(9), tx.Nonce)
+	assert.Equal(t, "20000000000", tx.GasPrice.String())
+	assert.Equal(t, uint64(21000), tx.Gas)
+	assert.Equal(t, "0x3535353535353535353535353535353535353535", tx.To.String())
+	assert.Equal(t, "1000000000000000000", tx.Value.String())
+	assert.Nil(t, tx.Data)
+	assert.Equal(t, uint64(37), tx.V)
+
+	data, err := MarshalRLP(tx)
+	require.NoError(t, err)
+	assert.Equal(t, signed, data)
+	assert.Equal(t, uint64(len(signed)), MustRLPByteCount(tx))
+
+	// The signing data, with the chain ID and empty R and S:
+	tx.V, tx.R, tx.S = 1, nil, nil
+	data, err = MarshalRLP(tx)
+	require.NoError(t, err)
+	assert.Equal(t, mustHex("ec098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a764000080018080"), data)
+}
+
+type rlpReceipt struct {
+	Status  uint64
+	GasUsed uint64
+	// Only present in the receipts of some networks.
+	L1Fee *big.Int `bin:"binary_extension"`
+}
+
+func TestRLP_BinaryExtension(t *testing.T) {
+	data, err := MarshalRLP(rlpReceipt{Status: 1, GasUsed: 21000})
+	require.NoError(t, err)
+	assert.Equal(t, mustHex("c401825208"), data)
+
+	var got rlpReceipt
+	require.NoError(t, UnmarshalRLP(&got, data))
+	assert.Equal(t, rlpReceipt{Status: 1, GasUsed: 21000}, got)
+
+	data, err = MarshalRLP(rlpReceipt{Status: 1, GasUsed: 21000, L1Fee: big.NewInt(5)})
+	require.NoError(t, err)
+	assert.Equal(t, mustHex("c50182520805"), data)
+
+	require.NoError(t, UnmarshalRLP(&got, data))
+	assert.Equal(t, "5", got.L1Fee.String())
+}
+
+func TestRLP_TrieNode(t *testing.T) {
+	// A branch node: 16 children (hashes or embedded nodes) and a value.
+	var node [17]RLPRaw
+	for i := range node {
+		node[i] = RLPRaw{0x80}
+	}
+	hash := make([]byte, 32)
+	hash[0] = 0xaa
+	node[3] = append(RLPRaw{0xa0}, hash...)
+	node[7] = RLPRaw(mustHex("c22001")) // embedded leaf node
+
+	data, err := MarshalRLP(node)
+	require.NoError(t, err)
+
+	var got [17]RLPRaw
+	require.NoError(t, UnmarshalRLP(&got, data))
+	assert.Equal(t, node, got)
+
+	var hashOut []byte
+	require.NoError(t, UnmarshalRLP(&hashOut, got[3]))
+	assert.Equal(t, hash, hashOut)
+}
+
+type rlpBlock struct {
+	Number uint64
+	Body   rlpBody
+}
+
+type rlpBody struct {
+	Txs    [][]byte
+	Uncles []uint64
+}
+
+func TestRLP_DecodeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		target   interface{}
+		sentinel error
+		expected string
+	}{
+		{
+			name:     "single byte in a short string",
+			data:     "8105",
+			target:   new([]byte),
+			sentinel: ErrRLPNonCanonicalSize,
+			expected: "rlp: []uint8: non-canonical size information",
+		},
+		{
+			name:     "short string with a long header",
+			data:     "b803646f67",
+			target:   new(string),
+			sentinel: ErrRLPNonCanonicalSize,
+		},
+		{
+			name:     "size with leading zeros",
+			data:     "b90038" + strings.Repeat("00", 56),
+			target:   new(string),
+			sentinel: ErrRLPNonCanonicalSize,
+		},
+		{
+			name:     "integer with leading zeros",
+			data:     "820001",
+			target:   new(uint64),
+			sentinel: ErrRLPNonCanonicalInteger,
+		},
+		{
+			name:     "zero byte integer",
+			data:     "00",
+			target:   new(uint64),
+			sentinel: ErrRLPNonCanonicalInteger,
+		},
+		{
+			name:     "truncated",
+			data:     "83646f",
+			target:   new(string),
+			sentinel: ErrRLPValueTooLarge,
+		},
+		{
+			name:     "field path",
+			data:     "c80ac6c0c401820001",
+			target:   new(rlpBlock),
+			sentinel: ErrRLPNonCanonicalInteger,
+			expected: "rlp: rlpBlock.Body.Uncles[1]: non-canonical integer (leading zero bytes)",
+		},
+		{
+			name:     "list instead of string",
+			data:     "c20a80",
+			target:   new(rlpBlock),
+			sentinel: ErrRLPExpectedList,
+			expected: "rlp: rlpBlock.Body: expected list",
+		},
+		{
+			name:     "integer overflow",
+			data:     "820100",
+			target:   new(uint8),
+			expected: "rlp: uint8: integer of 2 bytes overflows 1 bytes",
+		},
+		{
+			name:     "too many elements",
+			data:     "c401020304",
+			target:   new(rlpReceipt),
+			expected: "rlp: rlpReceipt: input list has too many elements",
+		},
+		{
+			name:     "too few elements",
+			data:     "c10a",
+			target:   new(rlpBlock),
+			expected: "rlp: rlpBlock.Body: too few elements",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := UnmarshalRLP(test.target, mustHex(test.data))
+			require.Error(t, err)
+			var rlpErr *RLPError
+			require.True(t, errors.As(err, &rlpErr), err)
+			if test.sentinel != nil {
+				assert.True(t, errors.Is(err, test.sentinel), err)
+			}
+			if test.expected != "" {
+				assert.Equal(t, test.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestRLP_EncodeErrors(t *testing.T) {
+	_, err := MarshalRLP(struct{ A int64 }{A: -1})
+	require.EqualError(t, err, `rlp: struct { A int64 }.A: unsupported type "int64"`)
+
+	_, err = MarshalRLP(big.NewInt(-1))
+	require.Error(t, err)
+}
@@ -0,0 +1,237 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+// Compact is an unsigned integer of up to 128 bits in the SCALE compact
+// encoding (e.g. a `Compact<u32>` or `Compact<u128>` field), whatever the
+// encoding of the encoder or decoder it is used with.
+//
+// See https://docs.substrate.io/reference/scale-codec/#fn-1
+type Compact Uint128
+
+var compactType = reflect.TypeOf(Compact{})
+
+func NewCompact(v uint64) Compact {
+	return Compact{Lo: v}
+}
+
+func (c Compact) Uint128() Uint128 {
+	return Uint128{Lo: c.Lo, Hi: c.Hi}
+}
+
+func (c Compact) BigInt() *big.Int {
+	return c.Uint128().BigInt()
+}
+
+func (c Compact) String() string {
+	return c.Uint128().DecimalString()
+}
+
+func (c Compact) MarshalWithEncoder(enc *Encoder) error {
+	return enc.WriteCompact(c)
+}
+
+func (c *Compact) UnmarshalWithDecoder(dec *Decoder) error {
+	value, err := dec.ReadCompact()
+	if err != nil {
+		return err
+	}
+	*c = value
+	return nil
+}
+
+// WriteCompact writes v in the SCALE compact encoding:
+// single-byte, two-byte and four-byte modes for values up to 2^30-1,
+// and the big-integer mode (length prefix plus 4 to 16 bytes) above.
+func (e *Encoder) WriteCompact(v Compact) (err error) {
+	if traceEnabled {
+		zlog.Debug("encode: write compact", zap.Stringer("val", v))
+	}
+	switch {
+	case v.Hi == 0 && v.Lo < 1<<6:
+		return e.WriteByte(byte(v.Lo << 2))
+	case v.Hi == 0 && v.Lo < 1<<14:
+		return e.WriteUint16(uint16(v.Lo<<2|0b01), LE)
+	case v.Hi == 0 && v.Lo < 1<<30:
+		return e.WriteUint32(uint32(v.Lo<<2|0b10), LE)
+	}
+
+	buf := make([]byte, 16)
+	binary.LittleEndian.PutUint64(buf, v.Lo)
+	binary.LittleEndian.PutUint64(buf[8:], v.Hi)
+	n := len(buf)
+	for n > 4 && buf[n-1] == 0 {
+		n--
+	}
+	if err = e.WriteByte(byte((n-4)<<2 | 0b11)); err != nil {
+		return err
+	}
+	return e.WriteBytes(buf[:n], false)
+}
+
+// ReadCompact reads a SCALE compact integer, rejecting the encodings
+// that do not use the smallest possible mode.
+func (dec *Decoder) ReadCompact() (out Compact, err error) {
+	prefix, err := dec.ReadByte()
+	if err != nil {
+		return out, fmt.Errorf("compact: %w", err)
+	}
+	switch prefix & 0b11 {
+	case 0b00:
+		out.Lo = uint64(prefix >> 2)
+	case 0b01:
+		next, err := dec.ReadByte()
+		if err != nil {
+			return out, fmt.Errorf("compact: %w", err)
+		}
+		out.Lo = uint64(binary.LittleEndian.Uint16([]byte{prefix, next}) >> 2)
+		if out.Lo < 1<<6 {
+			return Compact{}, errors.New("compact: non-canonical encoding")
+		}
+	case 0b10:
+		rest, err := dec.ReadNBytes(3)
+		if err != nil {
+			return out, fmt.Errorf("compact: %w", err)
+		}
+		out.Lo = uint64(binary.LittleEndian.Uint32(append([]byte{prefix}, rest...)) >> 2)
+		if out.Lo < 1<<14 {
+			return Compact{}, errors.New("compact: non-canonical encoding")
+		}
+	case 0b11:
+		n := int(prefix>>2) + 4
+		if n > 16 {
+			return out, fmt.Errorf("compact: %d bytes value overflows u128", n)
+		}
+		data, err := dec.ReadNBytes(n)
+		if err != nil {
+			return out, fmt.Errorf("compact: %w", err)
+		}
+		buf := make([]byte, 16)
+		copy(buf, data)
+		out.Lo = binary.LittleEndian.Uint64(buf)
+		out.Hi = binary.LittleEndian.Uint64(buf[8:])
+		if data[n-1] == 0 || (out.Hi == 0 && out.Lo < 1<<30) {
+			return Compact{}, errors.New("compact: non-canonical encoding")
+		}
+	}
+	if traceEnabled {
+		zlog.Debug("decode: read compact", zap.Stringer("val", out))
+	}
+	return out, nil
+}
+
+// scaleEncoding is the definition of SCALE: little-endian integers, compact
+// lengths, u8 enum tags, no floats, and the single byte of Option<bool>.
+type scaleEncoding struct{ noEncodingRules }
+
+func (scaleEncoding) Name() string                { return EncodingSCALE.String() }
+func (scaleEncoding) ByteOrder() binary.ByteOrder { return binary.LittleEndian }
+
+func (scaleEncoding) WriteLength(enc *Encoder, length int) error { return enc.WriteLength(length) }
+func (scaleEncoding) ReadLength(dec *Decoder) (int, error)       { return dec.ReadLength() }
+
+func (scaleEncoding) WriteOptionTag(enc *Encoder, isPresent bool) error {
+	return enc.WriteBool(isPresent)
+}
+func (scaleEncoding) ReadOptionTag(dec *Decoder) (bool, error) { return dec.ReadBool() }
+
+func (scaleEncoding) WriteEnumTag(enc *Encoder, index uint32) error {
+	return enc.WriteByte(byte(index))
+}
+
+func (scaleEncoding) ReadEnumTag(dec *Decoder) (uint32, error) {
+	tag, err := dec.ReadUint8()
+	return uint32(tag), err
+}
+
+func (scaleEncoding) WriteString(enc *Encoder, s string) error { return enc.WriteString(s) }
+func (scaleEncoding) ReadString(dec *Decoder) (string, error) {
+	return dec.readUTF8String("scale", nil)
+}
+
+func (scaleEncoding) WriteBool(enc *Encoder, b bool) error { return enc.WriteBool(b) }
+func (scaleEncoding) ReadBool(dec *Decoder) (bool, error)  { return dec.ReadBool() }
+
+// SortMapKeys sorts the keys, as in the encoding of a `BTreeMap`.
+func (scaleEncoding) SortMapKeys(keys []reflect.Value) { SortMapKeysByValue(keys) }
+
+// encodeOptional writes an Option<bool> as a single byte:
+// 0 for None, 1 for true, 2 for false.
+func (scaleEncoding) encodeOptional(enc *Encoder, rv reflect.Value) (bool, error) {
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Bool {
+		return false, nil
+	}
+	if rv.Elem().Bool() {
+		return true, enc.WriteByte(1)
+	}
+	return true, enc.WriteByte(2)
+}
+
+func (scaleEncoding) decodeOptional(dec *Decoder, rv reflect.Value) (bool, error) {
+	if rv.Kind() != reflect.Ptr || rv.Type().Elem().Kind() != reflect.Bool {
+		return false, nil
+	}
+	b, err := dec.ReadByte()
+	if err != nil {
+		return true, err
+	}
+	switch b {
+	case 0:
+		rv.Set(reflect.Zero(rv.Type()))
+	case 1, 2:
+		value := reflect.New(rv.Type().Elem())
+		value.Elem().SetBool(b == 1)
+		rv.Set(value)
+	default:
+		return true, fmt.Errorf("scale: invalid Option<bool> value %d", b)
+	}
+	return true, nil
+}
+
+func (scaleEncoding) encodeValue(enc *Encoder, rv reflect.Value, opt *option) (bool, error) {
+	if isBinaryMarshaler(rv) {
+		return false, nil
+	}
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return true, fmt.Errorf("encode: scale does not support floating point type %q", rv.Type())
+	}
+	return enc.writeLittleEndian(rv)
+}
+
+func (scaleEncoding) decodeValue(dec *Decoder, rv reflect.Value, opt *option, hasUnmarshaler bool) (bool, error) {
+	if hasUnmarshaler {
+		return false, nil
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		s, err := dec.readUTF8String("scale", opt)
+		rv.SetString(s)
+		return true, err
+	case reflect.Float32, reflect.Float64:
+		return true, fmt.Errorf("decode: scale does not support floating point type %q", rv.Type())
+	}
+	return dec.readLittleEndian(rv)
+}
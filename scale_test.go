@@ -0,0 +1,181 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test vectors are from https://github.com/paritytech/parity-scale-codec
+// and https://docs.substrate.io/reference/scale-codec/
+func TestSCALE_Compact(t *testing.T) {
+	tests := []struct {
+		value Compact
+		hex   string
+	}{
+		{NewCompact(0), "00"},
+		{NewCompact(1), "04"},
+		{NewCompact(42), "a8"},
+		{NewCompact(63), "fc"},
+		{NewCompact(64), "0101"},
+		{NewCompact(69), "1501"},
+		{NewCompact(16383), "fdff"},
+		{NewCompact(16384), "02000100"},
+		{NewCompact(65535), "feff0300"},
+		{NewCompact(1<<30 - 1), "feffffff"},
+		{NewCompact(1 << 30), "0300000040"},
+		{NewCompact(100000000000000), "0b00407a10f35a"},
+		{NewCompact(math.MaxUint64), "13ffffffffffffffff"},
+		{Compact{Lo: math.MaxUint64, Hi: math.MaxUint64}, "33ffffffffffffffffffffffffffffffff"},
+	}
+	for _, test := range tests {
+		t.Run(test.hex, func(t *testing.T) {
+			data, err := MarshalSCALE(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.hex, hex.EncodeToString(data))
+
+			var got Compact
+			require.NoError(t, UnmarshalSCALE(&got, data))
+			assert.Equal(t, test.value, got)
+		})
+	}
+}
+
+func TestSCALE_Compact_NonCanonical(t *testing.T) {
+	for _, in := range []string{
+		"0100",                // 0 in two-byte mode
+		"02000000",            // 0 in four-byte mode
+		"03ffffff3f",          // 2^30-1 in big-integer mode
+		"070000004000",        // trailing zero byte
+		"37" + "ff0000000000", // more than 16 bytes
+	} {
+		data, err := hex.DecodeString(in)
+		require.NoError(t, err)
+		var got Compact
+		assert.Error(t, UnmarshalSCALE(&got, data), in)
+	}
+}
+
+type scaleTestIntOrBool struct {
+	Enum BorshEnum `borsh_enum:"true"`
+	Int  uint8
+	Bool bool
+}
+
+type scaleTestStruct struct {
+	A     Compact
+	B     uint64
+	C     []uint16
+	D     *uint32 `bin:"optional"`
+	E     *bool   `bin:"optional"`
+	F     scaleTestIntOrBool
+	G     string
+	H     map[string]uint8
+	Total Compact `bin:"sizeof=I"`
+	I     []bool
+}
+
+func TestSCALE_Encode(t *testing.T) {
+	f := false
+	val := scaleTestStruct{
+		A: NewCompact(3),
+		B: 0,
+		C: []uint16{4, 8, 15, 16, 23, 42},
+		D: nil,
+		E: &f,
+		F: scaleTestIntOrBool{Enum: 1, Bool: true},
+		G: "ok",
+		H: map[string]uint8{"b": 2, "a": 1},
+		// The length of I is the compact Total:
+		Total: NewCompact(2),
+		I:     []bool{true, false},
+	}
+
+	data, err := MarshalSCALE(val)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"0c"+"0000000000000000"+
+			"18"+"040008000f00100017002a00"+
+			"00"+
+			"02"+
+			"0101"+
+			"08"+"6f6b"+
+			"08"+"0461"+"01"+"0462"+"02"+
+			"08"+
+			"0100",
+		hex.EncodeToString(data),
+	)
+
+	count, err := SCALEByteCount(val)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(data)), count)
+
+	var got scaleTestStruct
+	require.NoError(t, UnmarshalSCALE(&got, data))
+	assert.Equal(t, val, got)
+}
+
+func TestSCALE_OptionBool(t *testing.T) {
+	type optionBool struct {
+		V *bool `bin:"optional"`
+	}
+	tr, fa := true, false
+	for _, test := range []struct {
+		value optionBool
+		data  []byte
+	}{
+		{optionBool{}, []byte{0x00}},
+		{optionBool{V: &tr}, []byte{0x01}},
+		{optionBool{V: &fa}, []byte{0x02}},
+	} {
+		data, err := MarshalSCALE(test.value)
+		require.NoError(t, err)
+		assert.Equal(t, test.data, data)
+
+		var got optionBool
+		require.NoError(t, UnmarshalSCALE(&got, data))
+		assert.Equal(t, test.value, got)
+	}
+
+	var got optionBool
+	assert.EqualError(t, UnmarshalSCALE(&got, []byte{0x03}), `error while decoding "V" field: scale: invalid Option<bool> value 3`)
+}
+
+func TestSCALE_Errors(t *testing.T) {
+	var b bool
+	assert.EqualError(t, UnmarshalSCALE(&b, []byte{0x02}), "scale: invalid bool value 2")
+
+	var e scaleTestIntOrBool
+	assert.EqualError(t, UnmarshalSCALE(&e, []byte{0x02}), "complex enum too large")
+
+	var seq []uint8
+	assert.EqualError(t,
+		UnmarshalSCALE(&seq, []byte{0xfe, 0xff, 0xff, 0xff}),
+		"scale: length 1073741823 exceeds the remaining 0 bytes",
+	)
+	var m map[uint8]uint8
+	assert.EqualError(t,
+		UnmarshalSCALE(&m, []byte{0x0c, 0x01, 0x02}),
+		"scale: length 3 exceeds the remaining 2 bytes",
+	)
+
+	_, err := MarshalSCALE(float64(1))
+	assert.EqualError(t, err, `encode: scale does not support floating point type "float64"`)
+}
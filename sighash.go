@@ -39,6 +39,9 @@ const SIGHASH_GLOBAL_NAMESPACE string = "global"
 
 const SIGHASH_ACCOUNT_NAMESPACE string = "account"
 
+// Namespace for calculating event discriminators.
+const SIGHASH_EVENT_NAMESPACE string = "event"
+
 const ACCOUNT_DISCRIMINATOR_SIZE = 8
 
 // https://github.com/project-serum/anchor/pull/64/files
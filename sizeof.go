@@ -0,0 +1,119 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// SizeOfMode is how an Encoder treats the fields with a `sizeof=` tag,
+// which hold the length of a following slice encoded without length prefix.
+type SizeOfMode int
+
+const (
+	// SizeOfAsIs encodes the sizeof fields as they are, and the slices up to
+	// that length; it fails when a slice is shorter than its sizeof field.
+	SizeOfAsIs SizeOfMode = iota
+	// SizeOfAutoFill encodes the length of the slices in their sizeof fields,
+	// whatever their value.
+	SizeOfAutoFill
+	// SizeOfStrict fails when a sizeof field differs from the length of its slice.
+	SizeOfStrict
+)
+
+// SetSizeOfMode sets how the encoder treats the sizeof fields (SizeOfAsIs by default).
+func (e *Encoder) SetSizeOfMode(mode SizeOfMode) *Encoder {
+	e.sizeOfMode = mode
+	return e
+}
+
+// sizeOfTarget returns the index of the slice whose length is given by the
+// `sizeof=` tag of the i-th field of the struct rt, or -1 when the tag does
+// not name a following slice field. It fails when that slice also has a
+// `len=` length prefix.
+func sizeOfTarget(rt reflect.Type, i int, fieldTag *fieldTag) (int, error) {
+	target, ok := rt.FieldByName(fieldTag.SizeOf)
+	if !ok || len(target.Index) != 1 || target.Index[0] <= i || target.Type.Kind() != reflect.Slice {
+		return -1, nil
+	}
+	if targetTag := parseFieldTag(target.Tag); targetTag.LenPrefix != "" {
+		name := rt.Field(i).Name
+		return -1, fmt.Errorf("field %s: slice %s has both the sizeof field %s and a len=%s length prefix", name, target.Name, name, targetTag.LenPrefix)
+	}
+	return target.Index[0], nil
+}
+
+// structFieldValue returns the i-th field of the struct rv to encode, which is
+// the length of the slice of a sizeof field when the encoder auto-fills them.
+//
+// With SizeOfAsIs, a sizeof tag that does not name a following slice field
+// has no effect, as it always had; the other modes reject it.
+func (e *Encoder) structFieldValue(rv reflect.Value, i int, fieldTag *fieldTag) (reflect.Value, error) {
+	field := rv.Field(i)
+	if fieldTag.SizeOf == "" {
+		return field, nil
+	}
+	target, err := sizeOfTarget(rv.Type(), i, fieldTag)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if target < 0 {
+		if e.sizeOfMode == SizeOfAsIs {
+			return field, nil
+		}
+		return reflect.Value{}, fmt.Errorf("field %s: sizeof=%s requires a following slice field %s", rv.Type().Field(i).Name, fieldTag.SizeOf, fieldTag.SizeOf)
+	}
+
+	name, targetName := rv.Type().Field(i).Name, rv.Type().Field(target).Name
+	size, err := sizeof(field.Type(), field)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("field %s: %w", name, err)
+	}
+	length := rv.Field(target).Len()
+	switch {
+	case size == length:
+		return field, nil
+	case e.sizeOfMode == SizeOfStrict:
+		return reflect.Value{}, fmt.Errorf("field %s is %d, but slice %s has %d elements", name, size, targetName, length)
+	case e.sizeOfMode == SizeOfAsIs:
+		// The slice is encoded up to the length of the sizeof field,
+		// which it must have (this used to panic).
+		if size > length {
+			return reflect.Value{}, fmt.Errorf("field %s is %d, but slice %s has only %d elements", name, size, targetName, length)
+		}
+		return field, nil
+	}
+
+	filled := reflect.New(field.Type()).Elem()
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if filled.OverflowInt(int64(length)) {
+			return reflect.Value{}, fmt.Errorf("field %s: length %d of slice %s overflows %s", name, length, targetName, field.Type())
+		}
+		filled.SetInt(int64(length))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if filled.OverflowUint(uint64(length)) {
+			return reflect.Value{}, fmt.Errorf("field %s: length %d of slice %s overflows %s", name, length, targetName, field.Type())
+		}
+		filled.SetUint(uint64(length))
+	default:
+		if field.Type() != compactType {
+			return reflect.Value{}, fmt.Errorf("field %s: sizeof field of type %s", name, field.Type())
+		}
+		filled.Set(reflect.ValueOf(NewCompact(uint64(length))))
+	}
+	return filled, nil
+}
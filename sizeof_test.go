@@ -0,0 +1,118 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sizeOfList struct {
+	Count uint8 `bin:"sizeof=Items"`
+	Items []uint16
+	Total Compact `bin:"sizeof=More"`
+	More  []uint8
+}
+
+func TestSizeOfMode(t *testing.T) {
+	encode := func(v interface{}, enc Encoding, mode SizeOfMode) (string, error) {
+		buf := new(bytes.Buffer)
+		err := NewEncoderWithEncoding(buf, enc).SetSizeOfMode(mode).Encode(v)
+		return hex.EncodeToString(buf.Bytes()), err
+	}
+
+	value := sizeOfList{Items: []uint16{1, 2}, More: []uint8{3}}
+
+	{
+		data, err := encode(value, EncodingBorsh, SizeOfAutoFill)
+		require.NoError(t, err)
+		assert.Equal(t, "02"+"01000200"+"04"+"03", data)
+
+		var got sizeOfList
+		require.NoError(t, UnmarshalBorsh(&got, mustHex(data)))
+		assert.Equal(t, sizeOfList{Count: 2, Items: []uint16{1, 2}, Total: NewCompact(1), More: []uint8{3}}, got)
+	}
+	{
+		data, err := encode(value, EncodingSCALE, SizeOfAutoFill)
+		require.NoError(t, err)
+		assert.Equal(t, "02"+"01000200"+"04"+"03", data)
+	}
+	{
+		_, err := encode(value, EncodingBorsh, SizeOfStrict)
+		require.EqualError(t, err, `field Count is 0, but slice Items has 2 elements`)
+
+		value := sizeOfList{Count: 2, Items: []uint16{1, 2}, Total: NewCompact(1), More: []uint8{3}}
+		_, err = encode(value, EncodingBorsh, SizeOfStrict)
+		require.NoError(t, err)
+	}
+	{
+		// As is, the slices are truncated to their sizeof fields:
+		data, err := encode(sizeOfList{Count: 1, Items: []uint16{1, 2}}, EncodingBin, SizeOfAsIs)
+		require.NoError(t, err)
+		assert.Equal(t, "01"+"0100"+"00", data)
+
+		_, err = encode(sizeOfList{Count: 3, Items: []uint16{1, 2}}, EncodingBin, SizeOfAsIs)
+		require.EqualError(t, err, `field Count is 3, but slice Items has only 2 elements`)
+	}
+	{
+		_, err := encode(struct {
+			Count uint8 `bin:"sizeof=Items"`
+			Items []byte
+		}{Items: make([]byte, 256)}, EncodingBorsh, SizeOfAutoFill)
+		require.EqualError(t, err, `field Count: length 256 of slice Items overflows uint8`)
+	}
+}
+
+func TestSizeOf_Errors(t *testing.T) {
+	err := UnmarshalBorsh(&struct {
+		Count uint8  `bin:"sizeof=Items"`
+		Items []byte `bin:"len=u8"`
+	}{}, []byte{1, 1, 0})
+	require.EqualError(t, err, "field Count: slice Items has both the sizeof field Count and a len=u8 length prefix")
+
+	// By default, as they always had, the sizeof tags that name no following
+	// slice field have no effect:
+	var earlier struct {
+		Items []byte
+		Count uint8 `bin:"sizeof=Items"`
+	}
+	require.NoError(t, UnmarshalBorsh(&earlier, []byte{1, 0, 0, 0, 7, 1}))
+	assert.Equal(t, []byte{7}, earlier.Items)
+
+	misspelled := struct {
+		Count uint8 `bin:"sizeof=Itms"`
+		Items []byte
+	}{Count: 5, Items: []byte{1}}
+	data, err := MarshalBorsh(misspelled)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{5, 1, 0, 0, 0, 1}, data)
+
+	// The other modes reject them:
+	for _, mode := range []SizeOfMode{SizeOfAutoFill, SizeOfStrict} {
+		err := NewBorshEncoder(new(bytes.Buffer)).SetSizeOfMode(mode).Encode(misspelled)
+		require.EqualError(t, err, "field Count: sizeof=Itms requires a following slice field Itms")
+	}
+
+	// A Compact length must fit in an int:
+	_, err = MarshalSCALE(struct {
+		Total Compact `bin:"sizeof=More"`
+		More  []uint8
+	}{Total: Compact{Hi: 1}})
+	require.EqualError(t, err, "field Total: sizeof length 18446744073709551616 overflows int")
+}
@@ -0,0 +1,448 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Bitvector is an SSZ `Bitvector[N]`, whose length is set by the
+// `ssz_size=N` tag of the field.
+type Bitvector []bool
+
+// Bitlist is an SSZ `Bitlist[N]`, whose limit is set by the
+// `ssz_max=N` tag of the field.
+type Bitlist []bool
+
+var (
+	bitvectorType = reflect.TypeOf(Bitvector{})
+	bitlistType   = reflect.TypeOf(Bitlist{})
+	sszByteType   = reflect.TypeOf(byte(0))
+)
+
+const (
+	sszOffsetSize = 4
+	sszChunkSize  = 32
+)
+
+type sszKind int
+
+const (
+	sszBasic sszKind = iota
+	sszVector
+	sszList
+	sszBitvector
+	sszBitlist
+	sszContainer
+)
+
+// sszBounds are the `ssz_size` and `ssz_max` values of a field,
+// the first dimension being the one of the field itself.
+type sszBounds struct {
+	size []int
+	max  []int
+}
+
+func sszBoundsOf(tag *fieldTag) sszBounds {
+	return sszBounds{size: tag.SSZSize, max: tag.SSZMax}
+}
+
+func (b sszBounds) current() (size int, max int) {
+	if len(b.size) > 0 {
+		size = b.size[0]
+	}
+	if len(b.max) > 0 {
+		max = b.max[0]
+	}
+	return size, max
+}
+
+func (b sszBounds) elem() sszBounds {
+	out := b
+	if len(out.size) > 0 {
+		out.size = out.size[1:]
+	}
+	if len(out.max) > 0 {
+		out.max = out.max[1:]
+	}
+	return out
+}
+
+// sszType describes how the values of a Go type are serialized.
+type sszType struct {
+	kind sszKind
+	rt   reflect.Type
+	// size of the basic values.
+	size int
+	// length of the vectors and bitvectors, limit of the lists and bitlists.
+	length int
+	elem   *sszType
+	// fields of the containers.
+	fields      []int
+	fieldTypes  []*sszType
+	fixed       bool
+	fixedLength int
+}
+
+func sszTypeOf(rt reflect.Type, bounds sszBounds) (*sszType, error) {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	size, max := bounds.current()
+	t := &sszType{rt: rt}
+
+	switch rt {
+	case uint128Type:
+		t.kind, t.size = sszBasic, 16
+		return t.withSize(), nil
+	case uint256Type:
+		t.kind, t.size = sszBasic, 32
+		return t.withSize(), nil
+	case int128Type, int256Type, float128Type:
+		return nil, fmt.Errorf("unsupported type %q", rt)
+	case bitvectorType:
+		if size == 0 {
+			return nil, errors.New("bitvector requires a ssz_size tag")
+		}
+		t.kind, t.length = sszBitvector, size
+		return t.withSize(), nil
+	case bitlistType:
+		if max == 0 {
+			return nil, errors.New("bitlist requires a ssz_max tag")
+		}
+		t.kind, t.length = sszBitlist, max
+		return t.withSize(), nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool, reflect.Uint8:
+		t.kind, t.size = sszBasic, 1
+	case reflect.Uint16:
+		t.kind, t.size = sszBasic, 2
+	case reflect.Uint32:
+		t.kind, t.size = sszBasic, 4
+	case reflect.Uint64:
+		t.kind, t.size = sszBasic, 8
+	case reflect.String:
+		if max == 0 {
+			return nil, errors.New("string requires a ssz_max tag")
+		}
+		t.kind, t.length = sszList, max
+		t.elem = &sszType{kind: sszBasic, rt: sszByteType, size: 1}
+	case reflect.Array, reflect.Slice:
+		switch {
+		case rt.Kind() == reflect.Array:
+			t.kind, t.length = sszVector, rt.Len()
+		case size > 0:
+			t.kind, t.length = sszVector, size
+		case max > 0:
+			t.kind, t.length = sszList, max
+		default:
+			return nil, fmt.Errorf("slice %s requires a ssz_size or ssz_max tag", rt)
+		}
+		if t.kind == sszVector && t.length == 0 {
+			return nil, fmt.Errorf("empty vector %s is not supported", rt)
+		}
+		elem, err := sszTypeOf(rt.Elem(), bounds.elem())
+		if err != nil {
+			return nil, err
+		}
+		t.elem = elem
+	case reflect.Struct:
+		if isComplexEnumType(rt) {
+			return nil, fmt.Errorf("complex enum %s is not supported", rt)
+		}
+		t.kind = sszContainer
+		for i := 0; i < rt.NumField(); i++ {
+			structField := rt.Field(i)
+			fieldTag, err := parseStructFieldTag(structField)
+			if err != nil {
+				return nil, err
+			}
+			if fieldTag.Skip || structField.PkgPath != "" {
+				continue
+			}
+			if fieldTag.Optional || fieldTag.BinaryExtension {
+				return nil, fmt.Errorf("field %s: optional fields are not supported", structField.Name)
+			}
+			if fieldTag.LenPrefix != "" {
+				return nil, fmt.Errorf("field %s: len tags are not supported, lengths are defined by the offsets", structField.Name)
+			}
+			if fieldTag.Fixed > 0 || fieldTag.CString {
+				return nil, fmt.Errorf("field %s: fixed and cstring tags are not supported", structField.Name)
+			}
+			if fieldTag.Bitfield != "" || fieldTag.Bits > 0 {
+				return nil, fmt.Errorf("field %s: bitfields are not supported", structField.Name)
+			}
+			if fieldTag.If != "" {
+				return nil, fmt.Errorf("field %s: conditional fields are not supported", structField.Name)
+			}
+			fieldType, err := sszTypeOf(structField.Type, sszBoundsOf(fieldTag))
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", structField.Name, err)
+			}
+			t.fields = append(t.fields, i)
+			t.fieldTypes = append(t.fieldTypes, fieldType)
+		}
+		if len(t.fields) == 0 {
+			return nil, fmt.Errorf("empty container %s is not supported", rt)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %q", rt)
+	}
+	return t.withSize(), nil
+}
+
+// withSize sets whether the values are fixed-size, and their size if so.
+func (t *sszType) withSize() *sszType {
+	switch t.kind {
+	case sszBasic:
+		t.fixed, t.fixedLength = true, t.size
+	case sszBitvector:
+		t.fixed, t.fixedLength = true, (t.length+7)/8
+	case sszVector:
+		t.fixed, t.fixedLength = t.elem.fixed, t.length*t.elem.fixedLength
+	case sszContainer:
+		t.fixed = true
+		for _, fieldType := range t.fieldTypes {
+			if !fieldType.fixed {
+				t.fixed, t.fixedLength = false, 0
+				break
+			}
+			t.fixedLength += fieldType.fixedLength
+		}
+	}
+	return t
+}
+
+// headLength returns the size of a value in the fixed part of its parent.
+func (t *sszType) headLength() int {
+	if t.fixed {
+		return t.fixedLength
+	}
+	return sszOffsetSize
+}
+
+// elements returns the values of the fields or elements of rv, and their types.
+func (t *sszType) elements(rv reflect.Value) ([]reflect.Value, []*sszType) {
+	if t.kind == sszContainer {
+		values := make([]reflect.Value, len(t.fields))
+		for i, index := range t.fields {
+			values[i] = rv.Field(index)
+		}
+		return values, t.fieldTypes
+	}
+	values := make([]reflect.Value, rv.Len())
+	types := make([]*sszType, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i)
+		types[i] = t.elem
+	}
+	return values, types
+}
+
+// HashTreeRoot returns the SSZ hash tree root of v, whose SSZ types are
+// defined by the `ssz_size` and `ssz_max` tags of the struct fields.
+func HashTreeRoot(v interface{}) (out [32]byte, err error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return out, errors.New("ssz: hash tree root of nil")
+	}
+	t, err := sszTypeOf(rv.Type(), sszBounds{})
+	if err != nil {
+		return out, fmt.Errorf("ssz: %w", err)
+	}
+	out, err = sszHashTreeRoot(rv, t)
+	if err != nil {
+		return out, fmt.Errorf("ssz: %w", err)
+	}
+	return out, nil
+}
+
+func sszHashTreeRoot(rv reflect.Value, t *sszType) ([32]byte, error) {
+	rv = sszIndirect(rv, t)
+
+	switch t.kind {
+	case sszBasic:
+		var chunk [32]byte
+		copy(chunk[:], sszEncodeBasic(rv, t))
+		return chunk, nil
+	case sszBitvector, sszBitlist:
+		bits, err := sszBits(rv, t)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		root, err := sszMerkleize(sszPack(sszPackBits(bits)), (t.length+255)/256)
+		if err != nil {
+			return root, err
+		}
+		if t.kind == sszBitlist {
+			root = sszMixInLength(root, len(bits))
+		}
+		return root, nil
+	}
+
+	values, types := t.elements(rv)
+	if t.kind != sszContainer {
+		if err := t.checkLength(len(values)); err != nil {
+			return [32]byte{}, err
+		}
+	}
+
+	var root [32]byte
+	var err error
+	if t.kind != sszContainer && t.elem.kind == sszBasic {
+		var data []byte
+		for _, value := range values {
+			data = append(data, sszEncodeBasic(sszIndirect(value, t.elem), t.elem)...)
+		}
+		limit := (t.length*t.elem.size + sszChunkSize - 1) / sszChunkSize
+		root, err = sszMerkleize(sszPack(data), limit)
+	} else {
+		chunks := make([][32]byte, len(values))
+		for i, value := range values {
+			if chunks[i], err = sszHashTreeRoot(value, types[i]); err != nil {
+				return root, sszWrapError(t, i, err)
+			}
+		}
+		limit := t.length
+		if t.kind == sszContainer {
+			limit = len(t.fields)
+		}
+		root, err = sszMerkleize(chunks, limit)
+	}
+	if err != nil {
+		return root, err
+	}
+	if t.kind == sszList {
+		root = sszMixInLength(root, len(values))
+	}
+	return root, nil
+}
+
+// checkLength checks the number of elements of a vector or a list.
+func (t *sszType) checkLength(count int) error {
+	if t.kind == sszVector && count != t.length {
+		return fmt.Errorf("vector of %d elements, expected %d", count, t.length)
+	}
+	if t.kind == sszList && count > t.length {
+		return fmt.Errorf("list of %d elements exceeds the limit of %d", count, t.length)
+	}
+	return nil
+}
+
+// sszIndirect dereferences the pointers of rv; nil pointers are
+// handled as the zero value of their type.
+func sszIndirect(rv reflect.Value, t *sszType) reflect.Value {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Zero(t.rt)
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+func sszWrapError(t *sszType, i int, err error) error {
+	if t.kind == sszContainer {
+		return fmt.Errorf("field %s: %w", t.rt.Field(t.fields[i]).Name, err)
+	}
+	return fmt.Errorf("element %d: %w", i, err)
+}
+
+// sszBits returns the bits of a bitvector or a bitlist, checking their count.
+func sszBits(rv reflect.Value, t *sszType) ([]bool, error) {
+	bits := make([]bool, rv.Len())
+	for i := range bits {
+		bits[i] = rv.Index(i).Bool()
+	}
+	if t.kind == sszBitvector && len(bits) != t.length {
+		return nil, fmt.Errorf("bitvector of %d bits, expected %d", len(bits), t.length)
+	}
+	if t.kind == sszBitlist && len(bits) > t.length {
+		return nil, fmt.Errorf("bitlist of %d bits exceeds the limit of %d", len(bits), t.length)
+	}
+	return bits, nil
+}
+
+// sszPackBits returns the bytes of the bits, the first bit being
+// the least significant bit of the first byte.
+func sszPackBits(bits []bool) []byte {
+	out := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			out[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return out
+}
+
+// sszPack splits data in chunks, right-padding the last one with zeros.
+func sszPack(data []byte) [][32]byte {
+	chunks := make([][32]byte, (len(data)+sszChunkSize-1)/sszChunkSize)
+	for i := range chunks {
+		copy(chunks[i][:], data[i*sszChunkSize:])
+	}
+	return chunks
+}
+
+// sszZeroHashes[i] is the root of a tree of depth i whose chunks are all zero.
+var sszZeroHashes = func() (out [65][32]byte) {
+	for i := 1; i < len(out); i++ {
+		out[i] = sszHashPair(out[i-1], out[i-1])
+	}
+	return out
+}()
+
+func sszHashPair(a, b [32]byte) [32]byte {
+	return sha256.Sum256(append(a[:], b[:]...))
+}
+
+// sszMerkleize returns the root of the tree of the chunks,
+// padded with zero chunks to the next power of two of limit.
+func sszMerkleize(chunks [][32]byte, limit int) ([32]byte, error) {
+	if len(chunks) > limit {
+		return [32]byte{}, fmt.Errorf("%d chunks exceed the limit of %d", len(chunks), limit)
+	}
+	depth := 0
+	for 1<<uint(depth) < limit {
+		depth++
+	}
+	layer := chunks
+	for d := 0; d < depth; d++ {
+		if len(layer)%2 == 1 {
+			layer = append(layer[:len(layer):len(layer)], sszZeroHashes[d])
+		}
+		next := make([][32]byte, len(layer)/2)
+		for i := range next {
+			next[i] = sszHashPair(layer[2*i], layer[2*i+1])
+		}
+		layer = next
+	}
+	if len(layer) == 0 {
+		return sszZeroHashes[depth], nil
+	}
+	return layer[0], nil
+}
+
+func sszMixInLength(root [32]byte, length int) [32]byte {
+	var chunk [32]byte
+	binary.LittleEndian.PutUint64(chunk[:], uint64(length))
+	return sszHashPair(root, chunk)
+}
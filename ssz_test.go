@@ -0,0 +1,199 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sszMixed struct {
+	A uint16
+	B []byte `bin:"ssz_max=10"`
+	C uint8
+}
+
+type sszNested struct {
+	Lists [][]uint8 `bin:"ssz_max=4,4"`
+	Roots [][]byte  `bin:"ssz_size=2,4"`
+}
+
+type sszBitfields struct {
+	List   Bitlist   `bin:"ssz_max=8"`
+	Vector Bitvector `bin:"ssz_size=4"`
+}
+
+func TestSSZ_Serialization(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"uint64", uint64(0x0102030405060708), "0807060504030201"},
+		{"bool", true, "01"},
+		{"uint128", Uint128{Lo: 1, Hi: 2}, "0100000000000000" + "0200000000000000"},
+		{"vector", [3]uint16{1, 2, 3}, "010002000300"},
+		{
+			name:     "variable-size field",
+			value:    sszMixed{A: 0x0102, B: []byte{0xaa, 0xbb}, C: 3},
+			expected: "0201" + "07000000" + "03" + "aabb",
+		},
+		{
+			name: "nested lists",
+			value: sszNested{
+				Lists: [][]uint8{{1}, {2, 3}},
+				Roots: [][]byte{{1, 2, 3, 4}, {5, 6, 7, 8}},
+			},
+			expected: "0c000000" + "0102030405060708" + "08000000" + "09000000" + "01" + "0203",
+		},
+		{
+			name:     "bits",
+			value:    sszBitfields{List: Bitlist{true, true, false, true}, Vector: Bitvector{true, false, true, true}},
+			expected: "05000000" + "0d" + "1b",
+		},
+		{
+			name:     "empty bitlist",
+			value:    sszBitfields{Vector: Bitvector{false, false, false, false}},
+			expected: "05000000" + "00" + "01",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := MarshalSSZ(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, hex.EncodeToString(data))
+		})
+	}
+
+	{
+		var got sszNested
+		require.NoError(t, UnmarshalSSZ(&got, mustHex("0c000000"+"0102030405060708"+"08000000"+"09000000"+"01"+"0203")))
+		assert.Equal(t, sszNested{
+			Lists: [][]uint8{{1}, {2, 3}},
+			Roots: [][]byte{{1, 2, 3, 4}, {5, 6, 7, 8}},
+		}, got)
+	}
+	{
+		var got sszBitfields
+		require.NoError(t, UnmarshalSSZ(&got, mustHex("05000000"+"0d"+"1b")))
+		assert.Equal(t, sszBitfields{List: Bitlist{true, true, false, true}, Vector: Bitvector{true, false, true, true}}, got)
+	}
+	{
+		var got sszMixed
+		require.NoError(t, UnmarshalSSZ(&got, mustHex("0201"+"07000000"+"03"+"aabb")))
+		assert.Equal(t, sszMixed{A: 0x0102, B: []byte{0xaa, 0xbb}, C: 3}, got)
+	}
+}
+
+func TestSSZ_DecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		target interface{}
+	}{
+		{"first offset", "0201" + "08000000" + "03" + "aabb", new(sszMixed)},
+		{"list over its limit", "0201" + "07000000" + "03" + "0102030405060708090a0b", new(sszMixed)},
+		{"truncated fixed part", "020107", new(sszMixed)},
+		{"trailing bytes", "080706050403020100", new(uint64)},
+		{"invalid bool", "02", new(bool)},
+		{"bitlist without length bit", "05000000" + "0d" + "00", new(sszBitfields)},
+		{"bitlist over its limit", "05000000" + "0d" + "0003", new(sszBitfields)},
+		{"bitvector with extra bits", "05000000" + "1d" + "01", new(sszBitfields)},
+		{"decreasing offsets", "0c000000" + "0102030405060708" + "0c000000" + "08000000" + "01", new(sszNested)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Error(t, UnmarshalSSZ(test.target, mustHex(test.data)))
+		})
+	}
+
+	_, err := MarshalSSZ(sszMixed{B: make([]byte, 11)})
+	require.EqualError(t, err, "ssz: field B: list of 11 elements exceeds the limit of 10")
+
+	_, err = MarshalSSZ(struct{ A []uint64 }{})
+	require.EqualError(t, err, "ssz: field A: slice []uint64 requires a ssz_size or ssz_max tag")
+}
+
+type sszCheckpoint struct {
+	Epoch uint64
+	Root  [32]byte
+}
+
+type sszDepositData struct {
+	Pubkey                []byte `bin:"ssz_size=48"`
+	WithdrawalCredentials [32]byte
+	Amount                uint64
+	Signature             []byte `bin:"ssz_size=96"`
+}
+
+type sszDeposits struct {
+	Deposits []sszDepositData `bin:"ssz_max=4294967296"`
+}
+
+func TestSSZ_HashTreeRoot(t *testing.T) {
+	// Roots of the trees of zero chunks.
+	assert.Equal(t, "f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a92759fb4b", hex.EncodeToString(sszZeroHashes[1][:]))
+	assert.Equal(t, "db56114e00fdd4c1f85c892bf35ac9a89289aaecb1ebd0a96cde606a748b5d71", hex.EncodeToString(sszZeroHashes[2][:]))
+
+	{
+		// The deposit root of the deposit contract, when no deposit has been made.
+		root, err := HashTreeRoot(sszDeposits{})
+		require.NoError(t, err)
+		assert.Equal(t, "d70a234731285c6804c2a4f56711ddb8c82c99740f207854891028af34e27e5e", hex.EncodeToString(root[:]))
+	}
+	{
+		root, err := HashTreeRoot(uint16(0xffff))
+		require.NoError(t, err)
+		assert.Equal(t, "ffff"+zeroChunkHex[4:], hex.EncodeToString(root[:]))
+	}
+	{
+		checkpoint := sszCheckpoint{Epoch: 3}
+		checkpoint.Root[0] = 0xaa
+		root, err := HashTreeRoot(&checkpoint)
+		require.NoError(t, err)
+
+		var epoch [32]byte
+		epoch[0] = 3
+		assert.Equal(t, sha256.Sum256(append(epoch[:], checkpoint.Root[:]...)), root)
+	}
+	{
+		// A list of basic values is packed in chunks, and mixed in with its length:
+		root, err := HashTreeRoot(sszMixed{A: 1, B: []byte{0xaa, 0xbb}, C: 2})
+		require.NoError(t, err)
+
+		var a, b, c, length [32]byte
+		a[0], b[0], b[1], c[0], length[0] = 1, 0xaa, 0xbb, 2, 2
+		bRoot := sha256.Sum256(append(b[:], length[:]...))
+		left := sha256.Sum256(append(a[:], bRoot[:]...))
+		right := sha256.Sum256(append(c[:], make([]byte, 32)...))
+		assert.Equal(t, sha256.Sum256(append(left[:], right[:]...)), root)
+	}
+	{
+		// The bits of a bitlist are packed without the length bit:
+		root, err := HashTreeRoot(sszBitfields{List: Bitlist{true, true, false, true}, Vector: Bitvector{true, false, true, true}})
+		require.NoError(t, err)
+
+		var list, vector, length [32]byte
+		list[0], vector[0], length[0] = 0x0b, 0x0d, 4
+		listRoot := sha256.Sum256(append(list[:], length[:]...))
+		assert.Equal(t, sha256.Sum256(append(listRoot[:], vector[:]...)), root)
+	}
+}
+
+const zeroChunkHex = "0000000000000000000000000000000000000000000000000000000000000000"
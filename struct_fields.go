@@ -0,0 +1,179 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+// structFields handles the struct tags of the fields of a struct that the
+// struct walkers of all the encodings have in common.
+type structFields struct {
+	rv reflect.Value
+	// sizeOfMap holds the lengths given by the sizeof fields,
+	// by the name of their slice.
+	sizeOfMap map[string]int
+	// bitfieldEnd is the index of the last field of the last bitfield
+	// encoded or decoded, whose fields are done with its container.
+	bitfieldEnd int
+}
+
+func newStructFields(rv reflect.Value) *structFields {
+	return &structFields{rv: rv, sizeOfMap: map[string]int{}, bitfieldEnd: -1}
+}
+
+// option returns the option of the i-th field of the struct.
+func (f *structFields) option(i int, fieldTag *fieldTag, order binary.ByteOrder) *option {
+	opt := &option{
+		OptionalField: fieldTag.Optional,
+		LenPrefix:     fieldTag.LenPrefix,
+		FixedSize:     fieldTag.Fixed,
+		CString:       fieldTag.CString,
+		Order:         order,
+	}
+
+	name := f.rv.Type().Field(i).Name
+	if s, ok := f.sizeOfMap[name]; ok {
+		if traceEnabled {
+			zlog.Debug("setting sizeof option", zap.String("of", name), zap.Int("size", s))
+		}
+		opt.setSizeOfSlice(s)
+	}
+	return opt
+}
+
+// setSizeOf records the value v of the i-th field of the struct,
+// when it is a sizeof field, as the length of its slice.
+func (f *structFields) setSizeOf(i int, fieldTag *fieldTag, v reflect.Value) error {
+	if fieldTag.SizeOf == "" {
+		return nil
+	}
+	structField := f.rv.Type().Field(i)
+	size, err := sizeof(structField.Type, v)
+	if err != nil {
+		return fmt.Errorf("field %s: %w", structField.Name, err)
+	}
+	if traceEnabled {
+		zlog.Debug("setting size of field",
+			zap.String("field_name", fieldTag.SizeOf),
+			zap.Int("size", size),
+		)
+	}
+	f.sizeOfMap[fieldTag.SizeOf] = size
+	return nil
+}
+
+// conditionFails returns whether the `if=` condition of the i-th field of
+// the struct fails, leaving out the field (and its bitfield, if any).
+func (f *structFields) conditionFails(i int, fieldTag *fieldTag) (bool, error) {
+	if fieldTag.If == "" {
+		return false, nil
+	}
+	present, err := fieldConditionHolds(f.rv, i, fieldTag.If)
+	if err != nil || present {
+		return false, err
+	}
+	if traceEnabled {
+		zlog.Debug("skipping struct field with false condition",
+			zap.String("struct_field_name", f.rv.Type().Field(i).Name),
+			zap.String("condition", fieldTag.If),
+		)
+	}
+	if fieldTag.Bitfield != "" {
+		g, err := newBitfieldGroup(f.rv.Type(), i)
+		if err != nil {
+			return false, err
+		}
+		f.bitfieldEnd = g.last
+	}
+	return true, nil
+}
+
+// encodeFieldTags encodes with encode the fields of the struct that the
+// tag of its i-th field groups with it (a bitfield), and returns the value
+// of the i-th field left to the struct walker: the zero Value when the
+// field is already encoded or left out by its `if=` condition, and the
+// length of its slice for a sizeof field auto-filled by the encoder.
+func (e *Encoder) encodeFieldTags(fields *structFields, i int, fieldTag *fieldTag, order binary.ByteOrder, encode func(reflect.Value, *option) error) (reflect.Value, error) {
+	if i <= fields.bitfieldEnd {
+		return reflect.Value{}, nil
+	}
+	if leftOut, err := fields.conditionFails(i, fieldTag); err != nil || leftOut {
+		return reflect.Value{}, err
+	}
+	if fieldTag.Bitfield != "" || fieldTag.Bits > 0 {
+		last, err := encodeBitfield(fields.rv, i, order, encode)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		fields.bitfieldEnd = last
+		return reflect.Value{}, nil
+	}
+
+	rv, err := e.structFieldValue(fields.rv, i, fieldTag)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if err := fields.setSizeOf(i, fieldTag, rv); err != nil {
+		return reflect.Value{}, err
+	}
+	return rv, nil
+}
+
+// decodeFieldTags decodes with decode the fields of the struct that the
+// tag of its i-th field groups with it (a bitfield), and returns whether
+// the i-th field is left to the struct walker: it is not when the field
+// is already decoded, or left out (and zeroed) by its `if=` condition.
+func (dec *Decoder) decodeFieldTags(fields *structFields, i int, fieldTag *fieldTag, order binary.ByteOrder, decode func(reflect.Value, *option) error) (bool, error) {
+	if i <= fields.bitfieldEnd {
+		return false, nil
+	}
+	if leftOut, err := fields.conditionFails(i, fieldTag); err != nil {
+		return false, err
+	} else if leftOut {
+		// The fields left out are zero, whatever the value decoded into.
+		last := i
+		if fields.bitfieldEnd > i {
+			last = fields.bitfieldEnd
+		}
+		for j := i; j <= last; j++ {
+			if field := fields.rv.Field(j); field.CanSet() {
+				field.Set(reflect.Zero(field.Type()))
+			}
+		}
+		return false, nil
+	}
+	if fieldTag.Bitfield != "" || fieldTag.Bits > 0 {
+		last, err := decodeBitfield(fields.rv, i, order, decode)
+		if err != nil {
+			return false, err
+		}
+		fields.bitfieldEnd = last
+		return false, nil
+	}
+
+	// A slice cannot get its length from both a sizeof field and a len= tag
+	// (the sizeof tags that name no following slice have no effect).
+	if fieldTag.SizeOf != "" {
+		if _, err := sizeOfTarget(fields.rv.Type(), i, fieldTag); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
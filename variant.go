@@ -20,7 +20,9 @@ package bin
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -42,13 +44,57 @@ type VariantType struct {
 	Type interface{}
 }
 
+// NewNamespacedVariantType returns the variant type of an anchor instruction
+// whose sighash is in the provided namespace instead of SIGHASH_GLOBAL_NAMESPACE
+// (e.g. SIGHASH_STATE_NAMESPACE for the methods of a `#[state]` struct),
+// for use with AnchorTypeIDEncoding only.
+//
+// Its name is qualified by the namespace ("state:increment"), so that it
+// doesn't clash with an instruction of the same name in another namespace;
+// that's the name to use with VariantDefinition.TypeID and in JSON.
+func NewNamespacedVariantType(namespace string, name string, typ interface{}) VariantType {
+	return VariantType{
+		Name: namespace + ":" + name,
+		Type: namespacedVariantType{namespace: namespace, name: name, typ: typ},
+	}
+}
+
+// namespacedVariantType is the Type of the variant types returned by
+// NewNamespacedVariantType.
+type namespacedVariantType struct {
+	namespace string
+	name      string
+	typ       interface{}
+}
+
 type VariantDefinition struct {
 	typeIDToType   map[TypeID]reflect.Type
 	typeIDToName   map[TypeID]string
 	typeNameToID   map[string]TypeID
 	typeIDEncoding TypeIDEncoding
+
+	// rawFallback enables decoding of unknown type IDs into a *RawVariant.
+	rawFallback bool
+	// rawFallbackMaxLength bounds the number of bytes captured by
+	// a *RawVariant; zero or negative means all the remaining bytes.
+	rawFallbackMaxLength int
+
+	jsonStyle VariantJSONStyle
 }
 
+// VariantJSONStyle defines the JSON representation of a BaseVariant.
+type VariantJSONStyle int
+
+const (
+	// VariantJSONArray is the EOS-style `["type_name", impl]` pair (default).
+	VariantJSONArray VariantJSONStyle = iota
+	// VariantJSONTypeData is the adjacently tagged `{"type": "type_name", "data": impl}` object.
+	VariantJSONTypeData
+	// VariantJSONExternallyTagged is the serde/anchor `{"type_name": impl}` object;
+	// variants without implementation are represented as `"type_name"`.
+	VariantJSONExternallyTagged
+)
+
 // TypeID defines the internal representation of an instruction type ID
 // (or account type, etc. in anchor programs)
 // and it's used to associate instructions to decoders in the variant tracker.
@@ -141,13 +187,21 @@ const (
 	AnchorTypeIDEncoding
 	// No type ID; ONLY ONE VARIANT PER PROGRAM.
 	NoTypeIDEncoding
+	// EVMSelectorTypeIDEncoding is the 4 bytes function selector of
+	// solidity contracts. The variant name is either the canonical signature
+	// of the function (e.g. "transfer(address,uint256)"), or the function name,
+	// in which case the signature is derived from the fields of the variant type.
+	EVMSelectorTypeIDEncoding
+	// Uint32BETypeIDEncoding is the big-endian uint32 discriminant
+	// of the XDR unions, by index.
+	Uint32BETypeIDEncoding
 )
 
 var NoTypeIDDefaultID = TypeIDFromUint8(0)
 
 // NewVariantDefinition creates a variant definition based on the *ordered* provided types.
 //
-// - For anchor instructions, it's the name that defines the binary variant value.
+// - For anchor instructions, it's the name (and namespace) that defines the binary variant value.
 // - For all other types, it's the ordering that defines the binary variant value just like in native `nodeos` C++
 //   and in Smart Contract via the `std::variant` type. It's important to pass the entries
 //   in the right order!
@@ -167,6 +221,14 @@ func NewVariantDefinition(typeIDEncoding TypeIDEncoding, types []VariantType) (o
 		typeNameToID:   make(map[string]TypeID, typeCount),
 	}
 
+	if typeIDEncoding != AnchorTypeIDEncoding {
+		for _, typeDef := range types {
+			if _, ok := typeDef.Type.(namespacedVariantType); ok {
+				panic(fmt.Sprintf("namespaced variant type %q requires AnchorTypeIDEncoding", typeDef.Name))
+			}
+		}
+	}
+
 	switch typeIDEncoding {
 	case Uvarint32TypeIDEncoding:
 		for i, typeDef := range types {
@@ -190,6 +252,14 @@ func NewVariantDefinition(typeIDEncoding TypeIDEncoding, types []VariantType) (o
 			//        re-used like the `typeGo.Elem()` which is always the same. It would be preferable
 			//        to have those already pre-defined here so we can actually speed up the
 			//        Unmarshal code.
+			out.typeIDToType[typeID] = reflect.TypeOf(typeDef.Type)
+			out.typeIDToName[typeID] = typeDef.Name
+			out.typeNameToID[typeDef.Name] = typeID
+		}
+	case Uint32BETypeIDEncoding:
+		for i, typeDef := range types {
+			typeID := TypeIDFromUint32(uint32(i), binary.BigEndian)
+
 			out.typeIDToType[typeID] = reflect.TypeOf(typeDef.Type)
 			out.typeIDToName[typeID] = typeDef.Name
 			out.typeNameToID[typeDef.Name] = typeID
@@ -210,6 +280,10 @@ func NewVariantDefinition(typeIDEncoding TypeIDEncoding, types []VariantType) (o
 	case AnchorTypeIDEncoding:
 		for _, typeDef := range types {
 			typeID := TypeIDFromSighash(Sighash(SIGHASH_GLOBAL_NAMESPACE, typeDef.Name))
+			if namespaced, ok := typeDef.Type.(namespacedVariantType); ok {
+				typeID = TypeIDFromSighash(Sighash(namespaced.namespace, namespaced.name))
+				typeDef.Type = namespaced.typ
+			}
 
 			// FIXME: Check how the reflect.Type is used and cache all its usage in the definition.
 			//        Right now, on each Unmarshal, we re-compute some expensive stuff that can be
@@ -237,6 +311,25 @@ func NewVariantDefinition(typeIDEncoding TypeIDEncoding, types []VariantType) (o
 		out.typeIDToName[typeID] = typeDef.Name
 		out.typeNameToID[typeDef.Name] = typeID
 
+	case EVMSelectorTypeIDEncoding:
+		for _, typeDef := range types {
+			signature := typeDef.Name
+			if !strings.Contains(signature, "(") {
+				var err error
+				if signature, err = EVMSignature(typeDef.Name, typeDef.Type); err != nil {
+					panic(err)
+				}
+			}
+			typeID := EVMSelectorTypeID(signature)
+			if existing, found := out.typeIDToName[typeID]; found {
+				panic(fmt.Sprintf("variants %q and %q have the same selector", existing, typeDef.Name))
+			}
+
+			out.typeIDToType[typeID] = reflect.TypeOf(typeDef.Type)
+			out.typeIDToName[typeID] = typeDef.Name
+			out.typeNameToID[typeDef.Name] = typeID
+		}
+
 	default:
 		panic(fmt.Errorf("unsupported TypeIDEncoding: %v", typeIDEncoding))
 	}
@@ -260,6 +353,103 @@ func (d *VariantDefinition) TypeID(name string) TypeID {
 	return id
 }
 
+// WithRawFallback returns a copy of the definition that, instead of failing,
+// decodes variants with an unknown type ID into a *RawVariant.
+// The RawVariant captures at most maxLength bytes; when maxLength is zero
+// or negative, all the remaining bytes of the decoder are captured.
+func (d *VariantDefinition) WithRawFallback(maxLength int) *VariantDefinition {
+	out := *d
+	out.rawFallback = true
+	out.rawFallbackMaxLength = maxLength
+	return &out
+}
+
+// WithJSONStyle returns a copy of the definition whose variants are
+// marshaled to and unmarshaled from JSON using the provided style.
+func (d *VariantDefinition) WithJSONStyle(style VariantJSONStyle) *VariantDefinition {
+	out := *d
+	out.jsonStyle = style
+	return &out
+}
+
+// ErrUnknownVariant is the sentinel matched (via errors.Is) by the error returned
+// when decoding a variant whose type ID is not registered in the definition.
+var ErrUnknownVariant = errors.New("unknown variant")
+
+// UnknownVariantError is returned when decoding a variant whose type ID
+// is not registered in the definition.
+type UnknownVariantError struct {
+	TypeID TypeID
+}
+
+func (e *UnknownVariantError) Error() string {
+	return fmt.Sprintf("no known type for type %d", e.TypeID)
+}
+
+func (e *UnknownVariantError) Is(target error) bool {
+	return target == ErrUnknownVariant
+}
+
+// RawVariant holds the undecoded content of a variant whose type ID
+// is not known by the variant definition.
+// Encoding a RawVariant writes back its Data as-is (the type ID must be
+// written by the parent, just like for any other variant implementation),
+// so that the original bytes are reproduced exactly.
+type RawVariant struct {
+	TypeID TypeID
+	Data   []byte
+}
+
+func (r *RawVariant) MarshalWithEncoder(encoder *Encoder) error {
+	return encoder.WriteBytes(r.Data, false)
+}
+
+// rawVariantJSON is the JSON representation of a RawVariant.
+type rawVariantJSON struct {
+	TypeID string `json:"type_id"`
+	Data   string `json:"data"`
+}
+
+// MarshalJSON returns the type ID and the data of the variant, hex encoded:
+// `{"type_id": "0900000000000000", "data": "01020304"}`.
+func (r *RawVariant) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rawVariantJSON{
+		TypeID: hex.EncodeToString(r.TypeID[:]),
+		Data:   hex.EncodeToString(r.Data),
+	})
+}
+
+func (r *RawVariant) UnmarshalJSON(data []byte) error {
+	var raw rawVariantJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	typeID, err := hex.DecodeString(raw.TypeID)
+	if err != nil || len(typeID) != len(r.TypeID) {
+		return fmt.Errorf("raw variant: invalid type id %q", raw.TypeID)
+	}
+	payload, err := hex.DecodeString(raw.Data)
+	if err != nil {
+		return fmt.Errorf("raw variant: invalid data: %w", err)
+	}
+	r.TypeID = TypeIDFromBytes(typeID)
+	r.Data = payload
+	return nil
+}
+
+// rawVariantTypeName is the type name of a RawVariant in the JSON
+// representation of a BaseVariant: the hex encoded type ID.
+func rawVariantTypeName(typeID TypeID) string {
+	return hex.EncodeToString(typeID[:])
+}
+
+// IsRaw returns true if the variant implementation is a *RawVariant,
+// i.e. the type ID was not known by the definition used to decode it.
+func (a *BaseVariant) IsRaw() bool {
+	_, ok := a.Impl.(*RawVariant)
+	return ok
+}
+
 type VariantImplFactory = func() interface{}
 type OnVariant = func(impl interface{}) error
 
@@ -279,25 +469,97 @@ func (a *BaseVariant) Obtain(def *VariantDefinition) (typeID TypeID, typeName st
 	return a.TypeID, def.typeIDToName[a.TypeID], a.Impl
 }
 
+// MarshalJSON returns the JSON representation of the variant in the style
+// of the definition. A *RawVariant is named by its hex encoded type ID.
 func (a *BaseVariant) MarshalJSON(def *VariantDefinition) ([]byte, error) {
+	if def == nil {
+		return nil, errNoVariantDefinition
+	}
 	typeName, found := def.typeIDToName[a.TypeID]
+	if raw, ok := a.Impl.(*RawVariant); ok && !found {
+		typeName, found = rawVariantTypeName(raw.TypeID), true
+	}
 	if !found {
 		return nil, fmt.Errorf("type %d is not know by variant definition", a.TypeID)
 	}
 
-	return json.Marshal([]interface{}{typeName, a.Impl})
+	switch def.jsonStyle {
+	case VariantJSONTypeData:
+		if a.Impl == nil {
+			return json.Marshal(map[string]interface{}{"type": typeName})
+		}
+		return json.Marshal(map[string]interface{}{"type": typeName, "data": a.Impl})
+	case VariantJSONExternallyTagged:
+		if a.Impl == nil {
+			return json.Marshal(typeName)
+		}
+		return json.Marshal(map[string]interface{}{typeName: a.Impl})
+	default:
+		return json.Marshal([]interface{}{typeName, a.Impl})
+	}
 }
 
+// UnmarshalJSON parses the JSON representation of the variant in the style
+// of the definition. If the definition has a raw fallback (see WithRawFallback),
+// unknown type names are parsed as a *RawVariant.
 func (a *BaseVariant) UnmarshalJSON(data []byte, def *VariantDefinition) error {
-	typeResult := gjson.GetBytes(data, "0")
-	implResult := gjson.GetBytes(data, "1")
+	if def == nil {
+		return errNoVariantDefinition
+	}
+	var typeName string
+	var implResult gjson.Result
+
+	switch def.jsonStyle {
+	case VariantJSONTypeData:
+		typeResult := gjson.GetBytes(data, "type")
+		if !gjson.ValidBytes(data) || !typeResult.Exists() {
+			return fmt.Errorf("invalid format, expected '{\"type\": <type>, \"data\": <impl>}' object, got %q", string(data))
+		}
+		typeName = typeResult.String()
+		implResult = gjson.GetBytes(data, "data")
+	case VariantJSONExternallyTagged:
+		parsed := gjson.ParseBytes(data)
+		switch {
+		case parsed.Type == gjson.String:
+			// Unit variant, e.g. `"Transfer"`.
+			typeName = parsed.String()
+		case parsed.IsObject():
+			count := 0
+			parsed.ForEach(func(key, value gjson.Result) bool {
+				typeName = key.String()
+				implResult = value
+				count++
+				return true
+			})
+			if count != 1 {
+				return fmt.Errorf("invalid format, expected '{<type>: <impl>}' object with exactly one key, got %q", string(data))
+			}
+		default:
+			return fmt.Errorf("invalid format, expected '{<type>: <impl>}' object, got %q", string(data))
+		}
+	default:
+		typeResult := gjson.GetBytes(data, "0")
+		implResult = gjson.GetBytes(data, "1")
 
-	if !typeResult.Exists() || !implResult.Exists() {
-		return fmt.Errorf("invalid format, expected '[<type>, <impl>]' pair, got %q", string(data))
+		if !typeResult.Exists() || !implResult.Exists() {
+			return fmt.Errorf("invalid format, expected '[<type>, <impl>]' pair, got %q", string(data))
+		}
+		typeName = typeResult.String()
 	}
 
-	typeName := typeResult.String()
 	typeID, found := def.typeNameToID[typeName]
+	if !found && def.rawFallback && implResult.Exists() {
+		raw := new(RawVariant)
+		if err := raw.UnmarshalJSON([]byte(implResult.Raw)); err != nil {
+			return err
+		}
+		if rawVariantTypeName(raw.TypeID) != typeName {
+			return fmt.Errorf("raw variant type id %x does not match type %q", raw.TypeID, typeName)
+		}
+		a.TypeID = raw.TypeID
+		a.Impl = raw
+		return nil
+	}
 	if !found {
 		return fmt.Errorf("type %q is not know by variant definition", typeName)
 	}
@@ -309,9 +571,15 @@ func (a *BaseVariant) UnmarshalJSON(data []byte, def *VariantDefinition) error {
 
 	a.TypeID = typeID
 
+	implRaw := []byte(implResult.Raw)
+	if !implResult.Exists() {
+		// No data (unit variant): leave the implementation at its zero value.
+		implRaw = []byte("null")
+	}
+
 	if typeGo.Kind() == reflect.Ptr {
 		a.Impl = reflect.New(typeGo.Elem()).Interface()
-		if err := json.Unmarshal([]byte(implResult.Raw), a.Impl); err != nil {
+		if err := json.Unmarshal(implRaw, a.Impl); err != nil {
 			return err
 		}
 	} else {
@@ -337,7 +605,7 @@ func (a *BaseVariant) UnmarshalJSON(data []byte, def *VariantDefinition) error {
 		// the next step would be to explore the `unsafe` package and obtain
 		// an unsafe pointer and play with it.
 		value := reflect.New(typeGo)
-		if err := json.Unmarshal([]byte(implResult.Raw), value.Interface()); err != nil {
+		if err := json.Unmarshal(implRaw, value.Interface()); err != nil {
 			return err
 		}
 
@@ -347,6 +615,49 @@ func (a *BaseVariant) UnmarshalJSON(data []byte, def *VariantDefinition) error {
 	return nil
 }
 
+// JSONVariant binds a variant to its definition so that it implements
+// json.Marshaler and json.Unmarshaler, and can be passed directly
+// to json.Marshal/json.Unmarshal (or used as a struct field).
+//
+// Definition must always be set (see BaseVariant.JSON), and an error is
+// returned otherwise; when unmarshaling, a nil Variant is allocated.
+type JSONVariant struct {
+	Variant    *BaseVariant
+	Definition *VariantDefinition
+}
+
+// JSON binds the variant to the provided definition for use with encoding/json.
+func (a *BaseVariant) JSON(def *VariantDefinition) *JSONVariant {
+	return &JSONVariant{
+		Variant:    a,
+		Definition: def,
+	}
+}
+
+// errNoVariantDefinition is returned when marshaling or unmarshaling
+// the JSON of a variant without its definition.
+var errNoVariantDefinition = errors.New("json variant: no variant definition set")
+
+func (v JSONVariant) MarshalJSON() ([]byte, error) {
+	if v.Definition == nil {
+		return nil, errNoVariantDefinition
+	}
+	if v.Variant == nil {
+		return []byte("null"), nil
+	}
+	return v.Variant.MarshalJSON(v.Definition)
+}
+
+func (v *JSONVariant) UnmarshalJSON(data []byte) error {
+	if v.Definition == nil {
+		return errNoVariantDefinition
+	}
+	if v.Variant == nil {
+		v.Variant = new(BaseVariant)
+	}
+	return v.Variant.UnmarshalJSON(data, v.Definition)
+}
+
 func (a *BaseVariant) UnmarshalBinaryVariant(decoder *Decoder, def *VariantDefinition) (err error) {
 	var typeID TypeID
 	switch def.typeIDEncoding {
@@ -362,6 +673,12 @@ func (a *BaseVariant) UnmarshalBinaryVariant(decoder *Decoder, def *VariantDefin
 			return fmt.Errorf("uint32: unable to read variant type id: %s", err)
 		}
 		typeID = TypeIDFromUint32(val, binary.LittleEndian)
+	case Uint32BETypeIDEncoding:
+		val, err := decoder.ReadUint32(binary.BigEndian)
+		if err != nil {
+			return fmt.Errorf("uint32: unable to read variant type id: %s", err)
+		}
+		typeID = TypeIDFromUint32(val, binary.BigEndian)
 	case Uint8TypeIDEncoding:
 		id, err := decoder.ReadUint8()
 		if err != nil {
@@ -375,13 +692,34 @@ func (a *BaseVariant) UnmarshalBinaryVariant(decoder *Decoder, def *VariantDefin
 		}
 	case NoTypeIDEncoding:
 		typeID = NoTypeIDDefaultID
+	case EVMSelectorTypeIDEncoding:
+		selector, err := decoder.ReadNBytes(4)
+		if err != nil {
+			return fmt.Errorf("evm: unable to read variant selector: %s", err)
+		}
+		typeID = TypeIDFromBytes(selector)
 	}
 
 	a.TypeID = typeID
 
 	typeGo := def.typeIDToType[typeID]
 	if typeGo == nil {
-		return fmt.Errorf("no known type for type %d", typeID)
+		if !def.rawFallback {
+			return &UnknownVariantError{TypeID: typeID}
+		}
+		length := decoder.Remaining()
+		if def.rawFallbackMaxLength > 0 && def.rawFallbackMaxLength < length {
+			length = def.rawFallbackMaxLength
+		}
+		data, err := decoder.ReadNBytes(length)
+		if err != nil {
+			return fmt.Errorf("unable to read raw variant type %d: %s", typeID, err)
+		}
+		a.Impl = &RawVariant{
+			TypeID: typeID,
+			Data:   data,
+		}
+		return nil
 	}
 
 	if typeGo.Kind() == reflect.Ptr {
@@ -0,0 +1,135 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VariantDispatcherBuilder collects the handlers of a VariantDispatcher.
+// Handlers are registered by variant name, as declared in the VariantDefinition.
+type VariantDispatcherBuilder struct {
+	def       *VariantDefinition
+	handlers  map[TypeID]OnVariant
+	onUnknown func(raw *RawVariant) error
+	errs      []string
+}
+
+// VariantDispatcher routes a decoded variant to the handler
+// registered for its type.
+type VariantDispatcher struct {
+	def       *VariantDefinition
+	handlers  map[TypeID]OnVariant
+	onUnknown func(raw *RawVariant) error
+}
+
+// NewVariantDispatcher starts building a dispatcher for the variants of the provided definition:
+//
+//	dispatcher, err := NewVariantDispatcher(def).
+//		On("transfer", func(impl interface{}) error { ... }).
+//		On("approve", func(impl interface{}) error { ... }).
+//		Build()
+func NewVariantDispatcher(def *VariantDefinition) *VariantDispatcherBuilder {
+	return &VariantDispatcherBuilder{
+		def:      def,
+		handlers: make(map[TypeID]OnVariant, len(def.typeNameToID)),
+	}
+}
+
+// On registers the handler for the variant with the provided name.
+// Unknown names and duplicate registrations are reported by Build.
+func (b *VariantDispatcherBuilder) On(name string, handler OnVariant) *VariantDispatcherBuilder {
+	typeID, found := b.def.typeNameToID[name]
+	if !found {
+		b.errs = append(b.errs, fmt.Sprintf("unknown variant %q", name))
+		return b
+	}
+	if handler == nil {
+		b.errs = append(b.errs, fmt.Sprintf("nil handler for variant %q", name))
+		return b
+	}
+	if _, ok := b.handlers[typeID]; ok {
+		b.errs = append(b.errs, fmt.Sprintf("duplicate handler for variant %q", name))
+		return b
+	}
+	b.handlers[typeID] = handler
+	return b
+}
+
+// OnUnknown registers the handler for variants decoded as *RawVariant
+// (see VariantDefinition.WithRawFallback).
+func (b *VariantDispatcherBuilder) OnUnknown(handler func(raw *RawVariant) error) *VariantDispatcherBuilder {
+	b.onUnknown = handler
+	return b
+}
+
+// Build verifies that every variant of the definition has a handler,
+// and returns the dispatcher.
+func (b *VariantDispatcherBuilder) Build() (*VariantDispatcher, error) {
+	errs := append([]string{}, b.errs...)
+
+	missing := make([]string, 0)
+	for name, typeID := range b.def.typeNameToID {
+		if _, ok := b.handlers[typeID]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		errs = append(errs, fmt.Sprintf("missing handlers for variants %q", missing))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("variant dispatcher: %s", strings.Join(errs, "; "))
+	}
+
+	handlers := make(map[TypeID]OnVariant, len(b.handlers))
+	for typeID, handler := range b.handlers {
+		handlers[typeID] = handler
+	}
+	return &VariantDispatcher{
+		def:       b.def,
+		handlers:  handlers,
+		onUnknown: b.onUnknown,
+	}, nil
+}
+
+// MustBuild acts just like Build but panics on error.
+func (b *VariantDispatcherBuilder) MustBuild() *VariantDispatcher {
+	dispatcher, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return dispatcher
+}
+
+// Dispatch calls the handler registered for the type of the provided variant
+// with its implementation, and returns the handler's error.
+func (d *VariantDispatcher) Dispatch(v Variant) error {
+	typeID, _, impl := v.Obtain(d.def)
+	if raw, ok := impl.(*RawVariant); ok {
+		if d.onUnknown == nil {
+			return &UnknownVariantError{TypeID: raw.TypeID}
+		}
+		return d.onUnknown(raw)
+	}
+
+	handler, found := d.handlers[typeID]
+	if !found {
+		return &UnknownVariantError{TypeID: typeID}
+	}
+	return handler(impl)
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariantDispatcher(t *testing.T) {
+	var gotLeft *NodeLeft
+	var gotRight *NodeRight
+	var gotRaw *RawVariant
+	dispatcher, err := NewVariantDispatcher(NodeVariantDef).
+		On("left_node", func(impl interface{}) error {
+			gotLeft = impl.(*NodeLeft)
+			return nil
+		}).
+		On("right_node", func(impl interface{}) error {
+			gotRight = impl.(*NodeRight)
+			return nil
+		}).
+		On("inner_node", func(impl interface{}) error {
+			return errors.New("inner")
+		}).
+		OnUnknown(func(raw *RawVariant) error {
+			gotRaw = raw
+			return nil
+		}).
+		Build()
+	require.NoError(t, err)
+
+	{
+		buf := []byte{0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x61, 0x62, 0x63}
+		var node Node
+		require.NoError(t, UnmarshalBin(&node, buf))
+		require.NoError(t, dispatcher.Dispatch(&node))
+		require.Equal(t, &NodeLeft{Key: 3, Description: "abc"}, gotLeft)
+		require.Nil(t, gotRight)
+	}
+	{
+		node := &BaseVariant{
+			TypeID: TypeIDFromUint32(2, binary.LittleEndian),
+			Impl:   &NodeInner{},
+		}
+		require.EqualError(t, dispatcher.Dispatch(node), "inner")
+	}
+	{
+		var node BaseVariant
+		buf := []byte{0x07, 0x00, 0x00, 0x00, 0xaa}
+		require.NoError(t, node.UnmarshalBinaryVariant(NewBinDecoder(buf), NodeVariantDef.WithRawFallback(0)))
+		require.NoError(t, dispatcher.Dispatch(&node))
+		require.Equal(t, []byte{0xaa}, gotRaw.Data)
+	}
+	{
+		node := &BaseVariant{TypeID: TypeIDFromUint32(7, binary.LittleEndian)}
+		err := dispatcher.Dispatch(node)
+		require.True(t, errors.Is(err, ErrUnknownVariant))
+	}
+}
+
+func TestVariantDispatcher_Build(t *testing.T) {
+	noop := func(impl interface{}) error { return nil }
+
+	_, err := NewVariantDispatcher(NodeVariantDef).
+		On("left_node", noop).
+		Build()
+	require.EqualError(t, err, `variant dispatcher: missing handlers for variants ["inner_node" "right_node"]`)
+
+	_, err = NewVariantDispatcher(NodeVariantDef).
+		On("left_node", noop).
+		On("left_node", noop).
+		On("middle_node", noop).
+		On("right_node", noop).
+		On("inner_node", noop).
+		Build()
+	require.EqualError(t, err, `variant dispatcher: duplicate handler for variant "left_node"; unknown variant "middle_node"`)
+
+	require.Panics(t, func() {
+		NewVariantDispatcher(NodeVariantDef).MustBuild()
+	})
+}
@@ -20,6 +20,8 @@ package bin
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -313,3 +315,237 @@ func TestEncode_UnexporterStruct(t *testing.T) {
 	enc.Encode(&unexportesStruct{value: 5})
 	assert.Equal(t, expectData, buf.Bytes())
 }
+
+func TestDecode_UnknownVariant(t *testing.T) {
+	buf := []byte{
+		0x09, 0x00, 0x00, 0x00, // unknown type ID
+		0x01, 0x02, 0x03, 0x04,
+	}
+
+	{
+		var got BaseVariant
+		err := got.UnmarshalBinaryVariant(NewBinDecoder(buf), NodeVariantDef)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrUnknownVariant))
+
+		var unknownErr *UnknownVariantError
+		require.True(t, errors.As(err, &unknownErr))
+		require.Equal(t, TypeIDFromUint32(9, binary.LittleEndian), unknownErr.TypeID)
+	}
+	{
+		decoder := NewBinDecoder(buf)
+		var got BaseVariant
+		require.NoError(t, got.UnmarshalBinaryVariant(decoder, NodeVariantDef.WithRawFallback(0)))
+		require.Equal(t, 0, decoder.Remaining())
+		require.True(t, got.IsRaw())
+		assert.Equal(t, &RawVariant{
+			TypeID: TypeIDFromUint32(9, binary.LittleEndian),
+			Data:   []byte{0x01, 0x02, 0x03, 0x04},
+		}, got.Impl)
+
+		// Re-encoding reproduces the original bytes:
+		node := &Node{BaseVariant: got}
+		out, err := MarshalBin(node)
+		require.NoError(t, err)
+		assert.Equal(t, buf, out)
+	}
+	{
+		decoder := NewBinDecoder(buf)
+		var got BaseVariant
+		require.NoError(t, got.UnmarshalBinaryVariant(decoder, NodeVariantDef.WithRawFallback(3)))
+		require.Equal(t, 1, decoder.Remaining())
+		assert.Equal(t, []byte{0x01, 0x02, 0x03}, got.Impl.(*RawVariant).Data)
+	}
+	{
+		// The original definition is left untouched.
+		var got BaseVariant
+		err := got.UnmarshalBinaryVariant(NewBinDecoder(buf), NodeVariantDef)
+		require.True(t, errors.Is(err, ErrUnknownVariant))
+	}
+}
+
+func TestVariant_JSONStyles(t *testing.T) {
+	variant := BaseVariant{
+		TypeID: TypeIDFromUint32(0, binary.LittleEndian),
+		Impl: &NodeLeft{
+			Key:         3,
+			Description: "abc",
+		},
+	}
+
+	tests := []struct {
+		style    VariantJSONStyle
+		expected string
+	}{
+		{VariantJSONArray, `["left_node",{"Key":3,"Description":"abc"}]`},
+		{VariantJSONTypeData, `{"data":{"Key":3,"Description":"abc"},"type":"left_node"}`},
+		{VariantJSONExternallyTagged, `{"left_node":{"Key":3,"Description":"abc"}}`},
+	}
+	for _, test := range tests {
+		def := NodeVariantDef.WithJSONStyle(test.style)
+
+		out, err := variant.MarshalJSON(def)
+		require.NoError(t, err)
+		assert.JSONEq(t, test.expected, string(out))
+
+		var got BaseVariant
+		require.NoError(t, got.UnmarshalJSON(out, def))
+		assert.Equal(t, variant, got)
+
+		// Through encoding/json:
+		out, err = json.Marshal(variant.JSON(def))
+		require.NoError(t, err)
+		assert.JSONEq(t, test.expected, string(out))
+
+		got = BaseVariant{}
+		require.NoError(t, json.Unmarshal(out, got.JSON(def)))
+		assert.Equal(t, variant, got)
+	}
+}
+
+func TestVariant_RawFallback_JSON(t *testing.T) {
+	buf := []byte{
+		0x09, 0x00, 0x00, 0x00, // unknown type ID
+		0x01, 0x02, 0x03, 0x04,
+	}
+	def := NodeVariantDef.WithRawFallback(0)
+
+	var variant BaseVariant
+	require.NoError(t, variant.UnmarshalBinaryVariant(NewBinDecoder(buf), def))
+
+	out, err := json.Marshal(variant.Impl)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type_id":"0900000000000000","data":"01020304"}`, string(out))
+
+	raw := `{"type_id":"0900000000000000","data":"01020304"}`
+	tests := []struct {
+		style    VariantJSONStyle
+		expected string
+	}{
+		{VariantJSONArray, `["0900000000000000",` + raw + `]`},
+		{VariantJSONTypeData, `{"type":"0900000000000000","data":` + raw + `}`},
+		{VariantJSONExternallyTagged, `{"0900000000000000":` + raw + `}`},
+	}
+	for _, test := range tests {
+		def := def.WithJSONStyle(test.style)
+
+		out, err := json.Marshal(variant.JSON(def))
+		require.NoError(t, err)
+		assert.JSONEq(t, test.expected, string(out))
+
+		var got BaseVariant
+		require.NoError(t, json.Unmarshal(out, got.JSON(def)))
+		assert.Equal(t, variant, got)
+
+		// Without the raw fallback, the type is unknown:
+		got = BaseVariant{}
+		require.Error(t, got.UnmarshalJSON(out, NodeVariantDef.WithJSONStyle(test.style)))
+	}
+
+	var got BaseVariant
+	require.EqualError(t,
+		got.UnmarshalJSON([]byte(`["0a00000000000000",`+raw+`]`), def),
+		`raw variant type id 0900000000000000 does not match type "0a00000000000000"`,
+	)
+}
+
+func TestVariant_JSONStyles_Errors(t *testing.T) {
+	{
+		var got BaseVariant
+		err := got.UnmarshalJSON([]byte(`{"left_node":{},"right_node":{}}`), NodeVariantDef.WithJSONStyle(VariantJSONExternallyTagged))
+		require.Error(t, err)
+	}
+	{
+		var got BaseVariant
+		err := got.UnmarshalJSON([]byte(`["left_node",{}]`), NodeVariantDef.WithJSONStyle(VariantJSONTypeData))
+		require.Error(t, err)
+	}
+	{
+		var got BaseVariant
+		require.NoError(t, got.UnmarshalJSON([]byte(`"inner_node"`), NodeVariantDef.WithJSONStyle(VariantJSONExternallyTagged)))
+		assert.Equal(t, TypeIDFromUint32(2, binary.LittleEndian), got.TypeID)
+		assert.Equal(t, &NodeInner{}, got.Impl)
+	}
+	{
+		var v JSONVariant
+		require.EqualError(t, json.Unmarshal([]byte(`["left_node",{}]`), &v), "json variant: no variant definition set")
+	}
+	{
+		// Without a definition, the errors are explicit:
+		variant := BaseVariant{TypeID: TypeIDFromUint32(0, binary.LittleEndian), Impl: &NodeLeft{}}
+		_, err := json.Marshal(variant.JSON(nil))
+		require.EqualError(t, err, "json: error calling MarshalJSON for type *bin.JSONVariant: json variant: no variant definition set")
+		_, err = json.Marshal(JSONVariant{})
+		require.EqualError(t, err, "json: error calling MarshalJSON for type *bin.JSONVariant: json variant: no variant definition set")
+
+		_, err = variant.MarshalJSON(nil)
+		require.EqualError(t, err, "json variant: no variant definition set")
+		require.EqualError(t, variant.UnmarshalJSON([]byte(`["left_node",{}]`), nil), "json variant: no variant definition set")
+	}
+}
+
+type anchorIncrement struct {
+	By uint64
+}
+
+type anchorInitialize struct {
+	Start uint64
+}
+
+func TestVariant_AnchorNamespaces(t *testing.T) {
+	def := NewVariantDefinition(
+		AnchorTypeIDEncoding,
+		[]VariantType{
+			{"initialize", (*anchorInitialize)(nil)},
+			{"increment", (*anchorIncrement)(nil)},
+			NewNamespacedVariantType(SIGHASH_STATE_NAMESPACE, "increment", (*anchorIncrement)(nil)),
+		},
+	)
+	require.Equal(t, SighashTypeID(SIGHASH_GLOBAL_NAMESPACE, "initialize"), def.TypeID("initialize"))
+	require.Equal(t, SighashTypeID(SIGHASH_GLOBAL_NAMESPACE, "increment"), def.TypeID("increment"))
+	require.Equal(t, SighashTypeID(SIGHASH_STATE_NAMESPACE, "increment"), def.TypeID("state:increment"))
+
+	{
+		buf := append(Sighash(SIGHASH_STATE_NAMESPACE, "increment"), 0x05, 0, 0, 0, 0, 0, 0, 0)
+		var got BaseVariant
+		require.NoError(t, got.UnmarshalBinaryVariant(NewBorshDecoder(buf), def))
+		assert.Equal(t, def.TypeID("state:increment"), got.TypeID)
+		assert.Equal(t, &anchorIncrement{By: 5}, got.Impl)
+
+		data, err := json.Marshal(got.JSON(def.WithJSONStyle(VariantJSONExternallyTagged)))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"state:increment":{"By":5}}`, string(data))
+	}
+	{
+		buf := append(Sighash(SIGHASH_GLOBAL_NAMESPACE, "increment"), 0x06, 0, 0, 0, 0, 0, 0, 0)
+		var got BaseVariant
+		require.NoError(t, got.UnmarshalBinaryVariant(NewBorshDecoder(buf), def))
+		assert.Equal(t, def.TypeID("increment"), got.TypeID)
+		assert.Equal(t, &anchorIncrement{By: 6}, got.Impl)
+	}
+	{
+		buf := append(Sighash(SIGHASH_GLOBAL_NAMESPACE, "initialize"), 0x07, 0, 0, 0, 0, 0, 0, 0)
+		var got BaseVariant
+		require.NoError(t, got.UnmarshalBinaryVariant(NewBorshDecoder(buf), def))
+		assert.Equal(t, &anchorInitialize{Start: 7}, got.Impl)
+	}
+	{
+		// The state sighash of a global instruction is not known:
+		buf := append(Sighash(SIGHASH_STATE_NAMESPACE, "initialize"), 0x07, 0, 0, 0, 0, 0, 0, 0)
+		var got BaseVariant
+		err := got.UnmarshalBinaryVariant(NewBorshDecoder(buf), def)
+		require.True(t, errors.Is(err, ErrUnknownVariant))
+	}
+
+	// A plain name is hashed in the global namespace, even with a colon:
+	plain := NewVariantDefinition(AnchorTypeIDEncoding, []VariantType{
+		{"state:increment", (*anchorIncrement)(nil)},
+	})
+	require.Equal(t, SighashTypeID(SIGHASH_GLOBAL_NAMESPACE, "state:increment"), plain.TypeID("state:increment"))
+
+	require.Panics(t, func() {
+		NewVariantDefinition(Uint8TypeIDEncoding, []VariantType{
+			NewNamespacedVariantType(SIGHASH_STATE_NAMESPACE, "increment", (*anchorIncrement)(nil)),
+		})
+	})
+}
@@ -0,0 +1,277 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+)
+
+// View gives typed access, in place, to the bytes of a value with the
+// `#[repr(C)]` layout of EncodingReprC (e.g. an anchor zero_copy account),
+// without decoding the whole value.
+//
+// The offsets of the fields are computed once per type. Like reflect.Value,
+// the accessors panic when misused (unknown field, index out of range,
+// or kind mismatch); the length of the data is checked by NewView.
+//
+// Typed views can be declared by wrapping a View:
+//
+//	type OrderbookView struct{ bin.View }
+//
+//	func (v OrderbookView) Slots(i int) SlotView { return SlotView{v.Field("Slots").Index(i)} }
+//
+//	type SlotView struct{ bin.View }
+//
+//	func (v SlotView) Price() uint64 { return v.Field("Price").Uint64() }
+type View struct {
+	data []byte
+	t    *reprCType
+}
+
+var viewTypes sync.Map // reflect.Type => *reprCType
+
+// NewView returns a view of data, laid out as the type of v
+// (which can be a nil pointer, e.g. `(*Orderbook)(nil)`).
+// The data must hold at least the size of the type; it is not copied.
+func NewView(data []byte, v interface{}) (View, error) {
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt == nil {
+		return View{}, fmt.Errorf("view: unsupported value %v", v)
+	}
+
+	var t *reprCType
+	if cached, ok := viewTypes.Load(rt); ok {
+		t = cached.(*reprCType)
+	} else {
+		var err error
+		if t, err = reprCTypeOf(rt, false, defaultByteOrder); err != nil {
+			return View{}, fmt.Errorf("view: %w", err)
+		}
+		viewTypes.Store(rt, t)
+	}
+	if len(data) < t.size {
+		return View{}, fmt.Errorf("view: %s requires %d bytes, got %d", rt, t.size, len(data))
+	}
+	return View{data: data[:t.size], t: t}, nil
+}
+
+// Type returns the type of the viewed value.
+func (v View) Type() reflect.Type {
+	return v.t.rt
+}
+
+// Bytes returns the bytes of the viewed value (sharing its memory).
+func (v View) Bytes() []byte {
+	return v.data
+}
+
+// Field returns the view of the field of a struct.
+func (v View) Field(name string) View {
+	if v.t.rt.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("bin: View.Field of non-struct type %s", v.t.rt))
+	}
+	i, ok := v.t.names[name]
+	if !ok {
+		panic(fmt.Sprintf("bin: View.Field: no field %q in %s", name, v.t.rt))
+	}
+	field := v.t.fields[i]
+	return View{data: v.data[field.offset : field.offset+field.typ.size], t: field.typ}
+}
+
+// Len returns the length of an array.
+func (v View) Len() int {
+	if v.t.rt.Kind() != reflect.Array {
+		panic(fmt.Sprintf("bin: View.Len of non-array type %s", v.t.rt))
+	}
+	return v.t.rt.Len()
+}
+
+// Index returns the view of the i-th element of an array.
+func (v View) Index(i int) View {
+	if i < 0 || i >= v.Len() {
+		panic(fmt.Sprintf("bin: View.Index: index %d out of range [0, %d)", i, v.Len()))
+	}
+	size := v.t.elem.size
+	return View{data: v.data[i*size : (i+1)*size], t: v.t.elem}
+}
+
+// Decode decodes the viewed value into out, which must be a pointer to its type.
+func (v View) Decode(out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Type() != v.t.rt {
+		return fmt.Errorf("view: cannot decode %s into %T", v.t.rt, out)
+	}
+	return reprCGet(v.data, rv.Elem(), v.t)
+}
+
+// Set overwrites the viewed value with value, which must be of its type.
+func (v View) Set(value interface{}) error {
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() || rv.Type() != v.t.rt {
+		return fmt.Errorf("view: cannot set %s to %T", v.t.rt, value)
+	}
+	buf := make([]byte, v.t.size)
+	reprCPut(buf, rv, v.t)
+	copy(v.data, buf)
+	return nil
+}
+
+func (v View) mustBe(method string, kinds ...reflect.Kind) {
+	for _, kind := range kinds {
+		if v.t.rt.Kind() == kind {
+			return
+		}
+	}
+	panic(fmt.Sprintf("bin: View.%s of type %s", method, v.t.rt))
+}
+
+func (v View) Bool() bool {
+	v.mustBe("Bool", reflect.Bool)
+	return v.data[0] != 0
+}
+
+func (v View) SetBool(b bool) {
+	v.mustBe("SetBool", reflect.Bool)
+	v.data[0] = 0
+	if b {
+		v.data[0] = 1
+	}
+}
+
+func (v View) Uint8() uint8 {
+	v.mustBe("Uint8", reflect.Uint8)
+	return v.data[0]
+}
+
+func (v View) SetUint8(i uint8) {
+	v.mustBe("SetUint8", reflect.Uint8)
+	v.data[0] = i
+}
+
+func (v View) Int8() int8 {
+	v.mustBe("Int8", reflect.Int8)
+	return int8(v.data[0])
+}
+
+func (v View) SetInt8(i int8) {
+	v.mustBe("SetInt8", reflect.Int8)
+	v.data[0] = uint8(i)
+}
+
+func (v View) Uint16() uint16 {
+	v.mustBe("Uint16", reflect.Uint16)
+	return v.t.order.Uint16(v.data)
+}
+
+func (v View) SetUint16(i uint16) {
+	v.mustBe("SetUint16", reflect.Uint16)
+	v.t.order.PutUint16(v.data, i)
+}
+
+func (v View) Int16() int16 {
+	v.mustBe("Int16", reflect.Int16)
+	return int16(v.t.order.Uint16(v.data))
+}
+
+func (v View) SetInt16(i int16) {
+	v.mustBe("SetInt16", reflect.Int16)
+	v.t.order.PutUint16(v.data, uint16(i))
+}
+
+func (v View) Uint32() uint32 {
+	v.mustBe("Uint32", reflect.Uint32)
+	return v.t.order.Uint32(v.data)
+}
+
+func (v View) SetUint32(i uint32) {
+	v.mustBe("SetUint32", reflect.Uint32)
+	v.t.order.PutUint32(v.data, i)
+}
+
+func (v View) Int32() int32 {
+	v.mustBe("Int32", reflect.Int32)
+	return int32(v.t.order.Uint32(v.data))
+}
+
+func (v View) SetInt32(i int32) {
+	v.mustBe("SetInt32", reflect.Int32)
+	v.t.order.PutUint32(v.data, uint32(i))
+}
+
+func (v View) Uint64() uint64 {
+	v.mustBe("Uint64", reflect.Uint64, reflect.Uint)
+	return v.t.order.Uint64(v.data)
+}
+
+func (v View) SetUint64(i uint64) {
+	v.mustBe("SetUint64", reflect.Uint64, reflect.Uint)
+	v.t.order.PutUint64(v.data, i)
+}
+
+func (v View) Int64() int64 {
+	v.mustBe("Int64", reflect.Int64, reflect.Int)
+	return int64(v.t.order.Uint64(v.data))
+}
+
+func (v View) SetInt64(i int64) {
+	v.mustBe("SetInt64", reflect.Int64, reflect.Int)
+	v.t.order.PutUint64(v.data, uint64(i))
+}
+
+func (v View) Float32() float32 {
+	v.mustBe("Float32", reflect.Float32)
+	return math.Float32frombits(v.t.order.Uint32(v.data))
+}
+
+func (v View) SetFloat32(f float32) {
+	v.mustBe("SetFloat32", reflect.Float32)
+	v.t.order.PutUint32(v.data, math.Float32bits(f))
+}
+
+func (v View) Float64() float64 {
+	v.mustBe("Float64", reflect.Float64)
+	return math.Float64frombits(v.t.order.Uint64(v.data))
+}
+
+func (v View) SetFloat64(f float64) {
+	v.mustBe("SetFloat64", reflect.Float64)
+	v.t.order.PutUint64(v.data, math.Float64bits(f))
+}
+
+func (v View) Uint128() Uint128 {
+	if v.t.rt != uint128Type && v.t.rt != int128Type {
+		panic(fmt.Sprintf("bin: View.Uint128 of type %s", v.t.rt))
+	}
+	lo, hi := v.data[:8], v.data[8:16]
+	if v.t.order == BE {
+		lo, hi = hi, lo
+	}
+	return Uint128{Lo: v.t.order.Uint64(lo), Hi: v.t.order.Uint64(hi)}
+}
+
+func (v View) SetUint128(i Uint128) {
+	if v.t.rt != uint128Type && v.t.rt != int128Type {
+		panic(fmt.Sprintf("bin: View.SetUint128 of type %s", v.t.rt))
+	}
+	buf := make([]byte, v.t.size)
+	reprCPut(buf, reflect.ValueOf(i), v.t)
+	copy(v.data, buf)
+}
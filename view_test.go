@@ -0,0 +1,104 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type viewSlot struct {
+	Owner    [32]byte
+	Price    uint64
+	Quantity int32
+	Side     uint8
+	Filled   bool
+}
+
+type viewOrderbook struct {
+	SeqNum  Uint128
+	Count   uint16
+	Scale   float64
+	Slots   [4096]viewSlot
+	Trailer int8
+}
+
+type viewOrderbookView struct{ View }
+
+func (v viewOrderbookView) SeqNum() Uint128 { return v.Field("SeqNum").Uint128() }
+func (v viewOrderbookView) Slots(i int) viewSlotView {
+	return viewSlotView{v.Field("Slots").Index(i)}
+}
+
+type viewSlotView struct{ View }
+
+func (v viewSlotView) Price() uint64         { return v.Field("Price").Uint64() }
+func (v viewSlotView) SetPrice(price uint64) { v.Field("Price").SetUint64(price) }
+
+func TestView(t *testing.T) {
+	book := new(viewOrderbook)
+	book.SeqNum = Uint128{Lo: 7, Hi: 1}
+	book.Count = 2
+	book.Scale = 0.5
+	book.Slots[1] = viewSlot{Price: 100, Quantity: -5, Side: 1, Filled: true}
+	book.Slots[4095].Owner[31] = 0xaa
+	book.Trailer = -1
+
+	data, err := MarshalReprC(book)
+	require.NoError(t, err)
+	require.Len(t, data, 16+16+4096*48+8)
+
+	view, err := NewView(data, (*viewOrderbook)(nil))
+	require.NoError(t, err)
+	book2 := viewOrderbookView{view}
+
+	assert.Equal(t, Uint128{Lo: 7, Hi: 1}, book2.SeqNum())
+	assert.Equal(t, uint16(2), view.Field("Count").Uint16())
+	assert.Equal(t, 0.5, view.Field("Scale").Float64())
+	assert.Equal(t, uint64(100), book2.Slots(1).Price())
+	assert.Equal(t, int32(-5), book2.Slots(1).Field("Quantity").Int32())
+	assert.Equal(t, uint8(1), book2.Slots(1).Field("Side").Uint8())
+	assert.True(t, book2.Slots(1).Field("Filled").Bool())
+	assert.Equal(t, int8(-1), view.Field("Trailer").Int8())
+	assert.Equal(t, 4096, view.Field("Slots").Len())
+
+	var owner [32]byte
+	require.NoError(t, book2.Slots(4095).Field("Owner").Decode(&owner))
+	assert.Equal(t, byte(0xaa), owner[31])
+
+	// The setters write in place:
+	book2.Slots(2).SetPrice(42)
+	view.Field("Count").SetUint16(3)
+	view.Field("SeqNum").SetUint128(Uint128{Lo: 8})
+	require.NoError(t, book2.Slots(3).Set(viewSlot{Price: 1, Quantity: 2}))
+
+	var got viewOrderbook
+	require.NoError(t, UnmarshalReprC(&got, data))
+	assert.Equal(t, uint64(42), got.Slots[2].Price)
+	assert.Equal(t, uint16(3), got.Count)
+	assert.Equal(t, Uint128{Lo: 8}, got.SeqNum)
+	assert.Equal(t, viewSlot{Price: 1, Quantity: 2}, got.Slots[3])
+	assert.Equal(t, book.Slots[1], got.Slots[1])
+
+	_, err = NewView(data[:len(data)-1], (*viewOrderbook)(nil))
+	require.EqualError(t, err, "view: bin.viewOrderbook requires 196648 bytes, got 196647")
+
+	require.Error(t, view.Field("Count").Set(uint32(1)))
+	assert.Panics(t, func() { book2.Slots(4096) })
+	assert.Panics(t, func() { view.Field("Missing") })
+	assert.Panics(t, func() { view.Field("Count").Uint32() })
+}
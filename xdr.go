@@ -0,0 +1,374 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// xdrUnit is the alignment of the XDR items.
+const xdrUnit = 4
+
+func xdrPadding(length int) int {
+	return (xdrUnit - length%xdrUnit) % xdrUnit
+}
+
+// WriteXDROpaque writes the data as XDR opaque data, followed by zero padding
+// to a multiple of 4 bytes. Variable-length data is preceded by its length.
+func (e *Encoder) WriteXDROpaque(data []byte, variable bool) error {
+	if variable {
+		if err := e.WriteLength(len(data)); err != nil {
+			return err
+		}
+	}
+	if err := e.WriteBytes(data, false); err != nil {
+		return err
+	}
+	return e.WriteBytes(make([]byte, xdrPadding(len(data))), false)
+}
+
+// ReadXDROpaque reads XDR opaque data of the given length (or preceded by
+// its length when variable), and checks that its padding is zeroed.
+func (dec *Decoder) ReadXDROpaque(length int, variable bool) (out []byte, err error) {
+	if variable {
+		if length, err = dec.ReadLength(); err != nil {
+			return nil, err
+		}
+	}
+	data, err := dec.ReadNBytes(length)
+	if err != nil {
+		return nil, err
+	}
+	padding, err := dec.ReadNBytes(xdrPadding(length))
+	if err != nil {
+		return nil, err
+	}
+	if !isFilledWith(padding, 0) {
+		return nil, errors.New("xdr: non-zero padding")
+	}
+	return data, nil
+}
+
+func (e *Encoder) writeXDRBool(b bool) error {
+	if b {
+		return e.WriteUint32(1, BE)
+	}
+	return e.WriteUint32(0, BE)
+}
+
+func (dec *Decoder) readXDRBool() (bool, error) {
+	v, err := dec.ReadUint32(BE)
+	if err != nil {
+		return false, err
+	}
+	switch v {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	}
+	return false, fmt.Errorf("xdr: invalid bool value %d", v)
+}
+
+// checkMaxLength checks the length of a variable-length array, opaque or string
+// against the `xdr_max` tag of the field.
+func (o *option) checkMaxLength(length int) error {
+	if o.MaxLength > 0 && length > o.MaxLength {
+		return fmt.Errorf("length %d exceeds the maximum of %d", length, o.MaxLength)
+	}
+	return nil
+}
+
+// xdrEncoding is the definition of XDR: big-endian items aligned on 4 bytes,
+// with the fixed and maximum lengths of the `xdr_size` and `xdr_max` tags.
+type xdrEncoding struct{ noEncodingRules }
+
+func (xdrEncoding) Name() string                { return EncodingXDR.String() }
+func (xdrEncoding) ByteOrder() binary.ByteOrder { return binary.BigEndian }
+
+func (xdrEncoding) WriteLength(enc *Encoder, length int) error { return enc.WriteLength(length) }
+func (xdrEncoding) ReadLength(dec *Decoder) (int, error)       { return dec.ReadLength() }
+
+// Optional data is preceded by a bool telling whether it's present:
+func (xdrEncoding) WriteOptionTag(enc *Encoder, isPresent bool) error {
+	return enc.writeXDRBool(isPresent)
+}
+func (xdrEncoding) ReadOptionTag(dec *Decoder) (bool, error) { return dec.readXDRBool() }
+
+// The complex enums are discriminated unions:
+func (xdrEncoding) WriteEnumTag(enc *Encoder, index uint32) error { return enc.WriteUint32(index, BE) }
+func (xdrEncoding) ReadEnumTag(dec *Decoder) (uint32, error)      { return dec.ReadUint32(BE) }
+
+func (xdrEncoding) WriteString(enc *Encoder, s string) error {
+	return enc.WriteXDROpaque([]byte(s), true)
+}
+
+func (xdrEncoding) ReadString(dec *Decoder) (string, error) {
+	data, err := dec.ReadXDROpaque(0, true)
+	return string(data), err
+}
+
+func (xdrEncoding) WriteBool(enc *Encoder, b bool) error { return enc.writeXDRBool(b) }
+func (xdrEncoding) ReadBool(dec *Decoder) (bool, error)  { return dec.readXDRBool() }
+
+func (xdrEncoding) SortMapKeys(keys []reflect.Value) { SortMapKeysByValue(keys) }
+
+func (xdrEncoding) fieldOption(fieldTag *fieldTag, opt *option) error {
+	if fieldTag.LenPrefix != "" {
+		return errors.New("xdr: len tags are not supported, lengths are u32")
+	}
+	if fieldTag.Fixed > 0 || fieldTag.CString {
+		return errors.New("xdr: fixed and cstring tags are not supported")
+	}
+	opt.Order = binary.BigEndian
+	opt.FixedLength = fieldTag.XDRSize
+	opt.MaxLength = fieldTag.XDRMax
+	return nil
+}
+
+// encodeValue encodes the primitive types by kind, whatever their marshaler,
+// and the opaque data and arrays.
+func (def xdrEncoding) encodeValue(enc *Encoder, rv reflect.Value, opt *option) (bool, error) {
+	if iv := reflect.Indirect(rv); iv.IsValid() {
+		switch v := iv.Interface().(type) {
+		case Uint128, Int128:
+			u := iv.Convert(uint128Type).Interface().(Uint128)
+			if err := enc.WriteUint64(u.Hi, BE); err != nil {
+				return true, err
+			}
+			return true, enc.WriteUint64(u.Lo, BE)
+		case Uint256:
+			return true, enc.WriteBytes(v[:], false)
+		case Int256:
+			return true, enc.WriteBytes(v[:], false)
+		case Float128:
+			return true, fmt.Errorf("encode: xdr does not support type %q", iv.Type())
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		if err := opt.checkMaxLength(rv.Len()); err != nil {
+			return true, err
+		}
+		return true, enc.WriteXDROpaque([]byte(rv.String()), true)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return true, enc.WriteUint32(uint32(rv.Uint()), BE)
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return true, enc.WriteInt32(int32(rv.Int()), BE)
+	case reflect.Uint64:
+		return true, enc.WriteUint64(rv.Uint(), BE)
+	case reflect.Int64:
+		return true, enc.WriteInt64(rv.Int(), BE)
+	case reflect.Bool:
+		return true, enc.writeXDRBool(rv.Bool())
+	case reflect.Float32:
+		return true, enc.WriteFloat32(float32(rv.Float()), BE)
+	case reflect.Float64:
+		return true, enc.WriteFloat64(rv.Float(), BE)
+	}
+
+	if isBinaryMarshaler(rv) {
+		return false, nil
+	}
+
+	rt := rv.Type()
+	switch rt.Kind() {
+	case reflect.Array:
+		if rt.Elem().Kind() != reflect.Uint8 {
+			return false, nil
+		}
+		// Fixed-length opaque data:
+		arr := make([]byte, rt.Len())
+		reflect.Copy(reflect.ValueOf(arr), rv)
+		return true, enc.WriteXDROpaque(arr, false)
+	case reflect.Slice:
+		l := rv.Len()
+		if opt.hasSizeOfSlice() {
+			l = opt.getSizeOfSlice()
+		}
+		if opt.FixedLength > 0 && l != opt.FixedLength {
+			return true, fmt.Errorf("encode: fixed-length %s of %d elements, expected %d", rt, l, opt.FixedLength)
+		}
+		if err := opt.checkMaxLength(l); err != nil {
+			return true, err
+		}
+		variable := opt.FixedLength == 0 && !opt.hasSizeOfSlice()
+
+		if rt.Elem().Kind() == reflect.Uint8 {
+			buf := make([]byte, l)
+			reflect.Copy(reflect.ValueOf(buf), rv)
+			return true, enc.WriteXDROpaque(buf, variable)
+		}
+		if variable {
+			if err := enc.WriteLength(l); err != nil {
+				return true, err
+			}
+		}
+		for i := 0; i < l; i++ {
+			if err := enc.encodeRegistered(def, rv.Index(i), nil); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	case reflect.Map:
+		return true, fmt.Errorf("encode: unsupported type %q", rt)
+	}
+	return false, nil
+}
+
+// decodeValue decodes the primitive types by kind, whatever their unmarshaler,
+// and the opaque data and arrays.
+func (def xdrEncoding) decodeValue(dec *Decoder, rv reflect.Value, opt *option, hasUnmarshaler bool) (done bool, err error) {
+	rt := rv.Type()
+	switch rt {
+	case uint128Type, int128Type:
+		var v Uint128
+		if v.Hi, err = dec.ReadUint64(BE); err != nil {
+			return true, err
+		}
+		if v.Lo, err = dec.ReadUint64(BE); err != nil {
+			return true, err
+		}
+		rv.Set(reflect.ValueOf(v).Convert(rt))
+		return true, nil
+	case uint256Type, int256Type:
+		data, err := dec.ReadNBytes(32)
+		if err != nil {
+			return true, err
+		}
+		reflect.Copy(rv, reflect.ValueOf(data))
+		return true, nil
+	case float128Type:
+		return true, fmt.Errorf("decode: xdr does not support type %q", rt)
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		data, err := dec.ReadXDROpaque(0, true)
+		if err != nil {
+			return true, err
+		}
+		if err := opt.checkMaxLength(len(data)); err != nil {
+			return true, err
+		}
+		rv.SetString(string(data))
+		return true, nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		n, err := dec.ReadUint32(BE)
+		if err != nil {
+			return true, err
+		}
+		if rv.OverflowUint(uint64(n)) {
+			return true, fmt.Errorf("xdr: value %d overflows %s", n, rt)
+		}
+		rv.SetUint(uint64(n))
+		return true, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		n, err := dec.ReadInt32(BE)
+		if err != nil {
+			return true, err
+		}
+		if rv.OverflowInt(int64(n)) {
+			return true, fmt.Errorf("xdr: value %d overflows %s", n, rt)
+		}
+		rv.SetInt(int64(n))
+		return true, nil
+	case reflect.Uint64:
+		n, err := dec.ReadUint64(BE)
+		rv.SetUint(n)
+		return true, err
+	case reflect.Int64:
+		n, err := dec.ReadInt64(BE)
+		rv.SetInt(n)
+		return true, err
+	case reflect.Bool:
+		b, err := dec.readXDRBool()
+		rv.SetBool(b)
+		return true, err
+	case reflect.Float32:
+		f, err := dec.ReadFloat32(BE)
+		rv.SetFloat(float64(f))
+		return true, err
+	case reflect.Float64:
+		f, err := dec.ReadFloat64(BE)
+		rv.SetFloat(f)
+		return true, err
+	}
+
+	if hasUnmarshaler {
+		return false, nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Array:
+		if rt.Elem().Kind() != reflect.Uint8 {
+			return false, nil
+		}
+		// Fixed-length opaque data:
+		data, err := dec.ReadXDROpaque(rt.Len(), false)
+		if err != nil {
+			return true, err
+		}
+		reflect.Copy(rv, reflect.ValueOf(data))
+		return true, nil
+	case reflect.Slice:
+		var l int
+		switch {
+		case opt.hasSizeOfSlice():
+			l = opt.getSizeOfSlice()
+		case opt.FixedLength > 0:
+			l = opt.FixedLength
+		default:
+			if l, err = dec.ReadLength(); err != nil {
+				return true, err
+			}
+			if err = opt.checkMaxLength(l); err != nil {
+				return true, err
+			}
+		}
+
+		if rt.Elem().Kind() == reflect.Uint8 {
+			data, err := dec.ReadXDROpaque(l, false)
+			if err != nil {
+				return true, err
+			}
+			if l > 0 {
+				rv.Set(reflect.MakeSlice(rt, l, l))
+				reflect.Copy(rv, reflect.ValueOf(data))
+			}
+			return true, nil
+		}
+
+		if l == 0 {
+			// Empty slices are left nil
+			return true, nil
+		}
+
+		rv.Set(reflect.MakeSlice(rt, l, l))
+		for i := 0; i < l; i++ {
+			if err = dec.decodeRegistered(def, rv.Index(i), nil); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	case reflect.Map:
+		return true, fmt.Errorf("decode: unsupported type %q", rt)
+	}
+	return false, nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2021 github.com/gagliardetto
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type xdrAccount struct {
+	Code     [3]byte
+	Name     string `bin:"xdr_max=8"`
+	Hash     []byte `bin:"xdr_size=5"`
+	Data     []byte `bin:"xdr_max=6"`
+	Signers  []uint16
+	Flags    [2]int8
+	Sequence int64
+	Enabled  bool
+	Home     *uint32 `bin:"optional"`
+}
+
+type xdrMemo struct {
+	Enum BorshEnum `borsh_enum:"true"`
+	None struct{}
+	Text string
+	ID   uint64
+}
+
+func TestXDR_Serialization(t *testing.T) {
+	home := uint32(7)
+	account := xdrAccount{
+		Code:     [3]byte{'X', 'L', 'M'},
+		Name:     "alice",
+		Hash:     []byte{1, 2, 3, 4, 5},
+		Data:     []byte{0xaa},
+		Signers:  []uint16{1, 0xffff},
+		Flags:    [2]int8{-1, 2},
+		Sequence: -2,
+		Enabled:  true,
+		Home:     &home,
+	}
+	expected := "584c4d00" +
+		"00000005" + "616c696365000000" +
+		"0102030405000000" +
+		"00000001" + "aa000000" +
+		"00000002" + "00000001" + "0000ffff" +
+		"ffffffff" + "00000002" +
+		"fffffffffffffffe" +
+		"00000001" +
+		"00000001" + "00000007"
+
+	data, err := MarshalXDR(account)
+	require.NoError(t, err)
+	assert.Equal(t, expected, hex.EncodeToString(data))
+	assert.Zero(t, len(data)%4)
+
+	var got xdrAccount
+	require.NoError(t, UnmarshalXDR(&got, data))
+	assert.Equal(t, account, got)
+
+	{
+		// Absent optional data:
+		account.Home = nil
+		data, err := MarshalXDR(account)
+		require.NoError(t, err)
+		assert.Equal(t, expected[:len(expected)-16]+"00000000", hex.EncodeToString(data))
+
+		var got xdrAccount
+		require.NoError(t, UnmarshalXDR(&got, data))
+		assert.Equal(t, account, got)
+	}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"uint8", uint8(0xab), "000000ab"},
+		{"int16", int16(-2), "fffffffe"},
+		{"uint64", uint64(0x0102030405060708), "0102030405060708"},
+		{"float64", float64(1), "3ff0000000000000"},
+		{"false", false, "00000000"},
+		{"empty string", "", "00000000"},
+		{"uint128", Uint128{Lo: 2, Hi: 1}, "0000000000000001" + "0000000000000002"},
+		{"fixed opaque", [5]byte{1, 2, 3, 4, 5}, "0102030405000000"},
+		{"variable opaque", []byte{1, 2, 3, 4}, "00000004" + "01020304"},
+		{"union", xdrMemo{Enum: 1, Text: "hi"}, "00000001" + "00000002" + "68690000"},
+		{"union void arm", xdrMemo{Enum: 0}, "00000000"},
+		{"union u64 arm", xdrMemo{Enum: 2, ID: 9}, "00000002" + "0000000000000009"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := MarshalXDR(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, hex.EncodeToString(data))
+		})
+	}
+
+	{
+		var got xdrMemo
+		require.NoError(t, UnmarshalXDR(&got, mustHex("00000001"+"00000002"+"68690000")))
+		assert.Equal(t, xdrMemo{Enum: 1, Text: "hi"}, got)
+	}
+	{
+		var got Uint128
+		require.NoError(t, UnmarshalXDR(&got, mustHex("0000000000000001"+"0000000000000002")))
+		assert.Equal(t, Uint128{Lo: 2, Hi: 1}, got)
+	}
+}
+
+func TestXDR_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		target interface{}
+	}{
+		{"non-zero padding", "00000001" + "aa000100", new([]byte)},
+		{"non-zero fixed padding", "0102030405000001", new([5]byte)},
+		{"invalid bool", "00000002", new(bool)},
+		{"uint8 overflow", "00000100", new(uint8)},
+		{"length over the remaining bytes", "00000009" + "01020304", new([]byte)},
+		{"union discriminant", "00000003", new(xdrMemo)},
+		{"truncated padding", "00000001" + "aa", new([]byte)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Error(t, UnmarshalXDR(test.target, mustHex(test.data)))
+		})
+	}
+
+	{
+		var got xdrAccount
+		err := UnmarshalXDR(&got, mustHex("00000000"+"00000000"+"0000000000000000"+"00000007"+"0000000000000000"))
+		require.EqualError(t, err, `error while decoding "Data" field: length 7 exceeds the maximum of 6`)
+	}
+
+	_, err := MarshalXDR(xdrAccount{Name: "too long name"})
+	require.EqualError(t, err, `error while encoding "Name" field: length 13 exceeds the maximum of 8`)
+
+	_, err = MarshalXDR(xdrAccount{Hash: []byte{1}})
+	require.EqualError(t, err, `error while encoding "Hash" field: encode: fixed-length []uint8 of 1 elements, expected 5`)
+
+	_, err = MarshalXDR(map[string]int{"a": 1})
+	require.Error(t, err)
+}
+
+type xdrPayment struct {
+	Destination [4]byte
+	Amount      int64
+}
+
+type xdrBumpSequence struct {
+	BumpTo int64
+}
+
+var xdrOperationDef = NewVariantDefinition(
+	Uint32BETypeIDEncoding,
+	[]VariantType{
+		{Name: "payment", Type: (*xdrPayment)(nil)},
+		{Name: "bump_sequence", Type: (*xdrBumpSequence)(nil)},
+	},
+)
+
+type xdrOperation struct {
+	BaseVariant
+}
+
+func (o xdrOperation) MarshalWithEncoder(encoder *Encoder) error {
+	if err := encoder.WriteUint32(Uint32FromTypeID(o.TypeID, binary.BigEndian), binary.BigEndian); err != nil {
+		return err
+	}
+	return encoder.Encode(o.Impl)
+}
+
+func (o *xdrOperation) UnmarshalWithDecoder(decoder *Decoder) error {
+	return o.BaseVariant.UnmarshalBinaryVariant(decoder, xdrOperationDef)
+}
+
+func TestXDR_Variant(t *testing.T) {
+	assert.Equal(t, TypeIDFromUint32(1, binary.BigEndian), xdrOperationDef.TypeID("bump_sequence"))
+
+	op := xdrOperation{BaseVariant{
+		TypeID: xdrOperationDef.TypeID("bump_sequence"),
+		Impl:   &xdrBumpSequence{BumpTo: 3},
+	}}
+	buf := new(bytes.Buffer)
+	require.NoError(t, NewXDREncoder(buf).Encode(op))
+	assert.Equal(t, "00000001"+"0000000000000003", hex.EncodeToString(buf.Bytes()))
+
+	var got xdrOperation
+	require.NoError(t, NewXDRDecoder(buf.Bytes()).Decode(&got))
+	assert.Equal(t, op, got)
+
+	{
+		var got xdrOperation
+		require.NoError(t, NewXDRDecoder(mustHex("00000000"+"01020304"+"0000000000000064")).Decode(&got))
+		assert.Equal(t, &xdrPayment{Destination: [4]byte{1, 2, 3, 4}, Amount: 100}, got.Impl)
+	}
+}